@@ -0,0 +1,3 @@
+module github.com/shyim/tanjun/auth-proxy
+
+go 1.23.4
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"time"
+)
+
+// healthPath is answered by the proxy itself, so kamal-proxy can check it without credentials
+const healthPath = "/.tanjun/up"
+
+func main() {
+	upstream := os.Getenv("AUTH_UPSTREAM")
+	username := os.Getenv("AUTH_USERNAME")
+	password := os.Getenv("AUTH_PASSWORD")
+
+	if upstream == "" || username == "" || password == "" {
+		log.Fatal("AUTH_UPSTREAM, AUTH_USERNAME and AUTH_PASSWORD are required")
+	}
+
+	proxy := httputil.NewSingleHostReverseProxy(&url.URL{Scheme: "http", Host: upstream})
+
+	expectedUser := sha256.Sum256([]byte(username))
+	expectedPassword := sha256.Sum256([]byte(password))
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == healthPath {
+			conn, err := net.DialTimeout("tcp", upstream, 2*time.Second)
+
+			if err != nil {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+
+			_ = conn.Close()
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		user, pass, ok := r.BasicAuth()
+
+		if ok {
+			userHash := sha256.Sum256([]byte(user))
+			passHash := sha256.Sum256([]byte(pass))
+
+			if subtle.ConstantTimeCompare(userHash[:], expectedUser[:]) == 1 && subtle.ConstantTimeCompare(passHash[:], expectedPassword[:]) == 1 {
+				r.Header.Del("Authorization")
+				proxy.ServeHTTP(w, r)
+				return
+			}
+		}
+
+		w.Header().Set("WWW-Authenticate", `Basic realm="Restricted", charset="UTF-8"`)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+	})
+
+	log.Println("Listening on port 80")
+
+	if err := http.ListenAndServe(":80", handler); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -22,7 +22,7 @@ var copyCmd = &cobra.Command{
 	Short: "Copy out of containers or to containers",
 	Args:  cobra.ExactArgs(2),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		cfg, err := config.CreateConfig(configFile)
+		cfg, err := config.CreateConfig(configFile, environment)
 
 		if err != nil {
 			return err
@@ -49,7 +49,7 @@ var copyCmd = &cobra.Command{
 				serviceName = ""
 			}
 
-			containerID, err := docker.FindProjectContainer(cmd.Context(), client, cfg.Name, serviceName)
+			containerID, err := docker.FindProjectContainer(cmd.Context(), client, cfg.Identifier(), serviceName)
 
 			if err != nil {
 				return err
@@ -64,7 +64,7 @@ var copyCmd = &cobra.Command{
 				serviceName = ""
 			}
 
-			containerID, err := docker.FindProjectContainer(cmd.Context(), client, cfg.Name, serviceName)
+			containerID, err := docker.FindProjectContainer(cmd.Context(), client, cfg.Identifier(), serviceName)
 
 			if err != nil {
 				return err
@@ -12,7 +12,7 @@ var cronjobHistoryCmd = &cobra.Command{
 	Short: "List last executions of an cronjob",
 	Args:  cobra.MinimumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		cfg, err := config.CreateConfig(configFile)
+		cfg, err := config.CreateConfig(configFile, environment)
 
 		if err != nil {
 			return err
@@ -33,7 +33,7 @@ var cronjobHistoryCmd = &cobra.Command{
 		return docker.RunCronjobCommand(cmd.Context(), client, cfg, []string{"history", args[0]})
 	},
 	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
-		cfg, err := config.CreateConfig(configFile)
+		cfg, err := config.CreateConfig(configFile, environment)
 
 		if err != nil {
 			return []string{}, cobra.ShellCompDirectiveError
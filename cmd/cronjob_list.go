@@ -11,7 +11,7 @@ var cronjobListCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List all cronjobs",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		cfg, err := config.CreateConfig(configFile)
+		cfg, err := config.CreateConfig(configFile, environment)
 
 		if err != nil {
 			return err
@@ -12,7 +12,7 @@ var cronjobLogsCmd = &cobra.Command{
 	Short: "Show logs of an execution",
 	Args:  cobra.MinimumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		cfg, err := config.CreateConfig(configFile)
+		cfg, err := config.CreateConfig(configFile, environment)
 
 		if err != nil {
 			return err
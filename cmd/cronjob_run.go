@@ -12,7 +12,7 @@ var cronjobRunCmd = &cobra.Command{
 	Short: "Run a job out of schedule",
 	Args:  cobra.MinimumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		cfg, err := config.CreateConfig(configFile)
+		cfg, err := config.CreateConfig(configFile, environment)
 
 		if err != nil {
 			return err
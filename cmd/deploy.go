@@ -15,8 +15,8 @@ import (
 var deployCmd = &cobra.Command{
 	Use:   "deploy",
 	Short: "Deploys local source to server",
-	RunE: func(cmd *cobra.Command, args []string) error {
-		cfg, err := config.CreateConfig(configFile)
+	RunE: func(cmd *cobra.Command, args []string) (err error) {
+		cfg, err := config.CreateConfig(configFile, environment)
 
 		if err != nil {
 			return err
@@ -28,10 +28,47 @@ var deployCmd = &cobra.Command{
 			return err
 		}
 
+		defer func() {
+			if err := client.Close(); err != nil {
+				log.Warnf("Failed to close docker client: %s", err)
+			}
+		}()
+
 		rollback, _ := cmd.Flags().GetBool("rollback")
 
 		version, _ := cmd.Flags().GetString("version")
 
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+		canary, _ := cmd.Flags().GetInt("canary")
+
+		cfg.AllowServiceUpgrade, _ = cmd.Flags().GetBool("allow-service-upgrade")
+
+		if canary != 0 {
+			if err := docker.ValidateCanaryPercentage(canary); err != nil {
+				return err
+			}
+		}
+
+		history := docker.NewHistoryEntry(docker.HistoryActionDeploy)
+
+		if rollback {
+			history.Action = docker.HistoryActionRollback
+		}
+
+		if canary > 0 {
+			history.Action = docker.HistoryActionCanary
+			history.Details = fmt.Sprintf("%d%% of the traffic", canary)
+		}
+
+		if !dryRun {
+			defer func() {
+				history.Version = version
+				history.Finish(err)
+				docker.RecordHistory(cmd.Context(), client, cfg.Identifier(), history)
+			}()
+		}
+
 		if rollback {
 			version, err = docker.VersionCurrentlyActive(cmd.Context(), client, cfg)
 
@@ -75,6 +112,8 @@ var deployCmd = &cobra.Command{
 			if err != nil {
 				return err
 			}
+		} else if version == "" && dryRun {
+			version = nextBuildVersion
 		} else {
 			if version == "" {
 				currentDir, err := os.Getwd()
@@ -86,6 +125,11 @@ var deployCmd = &cobra.Command{
 				version, err = build.BuildImage(cmd.Context(), cfg, currentDir)
 
 				if err != nil {
+					// The history shows the attempted version, or that no version was built at all
+					if version == "" {
+						version = "build failed"
+					}
+
 					return err
 				}
 
@@ -93,6 +137,22 @@ var deployCmd = &cobra.Command{
 			}
 		}
 
+		if dryRun {
+			plan, err := docker.PlanDeploy(cmd.Context(), client, cfg, version)
+
+			if err != nil {
+				return err
+			}
+
+			printDeployPlan(os.Stdout, plan)
+
+			return nil
+		}
+
+		if canary > 0 {
+			return docker.DeployCanary(cmd.Context(), client, cfg, version, canary)
+		}
+
 		if err := docker.Deploy(cmd.Context(), client, cfg, version); err != nil {
 			return err
 		}
@@ -115,4 +175,7 @@ func init() {
 	rootCmd.AddCommand(deployCmd)
 	deployCmd.PersistentFlags().String("version", "", "Use this version to deploy, instead of building a new one. Useful for rollbacks")
 	deployCmd.PersistentFlags().Bool("rollback", false, "Rollback to previous version")
+	deployCmd.PersistentFlags().Int("canary", 0, "Route only this percentage of the traffic to the new version, finish it with tanjun rollout promote or abort")
+	deployCmd.PersistentFlags().Bool("dry-run", false, "Show what the deployment would change without applying it")
+	deployCmd.PersistentFlags().Bool("allow-service-upgrade", false, "Allow changing database services to another major version, the volumes are snapshotted before. A rollback of the app does not downgrade the data")
 }
@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/shyim/tanjun/internal/docker"
+)
+
+// nextBuildVersion is planned with, when deploy --dry-run would build a new image
+const nextBuildVersion = "<next build>"
+
+var servicePlanSymbols = map[string]string{
+	docker.ServiceActionKeep:     "=",
+	docker.ServiceActionCreate:   "+",
+	docker.ServiceActionRecreate: "~",
+}
+
+// printDeployPlan writes the plan as plain text, so it can be diffed between two runs
+func printDeployPlan(w io.Writer, plan *docker.DeployPlan) {
+	fmt.Fprintf(w, "Deployment plan for version %s\n", plan.Version)
+
+	fmt.Fprintln(w, "\nServices:")
+
+	if len(plan.Services) == 0 {
+		fmt.Fprintln(w, "  none")
+	}
+
+	for _, service := range plan.Services {
+		line := fmt.Sprintf("  %s %s (%s): %s", servicePlanSymbols[service.Action], service.Name, service.Image, service.Action)
+
+		if service.Reason != "" {
+			line += ", " + service.Reason
+		}
+
+		fmt.Fprintln(w, line)
+	}
+
+	fmt.Fprintln(w, "\nEnvironment variables:")
+
+	if len(plan.Environment) == 0 {
+		fmt.Fprintln(w, "  none")
+	}
+
+	for _, key := range plan.Environment {
+		fmt.Fprintf(w, "  %s=****\n", key)
+	}
+
+	if len(plan.Hooks) > 0 {
+		fmt.Fprintf(w, "\nHooks: %s\n", strings.Join(plan.Hooks, ", "))
+	}
+
+	for _, server := range plan.Servers {
+		fmt.Fprintf(w, "\nServer %s:\n", server.Address)
+
+		if server.CreateNetwork {
+			fmt.Fprintln(w, "  Network: create")
+		}
+
+		printPlanList(w, "Volumes to create", server.CreateVolumes)
+		printPlanList(w, "App containers to replace", server.ReplaceContainers)
+
+		if server.StartContainers > 0 {
+			fmt.Fprintf(w, "  App containers to start: %d\n", server.StartContainers)
+		}
+
+		printPlanList(w, "Workers to replace", server.ReplaceWorkers)
+		printPlanList(w, "Cronjob containers to replace", server.ReplaceCronjobs)
+		printPlanList(w, "Workers to start", server.StartWorkers)
+		printPlanList(w, "Cronjobs to schedule", server.StartCronjobs)
+
+		if len(server.KamalCommand) > 0 {
+			fmt.Fprintf(w, "  Proxy: %s\n", strings.Join(server.KamalCommand, " "))
+		}
+	}
+}
+
+func printPlanList(w io.Writer, title string, items []string) {
+	if len(items) == 0 {
+		return
+	}
+
+	fmt.Fprintf(w, "  %s:\n", title)
+
+	for _, item := range items {
+		fmt.Fprintf(w, "    - %s\n", item)
+	}
+}
@@ -1,6 +1,8 @@
 package cmd
 
 import (
+	"context"
+
 	"github.com/charmbracelet/log"
 	"github.com/shyim/tanjun/internal/config"
 	"github.com/shyim/tanjun/internal/docker"
@@ -11,35 +13,62 @@ var destroyCmd = &cobra.Command{
 	Use:   "destroy",
 	Short: "Destroy a project",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		cfg, err := config.CreateConfig(configFile)
+		cfg, err := config.CreateConfig(configFile, environment)
 
 		if err != nil {
 			return err
 		}
 
-		client, err := docker.CreateClientFromConfig(cfg)
+		history := docker.NewHistoryEntry(docker.HistoryActionDestroy)
 
-		if err != nil {
-			return err
-		}
+		err = destroyProjectOnServers(cmd.Context(), cfg)
+
+		history.Finish(err)
+
+		// The history lives on the primary server and survives the destroy
+		client, clientErr := docker.CreateClientFromConfig(cfg)
+
+		if clientErr == nil {
+			docker.RecordHistory(cmd.Context(), client, cfg.Identifier(), history)
 
-		defer func() {
 			if err := client.Close(); err != nil {
 				log.Warnf("Failed to close docker client: %s", err)
 			}
-		}()
+		}
 
-		if err := docker.DestroyProject(cmd.Context(), client, cfg.Name); err != nil {
+		if err != nil {
 			return err
 		}
 
-		log.Printf("Project %s destroyed\n", cfg.Name)
+		log.Printf("Project %s destroyed\n", cfg.Identifier())
 		log.Print("The docker image is still available, you need to delete it manually\n")
 
 		return nil
 	},
 }
 
+func destroyProjectOnServers(ctx context.Context, cfg *config.ProjectConfig) error {
+	for _, server := range cfg.AllServers() {
+		client, err := docker.CreateClientForServer(server)
+
+		if err != nil {
+			return err
+		}
+
+		err = docker.DestroyProject(ctx, client, cfg.Identifier())
+
+		if closeErr := client.Close(); closeErr != nil {
+			log.Warnf("Failed to close docker client: %s", closeErr)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func init() {
 	rootCmd.AddCommand(destroyCmd)
 }
@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"fmt"
+	"slices"
 	"time"
 )
 
@@ -44,3 +45,32 @@ func formatRelativeDate(t time.Time) string {
 		return fmt.Sprintf("%d months ago", months)
 	}
 }
+
+func formatBytes(size int64) string {
+	const unit = 1024
+
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+
+	div, exp := int64(unit), 0
+
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
+
+func sortedSecretKeys(secrets map[string]string) []string {
+	keys := make([]string, 0, len(secrets))
+
+	for key := range secrets {
+		keys = append(keys, key)
+	}
+
+	slices.Sort(keys)
+
+	return keys
+}
@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/charmbracelet/lipgloss/table"
+	"github.com/charmbracelet/log"
+	"github.com/shyim/tanjun/internal/config"
+	"github.com/shyim/tanjun/internal/docker"
+	"github.com/spf13/cobra"
+)
+
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "Shows the deployment history and audit log of the project",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.CreateConfig(configFile, environment)
+
+		if err != nil {
+			return err
+		}
+
+		client, err := docker.CreateClientFromConfig(cfg)
+
+		if err != nil {
+			return err
+		}
+
+		defer func() {
+			if err := client.Close(); err != nil {
+				log.Warnf("Failed to close docker client: %s", err)
+			}
+		}()
+
+		kv, err := docker.CreateKVConnection(cmd.Context(), client)
+
+		if err != nil {
+			return err
+		}
+
+		defer kv.Close()
+
+		entries, err := docker.ListHistory(kv, cfg.Identifier())
+
+		if err != nil {
+			return err
+		}
+
+		if asJSON, _ := cmd.Flags().GetBool("json"); asJSON {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+
+			return encoder.Encode(entries)
+		}
+
+		t := table.New().
+			Headers("Date", "Action", "Version", "Commit", "User", "Duration", "Status", "Details")
+
+		for _, entry := range entries {
+			status := "success"
+			details := entry.Details
+
+			if !entry.Success {
+				status = "failed"
+				details = entry.Error
+			}
+
+			commit := entry.GitCommit
+
+			if len(commit) > 7 {
+				commit = commit[:7]
+			}
+
+			t.Row(formatRelativeDate(entry.StartedAt), entry.Action, entry.Version, commit, fmt.Sprintf("%s@%s", entry.User, entry.Host), entry.Duration.String(), status, details)
+		}
+
+		fmt.Println(t.Render())
+
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(historyCmd)
+	historyCmd.Flags().Bool("json", false, "Print the history as JSON")
+}
@@ -0,0 +1,14 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var lockCmd = &cobra.Command{
+	Use:   "lock",
+	Short: "Manage the deploy lock of the project",
+}
+
+func init() {
+	rootCmd.AddCommand(lockCmd)
+}
@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"github.com/charmbracelet/log"
+	"github.com/shyim/tanjun/internal/config"
+	"github.com/shyim/tanjun/internal/docker"
+	"github.com/spf13/cobra"
+)
+
+var lockReleaseCmd = &cobra.Command{
+	Use:   "release",
+	Short: "Releases the deploy lock, use this only when no deployment is running anymore",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.CreateConfig(configFile, environment)
+
+		if err != nil {
+			return err
+		}
+
+		client, err := docker.CreateClientFromConfig(cfg)
+
+		if err != nil {
+			return err
+		}
+
+		defer func() {
+			if err := client.Close(); err != nil {
+				log.Warnf("Failed to close docker client: %s", err)
+			}
+		}()
+
+		kv, err := docker.CreateKVConnection(cmd.Context(), client)
+
+		if err != nil {
+			return err
+		}
+
+		defer kv.Close()
+
+		lock, err := docker.GetDeployLock(kv, cfg.Identifier())
+
+		if err != nil {
+			return err
+		}
+
+		if lock == nil {
+			log.Infof("Project %s is not locked", cfg.Identifier())
+			return nil
+		}
+
+		if err := docker.ReleaseDeployLock(kv, cfg.Identifier(), nil); err != nil {
+			return err
+		}
+
+		log.Infof("Released lock of %s", lock)
+
+		return nil
+	},
+}
+
+func init() {
+	lockCmd.AddCommand(lockReleaseCmd)
+}
@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"github.com/charmbracelet/log"
+	"github.com/shyim/tanjun/internal/config"
+	"github.com/shyim/tanjun/internal/docker"
+	"github.com/spf13/cobra"
+)
+
+var lockStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Shows who is currently deploying the project",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.CreateConfig(configFile, environment)
+
+		if err != nil {
+			return err
+		}
+
+		client, err := docker.CreateClientFromConfig(cfg)
+
+		if err != nil {
+			return err
+		}
+
+		defer func() {
+			if err := client.Close(); err != nil {
+				log.Warnf("Failed to close docker client: %s", err)
+			}
+		}()
+
+		kv, err := docker.CreateKVConnection(cmd.Context(), client)
+
+		if err != nil {
+			return err
+		}
+
+		defer kv.Close()
+
+		lock, err := docker.GetDeployLock(kv, cfg.Identifier())
+
+		if err != nil {
+			return err
+		}
+
+		if lock == nil {
+			log.Infof("Project %s is not locked", cfg.Identifier())
+			return nil
+		}
+
+		if lock.Expired() {
+			log.Infof("Project %s has a stale lock by %s, it will be taken over by the next deployment", cfg.Identifier(), lock)
+			return nil
+		}
+
+		log.Infof("Project %s is locked by %s", cfg.Identifier(), lock)
+
+		return nil
+	},
+}
+
+func init() {
+	lockCmd.AddCommand(lockStatusCmd)
+}
@@ -15,7 +15,7 @@ var logsCmd = &cobra.Command{
 	Use:   "logs",
 	Short: "Shows logs of a service",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		cfg, err := config.CreateConfig(configFile)
+		cfg, err := config.CreateConfig(configFile, environment)
 
 		if err != nil {
 			return err
@@ -35,7 +35,7 @@ var logsCmd = &cobra.Command{
 
 		serviceName, _ := cmd.PersistentFlags().GetString("service")
 
-		containerId, err := docker.FindProjectContainer(cmd.Context(), client, cfg.Name, serviceName)
+		containerId, err := docker.FindProjectContainer(cmd.Context(), client, cfg.Identifier(), serviceName)
 
 		if err != nil {
 			return err
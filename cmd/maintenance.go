@@ -0,0 +1,14 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var maintenanceCmd = &cobra.Command{
+	Use:   "maintenance",
+	Short: "Serve a maintenance page instead of the app",
+}
+
+func init() {
+	rootCmd.AddCommand(maintenanceCmd)
+}
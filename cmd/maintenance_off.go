@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"github.com/charmbracelet/log"
+	"github.com/shyim/tanjun/internal/config"
+	"github.com/shyim/tanjun/internal/docker"
+	"github.com/spf13/cobra"
+)
+
+var maintenanceOffCmd = &cobra.Command{
+	Use:   "off",
+	Short: "Routes the traffic to the app again and resumes paused workers and cronjobs",
+	RunE: func(cmd *cobra.Command, args []string) (err error) {
+		cfg, err := config.CreateConfig(configFile, environment)
+
+		if err != nil {
+			return err
+		}
+
+		client, err := docker.CreateClientFromConfig(cfg)
+
+		if err != nil {
+			return err
+		}
+
+		defer func() {
+			if err := client.Close(); err != nil {
+				log.Warnf("Failed to close docker client: %s", err)
+			}
+		}()
+
+		history := docker.NewHistoryEntry(docker.HistoryActionMaintenanceOff)
+
+		defer func() {
+			history.Finish(err)
+			docker.RecordHistory(cmd.Context(), client, cfg.Identifier(), history)
+		}()
+
+		if err := docker.DisableMaintenance(cmd.Context(), client, cfg); err != nil {
+			return err
+		}
+
+		log.Infof("Maintenance disabled, website is reachable at %s", cfg.Proxy.GetURL())
+
+		return nil
+	},
+}
+
+func init() {
+	maintenanceCmd.AddCommand(maintenanceOffCmd)
+}
@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/log"
+	"github.com/shyim/tanjun/internal/config"
+	"github.com/shyim/tanjun/internal/docker"
+	"github.com/spf13/cobra"
+)
+
+var maintenanceOnCmd = &cobra.Command{
+	Use:   "on",
+	Short: "Routes all traffic to a maintenance page, allowed IPs can still reach the app",
+	RunE: func(cmd *cobra.Command, args []string) (err error) {
+		cfg, err := config.CreateConfig(configFile, environment)
+
+		if err != nil {
+			return err
+		}
+
+		message, _ := cmd.Flags().GetString("message")
+		allowIPs, _ := cmd.Flags().GetStringSlice("allow-ip")
+		pauseWorkers, _ := cmd.Flags().GetBool("pause-workers")
+
+		for _, ip := range allowIPs {
+			if err := config.ValidateIPOrCIDR(ip); err != nil {
+				return err
+			}
+		}
+
+		client, err := docker.CreateClientFromConfig(cfg)
+
+		if err != nil {
+			return err
+		}
+
+		defer func() {
+			if err := client.Close(); err != nil {
+				log.Warnf("Failed to close docker client: %s", err)
+			}
+		}()
+
+		history := docker.NewHistoryEntry(docker.HistoryActionMaintenanceOn)
+		history.Details = strings.Join(allowIPs, ", ")
+
+		defer func() {
+			history.Finish(err)
+			docker.RecordHistory(cmd.Context(), client, cfg.Identifier(), history)
+		}()
+
+		if err := docker.EnableMaintenance(cmd.Context(), client, cfg, message, allowIPs, pauseWorkers); err != nil {
+			return err
+		}
+
+		log.Infof("Maintenance page is served at %s, disable it with tanjun maintenance off", cfg.Proxy.GetURL())
+
+		return nil
+	},
+}
+
+func init() {
+	maintenanceCmd.AddCommand(maintenanceOnCmd)
+	maintenanceOnCmd.Flags().String("message", "", "Message shown on the maintenance page, defaults to proxy.maintenance.message")
+	maintenanceOnCmd.Flags().StringSlice("allow-ip", nil, "IP or CIDR which can still reach the app, defaults to proxy.maintenance.allow_ips")
+	maintenanceOnCmd.Flags().Bool("pause-workers", false, "Stop workers and cronjobs until maintenance is disabled")
+}
@@ -0,0 +1,14 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var previewCmd = &cobra.Command{
+	Use:   "preview",
+	Short: "Manage throwaway preview environments, for example per pull request",
+}
+
+func init() {
+	rootCmd.AddCommand(previewCmd)
+}
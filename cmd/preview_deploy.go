@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/charmbracelet/log"
+	"github.com/shyim/tanjun/internal/build"
+	"github.com/shyim/tanjun/internal/config"
+	"github.com/shyim/tanjun/internal/docker"
+	"github.com/spf13/cobra"
+)
+
+var previewDeployCmd = &cobra.Command{
+	Use:   "deploy",
+	Short: "Deploys local source as preview with own services and host",
+	RunE: func(cmd *cobra.Command, args []string) (err error) {
+		cfg, err := config.CreateConfig(configFile, environment)
+
+		if err != nil {
+			return err
+		}
+
+		name, _ := cmd.Flags().GetString("name")
+		version, _ := cmd.Flags().GetString("version")
+
+		previewCfg, err := cfg.ForPreview(name)
+
+		if err != nil {
+			return err
+		}
+
+		client, err := docker.CreateClientFromConfig(cfg)
+
+		if err != nil {
+			return err
+		}
+
+		defer func() {
+			if err := client.Close(); err != nil {
+				log.Warnf("Failed to close docker client: %s", err)
+			}
+		}()
+
+		history := docker.NewHistoryEntry(docker.HistoryActionPreviewDeploy)
+		history.Details = name
+
+		defer func() {
+			history.Version = version
+			history.Finish(err)
+			docker.RecordHistory(cmd.Context(), client, cfg.Identifier(), history)
+		}()
+
+		if version == "" {
+			currentDir, err := os.Getwd()
+
+			if err != nil {
+				return err
+			}
+
+			version, err = build.BuildImage(cmd.Context(), cfg, currentDir)
+
+			if err != nil {
+				return err
+			}
+
+			log.Infof("Built version %s", version)
+		}
+
+		return docker.DeployPreview(cmd.Context(), client, previewCfg, version)
+	},
+}
+
+func init() {
+	previewCmd.AddCommand(previewDeployCmd)
+	previewDeployCmd.Flags().String("name", "", "Name of the preview, used for the project name and host")
+	previewDeployCmd.Flags().String("version", "", "Use this version to deploy, instead of building a new one")
+	_ = previewDeployCmd.MarkFlagRequired("name")
+}
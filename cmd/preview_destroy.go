@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"github.com/charmbracelet/log"
+	"github.com/shyim/tanjun/internal/config"
+	"github.com/shyim/tanjun/internal/docker"
+	"github.com/spf13/cobra"
+)
+
+var previewDestroyCmd = &cobra.Command{
+	Use:   "destroy",
+	Short: "Destroys a preview including its services and volumes",
+	RunE: func(cmd *cobra.Command, args []string) (err error) {
+		cfg, err := config.CreateConfig(configFile, environment)
+
+		if err != nil {
+			return err
+		}
+
+		name, _ := cmd.Flags().GetString("name")
+
+		previewCfg, err := cfg.ForPreview(name)
+
+		if err != nil {
+			return err
+		}
+
+		client, err := docker.CreateClientFromConfig(cfg)
+
+		if err != nil {
+			return err
+		}
+
+		defer func() {
+			if err := client.Close(); err != nil {
+				log.Warnf("Failed to close docker client: %s", err)
+			}
+		}()
+
+		history := docker.NewHistoryEntry(docker.HistoryActionPreviewDestroy)
+		history.Details = name
+
+		defer func() {
+			history.Finish(err)
+			docker.RecordHistory(cmd.Context(), client, cfg.Identifier(), history)
+		}()
+
+		if err := docker.DestroyPreview(cmd.Context(), client, previewCfg); err != nil {
+			return err
+		}
+
+		log.Infof("Preview %s destroyed", name)
+
+		return nil
+	},
+}
+
+func init() {
+	previewCmd.AddCommand(previewDestroyCmd)
+	previewDestroyCmd.Flags().String("name", "", "Name of the preview")
+	_ = previewDestroyCmd.MarkFlagRequired("name")
+}
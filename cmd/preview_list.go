@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/charmbracelet/lipgloss/table"
+	"github.com/charmbracelet/log"
+	"github.com/shyim/tanjun/internal/config"
+	"github.com/shyim/tanjun/internal/docker"
+	"github.com/spf13/cobra"
+)
+
+var previewListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Lists the previews of the project, --prune destroys the expired ones",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.CreateConfig(configFile, environment)
+
+		if err != nil {
+			return err
+		}
+
+		prune, _ := cmd.Flags().GetBool("prune")
+
+		client, err := docker.CreateClientFromConfig(cfg)
+
+		if err != nil {
+			return err
+		}
+
+		defer func() {
+			if err := client.Close(); err != nil {
+				log.Warnf("Failed to close docker client: %s", err)
+			}
+		}()
+
+		kv, err := docker.CreateKVConnection(cmd.Context(), client)
+
+		if err != nil {
+			return err
+		}
+
+		previews, err := docker.ListPreviews(kv, cfg.Identifier())
+
+		kv.Close()
+
+		if err != nil {
+			return err
+		}
+
+		t := table.New().
+			Headers("Name", "Host", "Version", "Deployed by", "Deployed at", "Expires")
+
+		for _, preview := range previews {
+			expiresAt := preview.ExpiresAt(cfg.Preview.ExpireAfter)
+			expires := expiresAt.Local().Format(time.DateTime)
+
+			if time.Now().After(expiresAt) {
+				expires = "expired"
+
+				if prune {
+					previewCfg, err := cfg.ForPreview(preview.Name)
+
+					if err != nil {
+						return err
+					}
+
+					history := docker.NewHistoryEntry(docker.HistoryActionPreviewDestroy)
+					history.Details = preview.Name
+
+					err = docker.DestroyPreview(cmd.Context(), client, previewCfg)
+
+					history.Finish(err)
+					docker.RecordHistory(cmd.Context(), client, cfg.Identifier(), history)
+
+					if err != nil {
+						return fmt.Errorf("could not destroy preview %s: %w", preview.Name, err)
+					}
+
+					expires = "destroyed"
+				}
+			}
+
+			t.Row(preview.Name, preview.Host, preview.Version, preview.User, formatRelativeDate(preview.DeployedAt), expires)
+		}
+
+		fmt.Println(t.Render())
+
+		log.Infof("Found %d previews", len(previews))
+
+		return nil
+	},
+}
+
+func init() {
+	previewCmd.AddCommand(previewListCmd)
+	previewListCmd.Flags().Bool("prune", false, "Destroy expired previews")
+}
@@ -20,7 +20,7 @@ var forwardCmd = &cobra.Command{
 	Short: "Forward a external port to localhost",
 	Args:  cobra.ExactArgs(2),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		cfg, err := config.CreateConfig(configFile)
+		cfg, err := config.CreateConfig(configFile, environment)
 
 		if err != nil {
 			return err
@@ -38,7 +38,7 @@ var forwardCmd = &cobra.Command{
 			}
 		}()
 
-		containerId, err := docker.FindProjectContainer(cmd.Context(), client, cfg.Name, args[0])
+		containerId, err := docker.FindProjectContainer(cmd.Context(), client, cfg.Identifier(), args[0])
 
 		if err != nil {
 			return err
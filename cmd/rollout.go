@@ -0,0 +1,14 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var rolloutCmd = &cobra.Command{
+	Use:   "rollout",
+	Short: "Manage a canary deployment started with deploy --canary",
+}
+
+func init() {
+	rootCmd.AddCommand(rolloutCmd)
+}
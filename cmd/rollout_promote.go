@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"github.com/charmbracelet/log"
+	"github.com/shyim/tanjun/internal/config"
+	"github.com/shyim/tanjun/internal/docker"
+	"github.com/spf13/cobra"
+)
+
+var rolloutPromoteCmd = &cobra.Command{
+	Use:   "promote",
+	Short: "Routes all traffic to the canary version and replaces the workers and cronjobs",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.CreateConfig(configFile, environment)
+
+		if err != nil {
+			return err
+		}
+
+		client, err := docker.CreateClientFromConfig(cfg)
+
+		if err != nil {
+			return err
+		}
+
+		defer func() {
+			if err := client.Close(); err != nil {
+				log.Warnf("Failed to close docker client: %s", err)
+			}
+		}()
+
+		history := docker.NewHistoryEntry(docker.HistoryActionPromote)
+
+		state, err := docker.PromoteCanary(cmd.Context(), client, cfg)
+
+		if state == nil {
+			return err
+		}
+
+		history.Version = state.Version
+		history.Finish(err)
+		docker.RecordHistory(cmd.Context(), client, cfg.Identifier(), history)
+
+		return err
+	},
+}
+
+func init() {
+	rolloutCmd.AddCommand(rolloutPromoteCmd)
+}
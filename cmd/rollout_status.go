@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/shyim/tanjun/internal/config"
+	"github.com/shyim/tanjun/internal/docker"
+	"github.com/spf13/cobra"
+)
+
+var rolloutStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Shows the running canary deployment",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.CreateConfig(configFile, environment)
+
+		if err != nil {
+			return err
+		}
+
+		client, err := docker.CreateClientFromConfig(cfg)
+
+		if err != nil {
+			return err
+		}
+
+		defer func() {
+			if err := client.Close(); err != nil {
+				log.Warnf("Failed to close docker client: %s", err)
+			}
+		}()
+
+		kv, err := docker.CreateKVConnection(cmd.Context(), client)
+
+		if err != nil {
+			return err
+		}
+
+		defer kv.Close()
+
+		state, err := docker.GetCanaryState(kv, cfg.Identifier())
+
+		if err != nil {
+			return err
+		}
+
+		if state == nil {
+			log.Infof("There is no canary deployment running for project %s", cfg.Identifier())
+			return nil
+		}
+
+		log.Infof("Version %s receives %d%% of the traffic, started by %s at %s", state.Version, state.Percentage, state.User, state.StartedAt.Format(time.RFC3339))
+
+		return nil
+	},
+}
+
+func init() {
+	rolloutCmd.AddCommand(rolloutStatusCmd)
+}
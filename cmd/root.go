@@ -9,6 +9,7 @@ import (
 )
 
 var configFile = ".tanjun.yml"
+var environment = ""
 var projectRoot = ""
 var verboseMode = false
 var version = "dev"
@@ -30,6 +31,7 @@ func init() {
 	rootCmd.SilenceErrors = true
 	rootCmd.SilenceUsage = true
 	rootCmd.PersistentFlags().StringVar(&configFile, "config", configFile, "Path to the config file")
+	rootCmd.PersistentFlags().StringVar(&environment, "env", "", "Name of the environment to use from the environments section of the config")
 	rootCmd.PersistentFlags().StringVar(&projectRoot, "project-root", "", "Path to the project root, otherwise it will use the current directory")
 	rootCmd.PersistentFlags().BoolVar(&verboseMode, "verbose", false, "Show debug info")
 
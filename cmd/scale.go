@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/log"
+	"github.com/docker/docker/client"
+	"github.com/shyim/tanjun/internal/config"
+	"github.com/shyim/tanjun/internal/docker"
+	"github.com/spf13/cobra"
+)
+
+var scaleCmd = &cobra.Command{
+	Use:   "scale [web=replicas] [worker=replicas] ...",
+	Short: "Changes the amount of app containers and workers, without arguments the current scale is shown",
+	RunE: func(cmd *cobra.Command, args []string) (err error) {
+		cfg, err := config.CreateConfig(configFile, environment)
+
+		if err != nil {
+			return err
+		}
+
+		client, err := docker.CreateClientFromConfig(cfg)
+
+		if err != nil {
+			return err
+		}
+
+		defer func() {
+			if err := client.Close(); err != nil {
+				log.Warnf("Failed to close docker client: %s", err)
+			}
+		}()
+
+		if len(args) == 0 {
+			return printScale(cmd, cfg, client)
+		}
+
+		scale := make(map[string]int)
+
+		for _, arg := range args {
+			name, value, ok := strings.Cut(arg, "=")
+
+			if !ok {
+				return fmt.Errorf("invalid argument %s, expected name=replicas", arg)
+			}
+
+			replicas, err := strconv.Atoi(value)
+
+			if err != nil {
+				return fmt.Errorf("invalid replicas for %s: %w", name, err)
+			}
+
+			scale[name] = replicas
+		}
+
+		history := docker.NewHistoryEntry(docker.HistoryActionScale)
+		history.Details = strings.Join(args, ", ")
+
+		defer func() {
+			history.Finish(err)
+			docker.RecordHistory(cmd.Context(), client, cfg.Identifier(), history)
+		}()
+
+		return docker.Scale(cmd.Context(), client, cfg, scale)
+	},
+}
+
+func printScale(cmd *cobra.Command, cfg *config.ProjectConfig, client *client.Client) error {
+	kv, err := docker.CreateKVConnection(cmd.Context(), client)
+
+	if err != nil {
+		return err
+	}
+
+	defer kv.Close()
+
+	scale, err := docker.GetScale(kv, cfg.Identifier())
+
+	if err != nil {
+		return err
+	}
+
+	replicas := map[string]int{docker.ScaleWeb: cfg.App.Replicas}
+	configured := map[string]string{docker.ScaleWeb: fmt.Sprintf("configured %d", cfg.App.Replicas)}
+	notes := map[string]string{}
+
+	for name, worker := range cfg.App.Workers {
+		replicas[name] = docker.WorkerReplicas(worker)
+		configured[name] = fmt.Sprintf("configured %d", worker.Replicas)
+
+		// A worker without replicas runs one container, it is not disabled
+		if worker.Replicas == 0 {
+			configured[name] = "replicas not configured"
+			notes[name] = configured[name]
+		}
+	}
+
+	for name, scaled := range scale {
+		if _, ok := replicas[name]; ok {
+			replicas[name] = scaled
+			notes[name] = "set by tanjun scale, " + configured[name]
+		}
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(replicas)) {
+		if note, ok := notes[name]; ok {
+			fmt.Printf("%s=%d (%s)\n", name, replicas[name], note)
+			continue
+		}
+
+		fmt.Printf("%s=%d\n", name, replicas[name])
+	}
+
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(scaleCmd)
+}
@@ -1,6 +1,8 @@
 package cmd
 
 import (
+	"strings"
+
 	"github.com/charmbracelet/log"
 	"github.com/shyim/tanjun/internal/config"
 	"github.com/shyim/tanjun/internal/docker"
@@ -12,7 +14,7 @@ var secretDelCmd = &cobra.Command{
 	Short: "Delete an secret",
 	Args:  cobra.MinimumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		cfg, err := config.CreateConfig(configFile)
+		cfg, err := config.CreateConfig(configFile, environment)
 
 		if err != nil {
 			return err
@@ -36,21 +38,34 @@ var secretDelCmd = &cobra.Command{
 			return err
 		}
 
-		secrets, err := docker.ListProjectSecrets(kv, cfg.Name)
+		secrets, err := docker.ListProjectSecrets(kv, cfg.Identifier())
 
 		if err != nil {
 			return err
 		}
 
+		var keys []string
+
 		for _, arg := range args {
 			if _, ok := secrets[arg]; ok {
-				delete(secrets, arg)
+				keys = append(keys, arg)
 			} else {
 				log.Warnf("Secret %s not found. Skipping..\n", arg)
 			}
 		}
 
-		if err := docker.SetProjectSecrets(kv, cfg.Name, secrets); err != nil {
+		history := docker.NewHistoryEntry(docker.HistoryActionSecretDel)
+		history.Details = strings.Join(keys, ", ")
+
+		err = docker.DeleteProjectSecrets(kv, cfg.Identifier(), keys)
+
+		history.Finish(err)
+
+		if historyErr := docker.AppendHistory(kv, cfg.Identifier(), *history); historyErr != nil {
+			log.Warnf("Could not record history: %s", historyErr)
+		}
+
+		if err != nil {
 			return err
 		}
 
@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/charmbracelet/log"
+	"github.com/shyim/tanjun/internal/config"
+	"github.com/shyim/tanjun/internal/docker"
+	"github.com/spf13/cobra"
+)
+
+var secretDiffCmd = &cobra.Command{
+	Use:   "diff [from-revision] [to-revision]",
+	Short: "Shows the changes between two secret revisions, by default between the latest and the previous one",
+	Args:  cobra.MaximumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.CreateConfig(configFile, environment)
+
+		if err != nil {
+			return err
+		}
+
+		client, err := docker.CreateClientFromConfig(cfg)
+
+		if err != nil {
+			return err
+		}
+
+		defer func() {
+			if err := client.Close(); err != nil {
+				log.Warnf("Failed to close docker client: %s", err)
+			}
+		}()
+
+		kv, err := docker.CreateKVConnection(cmd.Context(), client)
+
+		if err != nil {
+			return err
+		}
+
+		defer kv.Close()
+
+		revisions, err := docker.ListSecretRevisions(kv, cfg.Identifier())
+
+		if err != nil {
+			return err
+		}
+
+		if len(revisions) == 0 {
+			log.Infof("The secrets have no revisions yet")
+			return nil
+		}
+
+		from := map[string]string{}
+		to := revisions[len(revisions)-1]
+
+		if len(revisions) > 1 {
+			from = revisions[len(revisions)-2].Secrets
+		}
+
+		if len(args) > 0 {
+			fromRevision, err := findSecretRevision(revisions, args[0])
+
+			if err != nil {
+				return err
+			}
+
+			from = fromRevision.Secrets
+		}
+
+		if len(args) > 1 {
+			toRevision, err := findSecretRevision(revisions, args[1])
+
+			if err != nil {
+				return err
+			}
+
+			to = *toRevision
+		}
+
+		changes := docker.DiffSecrets(from, to.Secrets)
+
+		if len(changes) == 0 {
+			log.Infof("No changes")
+			return nil
+		}
+
+		for _, change := range changes {
+			switch change.Type {
+			case docker.SecretChangeAdded:
+				fmt.Printf("+ %s=%s\n", change.Key, change.NewValue)
+			case docker.SecretChangeRemoved:
+				fmt.Printf("- %s=%s\n", change.Key, change.OldValue)
+			case docker.SecretChangeChanged:
+				fmt.Printf("~ %s: %s -> %s\n", change.Key, change.OldValue, change.NewValue)
+			}
+		}
+
+		return nil
+	},
+}
+
+func findSecretRevision(revisions []docker.SecretRevision, arg string) (*docker.SecretRevision, error) {
+	number, err := strconv.Atoi(arg)
+
+	if err != nil {
+		return nil, fmt.Errorf("invalid revision %s", arg)
+	}
+
+	for _, revision := range revisions {
+		if revision.Revision == number {
+			return &revision, nil
+		}
+	}
+
+	return nil, fmt.Errorf("secret revision %d not found", number)
+}
+
+func init() {
+	secretCmd.AddCommand(secretDiffCmd)
+}
@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/charmbracelet/log"
+	"github.com/joho/godotenv"
+	"github.com/shyim/tanjun/internal/config"
+	"github.com/shyim/tanjun/internal/docker"
+	"github.com/spf13/cobra"
+)
+
+var secretExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Prints all secrets as dotenv or JSON",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, _ := cmd.Flags().GetString("format")
+
+		if format != "dotenv" && format != "json" {
+			return fmt.Errorf("unsupported format %s, use dotenv or json", format)
+		}
+
+		cfg, err := config.CreateConfig(configFile, environment)
+
+		if err != nil {
+			return err
+		}
+
+		client, err := docker.CreateClientFromConfig(cfg)
+
+		if err != nil {
+			return err
+		}
+
+		defer func() {
+			if err := client.Close(); err != nil {
+				log.Warnf("Failed to close docker client: %s", err)
+			}
+		}()
+
+		kv, err := docker.CreateKVConnection(cmd.Context(), client)
+
+		if err != nil {
+			return err
+		}
+
+		defer kv.Close()
+
+		secrets, err := docker.ListProjectSecrets(kv, cfg.Identifier())
+
+		if err != nil {
+			return err
+		}
+
+		if format == "json" {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+
+			return encoder.Encode(secrets)
+		}
+
+		content, err := godotenv.Marshal(secrets)
+
+		if err != nil {
+			return err
+		}
+
+		fmt.Println(content)
+
+		return nil
+	},
+}
+
+func init() {
+	secretCmd.AddCommand(secretExportCmd)
+	secretExportCmd.Flags().String("format", "dotenv", "Output format, dotenv or json")
+}
@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/charmbracelet/log"
+	"github.com/shyim/tanjun/internal/config"
+	"github.com/shyim/tanjun/internal/docker"
+	"github.com/spf13/cobra"
+)
+
+var secretExportKeyCmd = &cobra.Command{
+	Use:   "export-key",
+	Short: "Prints the master key of the server, store it in a safe place outside of the server to restore the secrets when the key volume is lost",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.CreateConfig(configFile, environment)
+
+		if err != nil {
+			return err
+		}
+
+		client, err := docker.CreateClientFromConfig(cfg)
+
+		if err != nil {
+			return err
+		}
+
+		defer func() {
+			if err := client.Close(); err != nil {
+				log.Warnf("Failed to close docker client: %s", err)
+			}
+		}()
+
+		key, err := docker.ExportMasterKey(cmd.Context(), client)
+
+		if err != nil {
+			return err
+		}
+
+		fmt.Println(key)
+
+		return nil
+	},
+}
+
+func init() {
+	secretCmd.AddCommand(secretExportKeyCmd)
+}
@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/charmbracelet/lipgloss/table"
+	"github.com/charmbracelet/log"
+	"github.com/shyim/tanjun/internal/config"
+	"github.com/shyim/tanjun/internal/docker"
+	"github.com/spf13/cobra"
+)
+
+var secretHistoryCmd = &cobra.Command{
+	Use:   "history [key]",
+	Short: "Shows the revisions which changed the secret",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.CreateConfig(configFile, environment)
+
+		if err != nil {
+			return err
+		}
+
+		client, err := docker.CreateClientFromConfig(cfg)
+
+		if err != nil {
+			return err
+		}
+
+		defer func() {
+			if err := client.Close(); err != nil {
+				log.Warnf("Failed to close docker client: %s", err)
+			}
+		}()
+
+		kv, err := docker.CreateKVConnection(cmd.Context(), client)
+
+		if err != nil {
+			return err
+		}
+
+		defer kv.Close()
+
+		revisions, err := docker.ListSecretRevisions(kv, cfg.Identifier())
+
+		if err != nil {
+			return err
+		}
+
+		t := table.New().
+			Headers("Revision", "Date", "User", "Change", "Value")
+
+		previous := map[string]string{}
+		found := false
+
+		for _, revision := range revisions {
+			for _, change := range docker.DiffSecrets(previous, revision.Secrets) {
+				if change.Key != args[0] {
+					continue
+				}
+
+				found = true
+				t.Row(fmt.Sprintf("%d", revision.Revision), formatRelativeDate(revision.CreatedAt), formatSecretAuthor(revision), change.Type, change.NewValue)
+			}
+
+			previous = revision.Secrets
+		}
+
+		if !found {
+			log.Infof("No revisions found for secret %s", args[0])
+			return nil
+		}
+
+		fmt.Println(t.Render())
+
+		return nil
+	},
+}
+
+func formatSecretAuthor(revision docker.SecretRevision) string {
+	if revision.User == "" {
+		return "unknown"
+	}
+
+	return fmt.Sprintf("%s@%s", revision.User, revision.Host)
+}
+
+func init() {
+	secretCmd.AddCommand(secretHistoryCmd)
+}
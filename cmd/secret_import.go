@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/log"
+	"github.com/shyim/tanjun/internal/config"
+	"github.com/shyim/tanjun/internal/docker"
+	"github.com/spf13/cobra"
+)
+
+var secretImportCmd = &cobra.Command{
+	Use:   "import [file]",
+	Short: "Sets all secrets of a dotenv file, other secrets are kept",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		fileSecrets, err := docker.ReadEnvFile(args[0])
+
+		if err != nil {
+			return err
+		}
+
+		cfg, err := config.CreateConfig(configFile, environment)
+
+		if err != nil {
+			return err
+		}
+
+		client, err := docker.CreateClientFromConfig(cfg)
+
+		if err != nil {
+			return err
+		}
+
+		defer func() {
+			if err := client.Close(); err != nil {
+				log.Warnf("Failed to close docker client: %s", err)
+			}
+		}()
+
+		kv, err := docker.CreateKVConnection(cmd.Context(), client)
+
+		if err != nil {
+			return err
+		}
+
+		defer kv.Close()
+
+		history := docker.NewHistoryEntry(docker.HistoryActionSecretImport)
+		history.Details = strings.Join(sortedSecretKeys(fileSecrets), ", ")
+
+		err = docker.SetProjectSecrets(kv, cfg.Identifier(), fileSecrets)
+
+		history.Finish(err)
+
+		if historyErr := docker.AppendHistory(kv, cfg.Identifier(), *history); historyErr != nil {
+			log.Warnf("Could not record history: %s", historyErr)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		log.Printf("Imported %d secrets. You need to redeploy the project for the changes to take effect\n", len(fileSecrets))
+
+		return nil
+	},
+}
+
+func init() {
+	secretCmd.AddCommand(secretImportCmd)
+}
@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/charmbracelet/log"
+	"github.com/shyim/tanjun/internal/config"
+	"github.com/shyim/tanjun/internal/docker"
+	"github.com/spf13/cobra"
+)
+
+var secretImportKeyCmd = &cobra.Command{
+	Use:   "import-key [file]",
+	Short: "Restores a master key exported with export-key, the current master key of the server is kept",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		exported, err := os.ReadFile(args[0])
+
+		if err != nil {
+			return err
+		}
+
+		cfg, err := config.CreateConfig(configFile, environment)
+
+		if err != nil {
+			return err
+		}
+
+		client, err := docker.CreateClientFromConfig(cfg)
+
+		if err != nil {
+			return err
+		}
+
+		defer func() {
+			if err := client.Close(); err != nil {
+				log.Warnf("Failed to close docker client: %s", err)
+			}
+		}()
+
+		unreadable, err := docker.ImportMasterKey(cmd.Context(), client, exported)
+
+		if err != nil {
+			return err
+		}
+
+		if unreadable > 0 {
+			log.Warnf("Imported the master key, but %d values are still encrypted with an unknown key", unreadable)
+			return nil
+		}
+
+		log.Infof("Imported the master key, all encrypted values can be decrypted")
+
+		return nil
+	},
+}
+
+func init() {
+	secretCmd.AddCommand(secretImportKeyCmd)
+}
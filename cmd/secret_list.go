@@ -14,7 +14,7 @@ var secretListCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List all secrets",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		cfg, err := config.CreateConfig(configFile)
+		cfg, err := config.CreateConfig(configFile, environment)
 
 		if err != nil {
 			return err
@@ -38,7 +38,7 @@ var secretListCmd = &cobra.Command{
 			return err
 		}
 
-		secrets, err := docker.ListProjectSecrets(kv, cfg.Name)
+		secrets, err := docker.ListProjectSecrets(kv, cfg.Identifier())
 
 		if err != nil {
 			return err
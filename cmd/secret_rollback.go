@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/charmbracelet/log"
+	"github.com/shyim/tanjun/internal/config"
+	"github.com/shyim/tanjun/internal/docker"
+	"github.com/spf13/cobra"
+)
+
+var secretRollbackCmd = &cobra.Command{
+	Use:   "rollback [revision]",
+	Short: "Restores the secrets of a revision",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		revision, err := strconv.Atoi(args[0])
+
+		if err != nil {
+			return fmt.Errorf("invalid revision %s", args[0])
+		}
+
+		cfg, err := config.CreateConfig(configFile, environment)
+
+		if err != nil {
+			return err
+		}
+
+		client, err := docker.CreateClientFromConfig(cfg)
+
+		if err != nil {
+			return err
+		}
+
+		defer func() {
+			if err := client.Close(); err != nil {
+				log.Warnf("Failed to close docker client: %s", err)
+			}
+		}()
+
+		kv, err := docker.CreateKVConnection(cmd.Context(), client)
+
+		if err != nil {
+			return err
+		}
+
+		defer kv.Close()
+
+		history := docker.NewHistoryEntry(docker.HistoryActionSecretRollback)
+		history.Details = fmt.Sprintf("revision %d", revision)
+
+		err = docker.RollbackProjectSecrets(kv, cfg.Identifier(), revision)
+
+		history.Finish(err)
+
+		if historyErr := docker.AppendHistory(kv, cfg.Identifier(), *history); historyErr != nil {
+			log.Warnf("Could not record history: %s", historyErr)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		log.Printf("Secrets rolled back to revision %d. You need to redeploy the project for the changes to take effect\n", revision)
+
+		return nil
+	},
+}
+
+func init() {
+	secretCmd.AddCommand(secretRollbackCmd)
+}
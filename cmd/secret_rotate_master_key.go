@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"github.com/charmbracelet/log"
+	"github.com/shyim/tanjun/internal/config"
+	"github.com/shyim/tanjun/internal/docker"
+	"github.com/spf13/cobra"
+)
+
+var secretRotateMasterKeyCmd = &cobra.Command{
+	Use:   "rotate-master-key",
+	Short: "Replaces the master key of the server encrypting the secrets of all projects",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.CreateConfig(configFile, environment)
+
+		if err != nil {
+			return err
+		}
+
+		client, err := docker.CreateClientFromConfig(cfg)
+
+		if err != nil {
+			return err
+		}
+
+		defer func() {
+			if err := client.Close(); err != nil {
+				log.Warnf("Failed to close docker client: %s", err)
+			}
+		}()
+
+		rotated, err := docker.RotateMasterKey(cmd.Context(), client)
+
+		if err != nil {
+			return err
+		}
+
+		log.Infof("Rotated the master key, %d encrypted values were updated. Export the new key with tanjun secret export-key, a previous export cannot decrypt them anymore", rotated)
+
+		return nil
+	},
+}
+
+func init() {
+	secretCmd.AddCommand(secretRotateMasterKeyCmd)
+}
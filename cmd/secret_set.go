@@ -15,7 +15,7 @@ var secretSetCmd = &cobra.Command{
 	Short: "Set a secret",
 	Args:  cobra.MinimumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		cfg, err := config.CreateConfig(configFile)
+		cfg, err := config.CreateConfig(configFile, environment)
 
 		if err != nil {
 			return err
@@ -39,11 +39,10 @@ var secretSetCmd = &cobra.Command{
 			return err
 		}
 
-		secrets, err := docker.ListProjectSecrets(kv, cfg.Name)
+		defer kv.Close()
 
-		if err != nil {
-			return err
-		}
+		secrets := map[string]string{}
+		var keys []string
 
 		for _, arg := range args {
 			parts := strings.SplitN(arg, "=", 2)
@@ -52,9 +51,21 @@ var secretSetCmd = &cobra.Command{
 			}
 
 			secrets[parts[0]] = parts[1]
+			keys = append(keys, parts[0])
 		}
 
-		if err := docker.SetProjectSecrets(kv, cfg.Name, secrets); err != nil {
+		history := docker.NewHistoryEntry(docker.HistoryActionSecretSet)
+		history.Details = strings.Join(keys, ", ")
+
+		err = docker.SetProjectSecrets(kv, cfg.Identifier(), secrets)
+
+		history.Finish(err)
+
+		if historyErr := docker.AppendHistory(kv, cfg.Identifier(), *history); historyErr != nil {
+			log.Warnf("Could not record history: %s", historyErr)
+		}
+
+		if err != nil {
 			return err
 		}
 
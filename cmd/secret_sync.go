@@ -0,0 +1,155 @@
+package cmd
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/huh"
+	"github.com/charmbracelet/log"
+	"github.com/shyim/tanjun/internal/config"
+	"github.com/shyim/tanjun/internal/docker"
+	"github.com/spf13/cobra"
+)
+
+var secretSyncCmd = &cobra.Command{
+	Use:   "sync [file]",
+	Short: "Changes the secrets to match a dotenv file, --prune also deletes the secrets missing in the file",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		prune, _ := cmd.Flags().GetBool("prune")
+		pruneGenerated, _ := cmd.Flags().GetBool("prune-generated")
+		yes, _ := cmd.Flags().GetBool("yes")
+
+		fileSecrets, err := docker.ReadEnvFile(args[0])
+
+		if err != nil {
+			return err
+		}
+
+		cfg, err := config.CreateConfig(configFile, environment)
+
+		if err != nil {
+			return err
+		}
+
+		client, err := docker.CreateClientFromConfig(cfg)
+
+		if err != nil {
+			return err
+		}
+
+		defer func() {
+			if err := client.Close(); err != nil {
+				log.Warnf("Failed to close docker client: %s", err)
+			}
+		}()
+
+		kv, err := docker.CreateKVConnection(cmd.Context(), client)
+
+		if err != nil {
+			return err
+		}
+
+		defer kv.Close()
+
+		secrets, err := docker.ListProjectSecrets(kv, cfg.Identifier())
+
+		if err != nil {
+			return err
+		}
+
+		// Generated secrets are usually missing in the file, the data of the services would not be accessible anymore without them
+		var keep, kept []string
+
+		if !pruneGenerated {
+			keep = docker.GeneratedProjectSecrets(cfg)
+		}
+
+		var changes []docker.SecretChange
+
+		for _, change := range docker.DiffSecrets(secrets, fileSecrets) {
+			if change.Type == docker.SecretChangeRemoved {
+				if !prune {
+					continue
+				}
+
+				if slices.Contains(keep, change.Key) {
+					kept = append(kept, change.Key)
+					continue
+				}
+			}
+
+			changes = append(changes, change)
+		}
+
+		if len(kept) > 0 {
+			log.Infof("Keeping the generated secrets %s, use --prune-generated to delete them as well", strings.Join(kept, ", "))
+		}
+
+		if len(changes) == 0 {
+			log.Infof("The secrets are already in sync with %s", args[0])
+			return nil
+		}
+
+		for _, change := range changes {
+			switch change.Type {
+			case docker.SecretChangeAdded:
+				fmt.Printf("+ %s=%s\n", change.Key, docker.MaskSecret(change.NewValue))
+			case docker.SecretChangeRemoved:
+				fmt.Printf("- %s=%s\n", change.Key, docker.MaskSecret(change.OldValue))
+			case docker.SecretChangeChanged:
+				fmt.Printf("~ %s: %s -> %s\n", change.Key, docker.MaskSecret(change.OldValue), docker.MaskSecret(change.NewValue))
+			}
+		}
+
+		if !yes {
+			confirmed := false
+
+			if err := huh.NewConfirm().Title(fmt.Sprintf("Apply %d changes?", len(changes))).Value(&confirmed).Run(); err != nil {
+				return err
+			}
+
+			if !confirmed {
+				log.Infof("Aborted, no secrets were changed")
+				return nil
+			}
+		}
+
+		var keys []string
+
+		for _, change := range changes {
+			keys = append(keys, change.Key)
+		}
+
+		history := docker.NewHistoryEntry(docker.HistoryActionSecretSync)
+		history.Details = strings.Join(keys, ", ")
+
+		if prune {
+			err = docker.ReplaceProjectSecrets(kv, cfg.Identifier(), fileSecrets, keep)
+		} else {
+			err = docker.SetProjectSecrets(kv, cfg.Identifier(), fileSecrets)
+		}
+
+		history.Finish(err)
+
+		if historyErr := docker.AppendHistory(kv, cfg.Identifier(), *history); historyErr != nil {
+			log.Warnf("Could not record history: %s", historyErr)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		log.Printf("Secrets synced with %s. You need to redeploy the project for the changes to take effect\n", args[0])
+
+		return nil
+	},
+}
+
+func init() {
+	secretCmd.AddCommand(secretSyncCmd)
+	secretSyncCmd.Flags().Bool("prune", false, "Delete the secrets which are missing in the file")
+	secretSyncCmd.Flags().Bool("prune-generated", false, "Delete the generated secrets like service credentials and initial secrets with --prune as well")
+	secretSyncCmd.Flags().BoolP("yes", "y", false, "Apply the changes without asking for confirmation")
+}
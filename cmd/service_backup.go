@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/shyim/tanjun/internal/config"
+	"github.com/shyim/tanjun/internal/docker"
+	"github.com/spf13/cobra"
+)
+
+var serviceBackupCmd = &cobra.Command{
+	Use:   "backup [name]",
+	Short: "Dumps the data of a database service into a local gzip compressed file",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) (err error) {
+		cfg, err := config.CreateConfig(configFile, environment)
+
+		if err != nil {
+			return err
+		}
+
+		output, _ := cmd.Flags().GetString("output")
+
+		if output == "" {
+			output = fmt.Sprintf("%s-%s-%s.sql.gz", cfg.Identifier(), args[0], time.Now().Format("20060102-150405"))
+		}
+
+		client, err := docker.CreateClientFromConfig(cfg)
+
+		if err != nil {
+			return err
+		}
+
+		defer func() {
+			if err := client.Close(); err != nil {
+				log.Warnf("Failed to close docker client: %s", err)
+			}
+		}()
+
+		var w io.Writer = os.Stdout
+
+		if output != "-" {
+			file, err := os.Create(output)
+
+			if err != nil {
+				return err
+			}
+
+			defer func() {
+				if closeErr := file.Close(); err == nil {
+					err = closeErr
+				}
+
+				if err != nil {
+					_ = os.Remove(output)
+				}
+			}()
+
+			w = file
+		}
+
+		if err := docker.BackupService(cmd.Context(), client, cfg, args[0], w); err != nil {
+			return err
+		}
+
+		if output != "-" {
+			log.Infof("Backup of service %s written to %s", args[0], output)
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	serviceCmd.AddCommand(serviceBackupCmd)
+	serviceBackupCmd.Flags().StringP("output", "o", "", "File to write the backup to, - writes to stdout. Defaults to <project>-<service>-<time>.sql.gz")
+}
@@ -0,0 +1,14 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var serviceBackupsCmd = &cobra.Command{
+	Use:   "backups",
+	Short: "Browse the scheduled backups stored on the server",
+}
+
+func init() {
+	serviceCmd.AddCommand(serviceBackupsCmd)
+}
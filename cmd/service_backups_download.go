@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/charmbracelet/log"
+	"github.com/shyim/tanjun/internal/config"
+	"github.com/shyim/tanjun/internal/docker"
+	"github.com/spf13/cobra"
+)
+
+var serviceBackupsDownloadCmd = &cobra.Command{
+	Use:   "download [service] [backup]",
+	Short: "Downloads a stored backup of a service",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) (err error) {
+		cfg, err := config.CreateConfig(configFile, environment)
+
+		if err != nil {
+			return err
+		}
+
+		output, _ := cmd.Flags().GetString("output")
+
+		if output == "" {
+			output = fmt.Sprintf("%s-%s-%s", cfg.Identifier(), args[0], args[1])
+		}
+
+		client, err := docker.CreateClientFromConfig(cfg)
+
+		if err != nil {
+			return err
+		}
+
+		defer func() {
+			if err := client.Close(); err != nil {
+				log.Warnf("Failed to close docker client: %s", err)
+			}
+		}()
+
+		var w io.Writer = os.Stdout
+
+		if output != "-" {
+			file, err := os.Create(output)
+
+			if err != nil {
+				return err
+			}
+
+			defer func() {
+				if closeErr := file.Close(); err == nil {
+					err = closeErr
+				}
+
+				if err != nil {
+					_ = os.Remove(output)
+				}
+			}()
+
+			w = file
+		}
+
+		if err := docker.DownloadServiceBackup(cmd.Context(), client, cfg, args[0], args[1], w); err != nil {
+			return err
+		}
+
+		if output != "-" {
+			log.Infof("Backup %s of service %s written to %s", args[1], args[0], output)
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	serviceBackupsCmd.AddCommand(serviceBackupsDownloadCmd)
+	serviceBackupsDownloadCmd.Flags().StringP("output", "o", "", "File to write the backup to, - writes to stdout. Defaults to <project>-<service>-<backup>")
+}
@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/charmbracelet/lipgloss/table"
+	"github.com/charmbracelet/log"
+	"github.com/shyim/tanjun/internal/config"
+	"github.com/shyim/tanjun/internal/docker"
+	"github.com/spf13/cobra"
+)
+
+var serviceBackupsListCmd = &cobra.Command{
+	Use:   "list [service]",
+	Short: "Lists the stored backups of a service, newest first",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.CreateConfig(configFile, environment)
+
+		if err != nil {
+			return err
+		}
+
+		client, err := docker.CreateClientFromConfig(cfg)
+
+		if err != nil {
+			return err
+		}
+
+		defer func() {
+			if err := client.Close(); err != nil {
+				log.Warnf("Failed to close docker client: %s", err)
+			}
+		}()
+
+		backups, err := docker.ListServiceBackups(cmd.Context(), client, cfg, args[0])
+
+		if err != nil {
+			return err
+		}
+
+		t := table.New().
+			Headers("Name", "Size", "Created at")
+
+		for _, backup := range backups {
+			t.Row(backup.Name, formatBytes(backup.Size), formatRelativeDate(backup.CreatedAt))
+		}
+
+		fmt.Println(t.Render())
+
+		log.Infof("Found %d backups of service %s", len(backups), args[0])
+
+		return nil
+	},
+}
+
+func init() {
+	serviceBackupsCmd.AddCommand(serviceBackupsListCmd)
+}
@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"github.com/charmbracelet/log"
+	"github.com/shyim/tanjun/internal/config"
+	"github.com/shyim/tanjun/internal/docker"
+	"github.com/spf13/cobra"
+)
+
+var serviceBackupsRestoreCmd = &cobra.Command{
+	Use:   "restore [service] [backup]",
+	Short: "Restores a stored backup into the service",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.CreateConfig(configFile, environment)
+
+		if err != nil {
+			return err
+		}
+
+		client, err := docker.CreateClientFromConfig(cfg)
+
+		if err != nil {
+			return err
+		}
+
+		defer func() {
+			if err := client.Close(); err != nil {
+				log.Warnf("Failed to close docker client: %s", err)
+			}
+		}()
+
+		if err := docker.RestoreServiceBackup(cmd.Context(), client, cfg, args[0], args[1]); err != nil {
+			return err
+		}
+
+		log.Infof("Restored backup %s into service %s", args[1], args[0])
+
+		return nil
+	},
+}
+
+func init() {
+	serviceBackupsCmd.AddCommand(serviceBackupsRestoreCmd)
+}
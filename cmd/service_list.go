@@ -14,7 +14,7 @@ var serviceList = &cobra.Command{
 	Use:   "list",
 	Short: "List all services",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		cfg, err := config.CreateConfig(configFile)
+		cfg, err := config.CreateConfig(configFile, environment)
 
 		if err != nil {
 			return err
@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"io"
+	"os"
+
+	"github.com/charmbracelet/log"
+	"github.com/shyim/tanjun/internal/config"
+	"github.com/shyim/tanjun/internal/docker"
+	"github.com/spf13/cobra"
+)
+
+var serviceRestoreCmd = &cobra.Command{
+	Use:   "restore [name] [file]",
+	Short: "Restores a dump created with service backup into a database service, - reads from stdin",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.CreateConfig(configFile, environment)
+
+		if err != nil {
+			return err
+		}
+
+		var r io.Reader = os.Stdin
+
+		if args[1] != "-" {
+			file, err := os.Open(args[1])
+
+			if err != nil {
+				return err
+			}
+
+			defer file.Close()
+
+			r = file
+		}
+
+		client, err := docker.CreateClientFromConfig(cfg)
+
+		if err != nil {
+			return err
+		}
+
+		defer func() {
+			if err := client.Close(); err != nil {
+				log.Warnf("Failed to close docker client: %s", err)
+			}
+		}()
+
+		if err := docker.RestoreService(cmd.Context(), client, cfg, args[0], r); err != nil {
+			return err
+		}
+
+		log.Infof("Restored service %s from %s", args[0], args[1])
+
+		return nil
+	},
+}
+
+func init() {
+	serviceCmd.AddCommand(serviceRestoreCmd)
+}
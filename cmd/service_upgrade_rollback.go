@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"github.com/charmbracelet/log"
+	"github.com/shyim/tanjun/internal/config"
+	"github.com/shyim/tanjun/internal/docker"
+	"github.com/spf13/cobra"
+)
+
+var serviceUpgradeRollbackCmd = &cobra.Command{
+	Use:   "upgrade-rollback [name]",
+	Short: "Restores the volumes of a service from the snapshot taken before its last upgrade",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.CreateConfig(configFile, environment)
+
+		if err != nil {
+			return err
+		}
+
+		client, err := docker.CreateClientFromConfig(cfg)
+
+		if err != nil {
+			return err
+		}
+
+		defer func() {
+			if err := client.Close(); err != nil {
+				log.Warnf("Failed to close docker client: %s", err)
+			}
+		}()
+
+		image, err := docker.RollbackServiceUpgrade(cmd.Context(), client, cfg.Identifier(), args[0])
+
+		if err != nil {
+			return err
+		}
+
+		log.Infof("Restored the volumes of service %s, the snapshot was taken with image %s. Change the type of the service back and deploy to start it again", args[0], image)
+
+		return nil
+	},
+}
+
+func init() {
+	serviceCmd.AddCommand(serviceUpgradeRollbackCmd)
+}
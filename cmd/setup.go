@@ -12,26 +12,32 @@ var setupCmd = &cobra.Command{
 	Use:   "setup",
 	Short: "Setups a server for initial deployment",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		cfg, err := config.CreateConfig(configFile)
+		cfg, err := config.CreateConfig(configFile, environment)
 
 		if err != nil {
 			return err
 		}
 
-		if cfg.Server.Address != "127.0.0.1" {
-			if err := server.Setup(cmd.Context(), cfg.Server); err != nil {
-				return err
+		for _, projectServer := range cfg.AllServers() {
+			if projectServer.Address != "127.0.0.1" {
+				if err := server.Setup(cmd.Context(), projectServer); err != nil {
+					return err
+				}
 			}
-		}
 
-		client, err := docker.CreateClientFromConfig(cfg)
+			client, err := docker.CreateClientForServer(projectServer)
 
-		if err != nil {
-			return err
-		}
+			if err != nil {
+				return err
+			}
 
-		if err := docker.ConfigureServer(cmd.Context(), client); err != nil {
-			return err
+			if err := docker.ConfigureServer(cmd.Context(), client); err != nil {
+				return err
+			}
+
+			if err := client.Close(); err != nil {
+				log.Warnf("Failed to close docker client: %s", err)
+			}
 		}
 
 		log.Print("Server setup complete\n")
@@ -20,7 +20,7 @@ var shellCmd = &cobra.Command{
 	Use:   "shell",
 	Short: "Opens a shell to the app container or shell",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		cfg, err := config.CreateConfig(configFile)
+		cfg, err := config.CreateConfig(configFile, environment)
 
 		if err != nil {
 			return err
@@ -40,7 +40,7 @@ var shellCmd = &cobra.Command{
 
 		serviceName, _ := cmd.PersistentFlags().GetString("service")
 
-		containerId, err := docker.FindProjectContainer(cmd.Context(), client, cfg.Name, serviceName)
+		containerId, err := docker.FindProjectContainer(cmd.Context(), client, cfg.Identifier(), serviceName)
 
 		if err != nil {
 			return err
@@ -15,7 +15,7 @@ var versionListCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List all versions of an image",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		cfg, err := config.CreateConfig(configFile)
+		cfg, err := config.CreateConfig(configFile, environment)
 
 		if err != nil {
 			return err
@@ -11,7 +11,7 @@ var versionPruneCmd = &cobra.Command{
 	Use:   "prune",
 	Short: "Remove old versions of images",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		cfg, err := config.CreateConfig(configFile)
+		cfg, err := config.CreateConfig(configFile, environment)
 
 		if err != nil {
 			return err
@@ -14,12 +14,24 @@ target "kv-store" {
   context = "."
   dockerfile = "kv-store/Dockerfile"
   platforms = ["linux/amd64", "linux/arm64"]
-  tags = ["ghcr.io/shyim/tanjun/kv-store:v1"]
+  tags = ["ghcr.io/shyim/tanjun/kv-store:v3"]
 }
 
 target "scheduler" {
   context = "."
   dockerfile = "scheduler/Dockerfile"
   platforms = ["linux/amd64", "linux/arm64"]
-  tags = ["ghcr.io/shyim/tanjun/scheduler:v1"]
-}
\ No newline at end of file
+  tags = ["ghcr.io/shyim/tanjun/scheduler:v2"]
+}
+
+target "maintenance" {
+  context = "./maintenance"
+  platforms = ["linux/amd64", "linux/arm64"]
+  tags = ["ghcr.io/shyim/tanjun/maintenance:v1"]
+}
+
+target "auth-proxy" {
+  context = "./auth-proxy"
+  platforms = ["linux/amd64", "linux/arm64"]
+  tags = ["ghcr.io/shyim/tanjun/auth-proxy:v1"]
+}
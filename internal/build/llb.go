@@ -13,6 +13,7 @@ import (
 	"github.com/moby/buildkit/solver/pb"
 	imageSpecsV1 "github.com/opencontainers/image-spec/specs-go/v1"
 	"github.com/shyim/tanjun/internal/config"
+	"github.com/shyim/tanjun/internal/docker"
 )
 
 func llbFromProject(ctx context.Context, info system.Info) (string, *llb.Definition, error) {
@@ -32,6 +33,15 @@ func llbFromProject(ctx context.Context, info system.Info) (string, *llb.Definit
 
 	caps := pb.Caps.CapSet(pb.Caps.All())
 
+	// the project label keeps environments sharing the image repository from draining each other's versions
+	labels := map[string]string{}
+
+	for key, value := range configFile.Build.Labels {
+		labels[key] = value
+	}
+
+	labels[docker.ImageProjectLabel] = configFile.Identifier()
+
 	local := llb.Local("context", llb.ExcludePatterns(dockerIgnore))
 	state, img, _, _, err := dockerfile2llb.Dockerfile2LLB(ctx, dockerFile, dockerfile2llb.ConvertOpt{
 		MainContext:  &local,
@@ -42,7 +52,7 @@ func llbFromProject(ctx context.Context, info system.Info) (string, *llb.Definit
 			Architecture: architecture,
 		},
 		Config: dockerui.Config{
-			Labels:    configFile.Build.Labels,
+			Labels:    labels,
 			BuildArgs: configFile.Build.BuildArgs,
 		},
 	})
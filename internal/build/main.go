@@ -26,6 +26,8 @@ const contextRootPathField contextRootPath = "rootPath"
 const contextDockerClientField contextDockerClient = "dockerClient"
 const contextRemoteClientField contextRemoteClient = "remoteClient"
 
+// BuildImage builds the project and returns the version. When the build fails after the version was determined, the
+// version is returned with the error
 func BuildImage(ctx context.Context, config *config.ProjectConfig, root string) (string, error) {
 	var dockerClient *client.Client
 	var err error
@@ -178,15 +180,15 @@ func BuildImage(ctx context.Context, config *config.ProjectConfig, root string)
 	_, err = builder.Solve(ctx, def, *solveOpt, createSolveChan(ctx))
 
 	if err != nil {
-		return "", err
+		return version, err
 	}
 
 	if config.Build.RemoteBuild {
 		log.Debugf("Loading image to local docker registry")
 	}
 
-	if <-waitChain != nil {
-		return "", err
+	if err := <-waitChain; err != nil {
+		return version, err
 	}
 
 	return version, nil
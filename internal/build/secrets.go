@@ -3,6 +3,7 @@ package build
 import (
 	"context"
 	"fmt"
+	"maps"
 	"os"
 	"sync"
 
@@ -10,18 +11,21 @@ import (
 	"github.com/shyim/tanjun/internal/config"
 	"github.com/shyim/tanjun/internal/docker"
 	"github.com/shyim/tanjun/internal/onepassword"
+	"github.com/shyim/tanjun/internal/secretfile"
 )
 
 type secretStore struct {
-	config              *config.ProjectConfig
-	remoteClient        *client.Client
-	secrets             map[string]string
-	onePasswordResolved map[string]string
+	config          *config.ProjectConfig
+	remoteClient    *client.Client
+	secrets         map[string]string
+	resolvedSecrets map[string]string
 }
 
+// secretLock guards the resolved secrets, buildkit requests the secrets of parallel build steps concurrently
 var secretLock = sync.Mutex{}
 
-func (s secretStore) GetSecret(ctx context.Context, secret string) ([]byte, error) {
+// GetSecret resolves the secret sources only once per build, the store is shared by all requests of the build
+func (s *secretStore) GetSecret(ctx context.Context, secret string) ([]byte, error) {
 	if fieldName, ok := s.config.Build.Secrets.FromEnv[secret]; ok {
 		if fieldName == "" {
 			fieldName = secret
@@ -34,23 +38,17 @@ func (s secretStore) GetSecret(ctx context.Context, secret string) ([]byte, erro
 		return nil, fmt.Errorf("could not found value for secret %s: using environment value %s", secret, fieldName)
 	}
 
-	if s.onePasswordResolved == nil {
-		s.onePasswordResolved = make(map[string]string)
-		secretLock.Lock()
+	secretLock.Lock()
+	defer secretLock.Unlock()
 
-		for _, secret := range s.config.Build.Secrets.OnePassword.Secret {
-			onePasswordSecrets, err := onepassword.ResolveSecrets(ctx, secret)
+	if s.resolvedSecrets == nil {
+		resolved, err := s.resolveFiles(ctx)
 
-			if err != nil {
-				return nil, err
-			}
-
-			for key, value := range onePasswordSecrets {
-				s.onePasswordResolved[key] = value
-			}
+		if err != nil {
+			return nil, err
 		}
 
-		secretLock.Unlock()
+		s.resolvedSecrets = resolved
 	}
 
 	if fieldName, ok := s.config.Build.Secrets.FromStored[secret]; ok {
@@ -59,24 +57,20 @@ func (s secretStore) GetSecret(ctx context.Context, secret string) ([]byte, erro
 		}
 
 		if s.secrets == nil {
-			secretLock.Lock()
-
-			defer secretLock.Unlock()
-
 			kv, err := docker.CreateKVConnection(ctx, s.remoteClient)
 
 			if err != nil {
 				return nil, err
 			}
 
-			secrets, err := docker.ListProjectSecrets(kv, s.config.Name)
+			secrets, err := docker.ListProjectSecrets(kv, s.config.Identifier())
+
+			kv.Close()
 
 			if err != nil {
 				return nil, err
 			}
 
-			kv.Close()
-
 			s.secrets = secrets
 		}
 
@@ -87,9 +81,47 @@ func (s secretStore) GetSecret(ctx context.Context, secret string) ([]byte, erro
 		return nil, fmt.Errorf("could not found value for secret %s: using stored value %s", secret, fieldName)
 	}
 
-	if val, ok := s.onePasswordResolved[secret]; ok {
+	if val, ok := s.resolvedSecrets[secret]; ok {
 		return []byte(val), nil
 	}
 
 	return nil, fmt.Errorf("could not found source for secret \"%s\". Did you maybe forgot to add the secret to your .tanjun.yml", secret)
 }
+
+// resolveFiles decrypts the encrypted files and fetches the 1Password items, the encrypted files are resolved first,
+// so 1Password wins like for the runtime secrets
+func (s *secretStore) resolveFiles(ctx context.Context) (map[string]string, error) {
+	resolved := make(map[string]string)
+
+	for _, file := range s.config.Build.Secrets.FromSops {
+		fileSecrets, err := secretfile.ResolveSops(ctx, file)
+
+		if err != nil {
+			return nil, err
+		}
+
+		maps.Copy(resolved, fileSecrets)
+	}
+
+	for _, file := range s.config.Build.Secrets.FromAge {
+		fileSecrets, err := secretfile.ResolveAge(ctx, file)
+
+		if err != nil {
+			return nil, err
+		}
+
+		maps.Copy(resolved, fileSecrets)
+	}
+
+	for _, secret := range s.config.Build.Secrets.OnePassword.Secret {
+		onePasswordSecrets, err := onepassword.ResolveSecrets(ctx, secret)
+
+		if err != nil {
+			return nil, err
+		}
+
+		maps.Copy(resolved, onePasswordSecrets)
+	}
+
+	return resolved, nil
+}
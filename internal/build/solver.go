@@ -59,7 +59,7 @@ func getSolveConfiguration(ctx context.Context, containerConfig string) (string,
 		}
 	}
 
-	attachables = append(attachables, secretsprovider.NewSecretProvider(secretStore{
+	attachables = append(attachables, secretsprovider.NewSecretProvider(&secretStore{
 		config:       configFile,
 		remoteClient: ctx.Value(contextRemoteClientField).(*client.Client),
 	}))
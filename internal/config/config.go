@@ -2,8 +2,13 @@ package config
 
 import (
 	"fmt"
+	"maps"
+	"net"
 	"os"
+	"path/filepath"
 	"regexp"
+	"slices"
+	"strings"
 
 	"github.com/shyim/tanjun/internal/buildpack"
 
@@ -15,6 +20,9 @@ import (
 
 var validHostName = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
 
+// validExposeHost matches a fully qualified domain name
+var validExposeHost = regexp.MustCompile(`^([a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?\.)+[a-z]{2,}$`)
+
 type IncludeConfig struct {
 	Include []string `yaml:"include,omitempty"`
 }
@@ -32,23 +40,167 @@ type ProjectConfig struct {
 		BuildArgs            map[string]string `yaml:"args,omitempty"`
 		PassThroughSSHSocket bool              `yaml:"passthroughs_ssh_socket,omitempty"`
 		Secrets              struct {
-			FromEnv     ProjectFromEnv `yaml:"from_env,omitempty"`
-			FromStored  ProjectFromEnv `yaml:"from_stored,omitempty"`
+			FromEnv     ProjectFromEnv   `yaml:"from_env,omitempty"`
+			FromStored  ProjectFromEnv   `yaml:"from_stored,omitempty"`
+			FromSops    []string         `yaml:"from_sops,omitempty"`
+			FromAge     []ProjectAgeFile `yaml:"from_age,omitempty"`
 			OnePassword struct {
 				Secret []ProjectOnePassword `yaml:"items,omitempty"`
 			} `yaml:"onepassword,omitempty"`
 		} `yaml:"secrets,omitempty"`
 	} `yaml:"build,omitempty"`
-	Server   ProjectServer             `yaml:"server" jsonschema:"required"`
-	Proxy    ProjectProxy              `yaml:"proxy"`
-	App      ProjectApp                `yaml:"app,omitempty"`
-	Services map[string]ProjectService `yaml:"services,omitempty"`
+	Server       ProjectServer                           `yaml:"server" jsonschema:"required"`
+	Servers      []ProjectServer                         `yaml:"servers,omitempty"`
+	Rollout      string                                  `yaml:"rollout,omitempty" jsonschema:"enum=sequential,enum=parallel"`
+	Proxy        ProjectProxy                            `yaml:"proxy"`
+	App          ProjectApp                              `yaml:"app,omitempty"`
+	Services     map[string]ProjectService               `yaml:"services,omitempty"`
+	Environments map[string]ProjectDeploymentEnvironment `yaml:"environments,omitempty"`
+	Preview      ProjectPreview                          `yaml:"preview,omitempty"`
+
+	// Environment is the selected entry of Environments, empty when the base configuration is used
+	Environment string `yaml:"-"`
+	// PreviewName is set on the configuration returned by ForPreview
+	PreviewName string `yaml:"-"`
+	// AllowServiceUpgrade permits changing a service to another data version, set by deploy --allow-service-upgrade
+	AllowServiceUpgrade bool `yaml:"-"`
+	// previewParent is the identifier of the project the preview was derived from
+	previewParent string
+}
+
+// ProjectPreview configures the throwaway environments created with tanjun preview deploy
+type ProjectPreview struct {
+	// Host of the previews, {name} is replaced with the preview name. Defaults to {name}.<proxy.host>
+	Host string `yaml:"host,omitempty"`
+	// ExpireAfter is the amount of seconds after the last deployment when a preview is considered expired, defaults to 7 days
+	ExpireAfter int `yaml:"expire_after,omitempty"`
+	// Seed copies the data of these services from the project into a new preview
+	Seed []string `yaml:"seed,omitempty"`
+}
+
+// The parts of Identifier are joined with underscores. Project, environment and preview names are valid host names,
+// so they cannot contain one and e.g. project foo-bar cannot collide with environment bar of project foo
+const (
+	environmentSeparator = "_"
+	previewSeparator     = "__"
+)
+
+// Identifier is used to scope all resources of the project on the server.
+// A named environment gets its own scope, so multiple environments can share one Docker host.
+func (p *ProjectConfig) Identifier() string {
+	if p.PreviewName != "" {
+		return p.previewParent + previewSeparator + p.PreviewName
+	}
+
+	if p.Environment == "" {
+		return p.Name
+	}
+
+	return p.Name + environmentSeparator + p.Environment
+}
+
+// PreviewParent returns the identifier of the project a preview was derived from
+func (p *ProjectConfig) PreviewParent() string {
+	return p.previewParent
+}
+
+// ForPreview returns a copy of the configuration for the preview with the given name.
+// The preview gets its own identifier and host and runs only on the primary server
+func (p *ProjectConfig) ForPreview(name string) (*ProjectConfig, error) {
+	if p.PreviewName != "" {
+		return nil, fmt.Errorf("cannot create a preview of the preview %s", p.PreviewName)
+	}
+
+	if !validHostName.MatchString(name) {
+		return nil, fmt.Errorf("the preview name %s cannot contain special symbols as this needs to be resolable with DNS", name)
+	}
+
+	preview := p.clone()
+	preview.PreviewName = name
+	preview.previewParent = p.Identifier()
+	preview.Servers = nil
+	preview.Server.Roles = nil
+
+	if p.Preview.Host != "" {
+		preview.Proxy.Host = strings.ReplaceAll(p.Preview.Host, "{name}", name)
+	} else {
+		preview.Proxy.Host = fmt.Sprintf("%s.%s", name, p.Proxy.Host)
+	}
+
+	return preview, nil
+}
+
+// clone copies the configuration including its maps and slices, so changing the copy e.g. while applying the
+// stored scale of a preview cannot change the configuration it was derived from
+func (p *ProjectConfig) clone() *ProjectConfig {
+	c := *p
+	c.Include = slices.Clone(p.Include)
+	c.Build.Labels = maps.Clone(p.Build.Labels)
+	c.Build.BuildArgs = maps.Clone(p.Build.BuildArgs)
+	c.Servers = slices.Clone(p.Servers)
+	c.App.Environment = maps.Clone(p.App.Environment)
+	c.App.InitialSecrets = maps.Clone(p.App.InitialSecrets)
+	c.App.Mounts = maps.Clone(p.App.Mounts)
+	c.App.Workers = maps.Clone(p.App.Workers)
+	c.App.Cronjobs = slices.Clone(p.App.Cronjobs)
+	c.Environments = maps.Clone(p.Environments)
+	c.Preview.Seed = slices.Clone(p.Preview.Seed)
+
+	if p.Services != nil {
+		c.Services = make(map[string]ProjectService, len(p.Services))
+
+		for name, service := range p.Services {
+			service.Settings = maps.Clone(service.Settings)
+			service.Environment = maps.Clone(service.Environment)
+			service.Custom.Command = slices.Clone(service.Custom.Command)
+			service.Custom.Ports = slices.Clone(service.Custom.Ports)
+			service.Custom.Volumes = maps.Clone(service.Custom.Volumes)
+			c.Services[name] = service
+		}
+	}
+
+	return &c
+}
+
+type ProjectDeploymentEnvironment struct {
+	Server *ProjectServer `yaml:"server,omitempty"`
+	Proxy  struct {
+		Host string `yaml:"host,omitempty"`
+	} `yaml:"proxy,omitempty"`
+	Env      map[string]ProjectEnvironment `yaml:"env,omitempty"`
+	Services map[string]ProjectService     `yaml:"services,omitempty"`
+	Workers  map[string]ProjectWorker      `yaml:"workers,omitempty"`
 }
 
+const (
+	ServerRoleWeb    = "web"
+	ServerRoleWorker = "worker"
+	ServerRoleCron   = "cron"
+)
+
+var validServerRoles = []string{ServerRoleWeb, ServerRoleWorker, ServerRoleCron}
+
 type ProjectServer struct {
-	Address  string `yaml:"address" jsonschema:"required"`
-	Username string `yaml:"username,omitempty"`
-	Port     int    `yaml:"port,omitempty"`
+	Address        string   `yaml:"address" jsonschema:"required"`
+	Username       string   `yaml:"username,omitempty"`
+	Port           int      `yaml:"port,omitempty"`
+	Roles          []string `yaml:"roles,omitempty" jsonschema:"enum=web,enum=worker,enum=cron"`
+	PrivateAddress string   `yaml:"private_address,omitempty"`
+}
+
+// HasRole reports whether the server should run the given part of the app. A server without roles runs everything
+func (s ProjectServer) HasRole(role string) bool {
+	if len(s.Roles) == 0 {
+		return true
+	}
+
+	return slices.Contains(s.Roles, role)
+}
+
+// AllServers returns the primary server followed by the additional servers.
+// The primary server runs the services, the key value store and the deploy hooks.
+func (p *ProjectConfig) AllServers() []ProjectServer {
+	return append([]ProjectServer{p.Server}, p.Servers...)
 }
 
 type ProjectProxy struct {
@@ -68,6 +220,34 @@ type ProjectProxy struct {
 		MaxResponseBody int  `yaml:"max_response_body,omitempty"`
 		Memory          int  `yaml:"memory,omitempty"`
 	} `yaml:"buffering,omitempty"`
+	Verify      *ProjectProxyVerify `yaml:"verify,omitempty"`
+	Maintenance ProjectMaintenance  `yaml:"maintenance,omitempty"`
+}
+
+// ProjectMaintenance configures the page served by tanjun maintenance on
+type ProjectMaintenance struct {
+	Message string `yaml:"message,omitempty"`
+	// AllowIPs can still reach the app, IPs or CIDR ranges
+	AllowIPs []string `yaml:"allow_ips,omitempty"`
+	// DuringDeploy serves the maintenance page while the deploy hook runs
+	DuringDeploy bool `yaml:"during_deploy,omitempty"`
+}
+
+// ProjectProxyVerify checks the new version after the traffic was switched to it, a failing check rolls the deployment back
+type ProjectProxyVerify struct {
+	// GracePeriod is the time in seconds the checks are repeated
+	GracePeriod int                  `yaml:"grace_period,omitempty" jsonschema:"default=30"`
+	Interval    int                  `yaml:"interval,omitempty" jsonschema:"default=5"`
+	Probes      []ProjectVerifyProbe `yaml:"probes,omitempty"`
+	// Command is executed in the new app container and has to exit with 0
+	Command string `yaml:"command,omitempty"`
+}
+
+type ProjectVerifyProbe struct {
+	Path string `yaml:"path" jsonschema:"required"`
+	// Status is the expected status code, without it every 2xx and 3xx status is accepted
+	Status   int    `yaml:"status,omitempty"`
+	Contains string `yaml:"contains,omitempty"`
 }
 
 func (p ProjectProxy) GetURL() string {
@@ -94,6 +274,8 @@ type ProjectApp struct {
 	InitialSecrets map[string]ProjectInitialSecrets `yaml:"initial_secrets,omitempty"`
 	Secrets        ProjectGenericSecrets            `yaml:"secrets,omitempty"`
 	Mounts         map[string]ProjectMount          `yaml:"mounts,omitempty"`
+	Replicas       int                              `yaml:"replicas,omitempty" jsonschema:"default=1"`
+	KeepWarm       ProjectKeepWarm                  `yaml:"keep_warm,omitempty"`
 	Workers        map[string]ProjectWorker         `yaml:"workers,omitempty"`
 	Cronjobs       []ProjectCronjob                 `yaml:"cronjobs,omitempty"`
 	Hooks          struct {
@@ -102,6 +284,14 @@ type ProjectApp struct {
 	} `yaml:"hooks,omitempty"`
 }
 
+// ProjectKeepWarm keeps the previous app containers after a deployment, so a rollback to them only re-targets the proxy
+type ProjectKeepWarm struct {
+	// Duration in seconds the previous app containers are kept
+	Duration int `yaml:"duration,omitempty"`
+	// Running keeps the containers running instead of stopping them, a rollback does not have to wait for them to boot
+	Running bool `yaml:"running,omitempty"`
+}
+
 type ProjectOnePassword struct {
 	Name        string            `yaml:"name" jsonschema:"required"`
 	Vault       string            `yaml:"vault" jsonschema:"required"`
@@ -109,6 +299,14 @@ type ProjectOnePassword struct {
 	RemapFields map[string]string `yaml:"remap_fields,omitempty"`
 }
 
+// ProjectAgeFile is an age encrypted YAML file, it is decrypted locally with the age binary
+type ProjectAgeFile struct {
+	// File is relative to the config file
+	File string `yaml:"file" jsonschema:"required"`
+	// Identity is the age identity file relative to the config file, defaults to the environment variable AGE_IDENTITY_FILE or ~/.config/age/keys.txt
+	Identity string `yaml:"identity,omitempty"`
+}
+
 type ProjectFromEnv map[string]string
 
 func (e ProjectFromEnv) JSONSchema() *jsonschema.Schema {
@@ -145,17 +343,84 @@ type ProjectService struct {
 	Settings    map[string]string             `yaml:"settings,omitempty"`
 	Environment map[string]ProjectEnvironment `yaml:"env,omitempty"`
 	Secrets     ProjectGenericSecrets         `yaml:"secrets,omitempty"`
+	Backup      *ProjectServiceBackup         `yaml:"backup,omitempty"`
+	Expose      *ProjectServiceExpose         `yaml:"expose,omitempty"`
+	// HostPort publishes the service on the private address of the primary server for app containers on the other servers, required when the project has multiple servers
+	HostPort int                  `yaml:"host_port,omitempty"`
+	Custom   ProjectCustomService `yaml:",inline"`
+}
+
+// ProjectCustomService configures a service of type custom running an arbitrary image
+type ProjectCustomService struct {
+	Image   string   `yaml:"image,omitempty"`
+	Command []string `yaml:"command,omitempty"`
+	// Ports the service listens on, the first one is exposed as service.<name>.port
+	Ports []int `yaml:"ports,omitempty"`
+	// Volumes maps a volume name to the path in the container
+	Volumes     map[string]string         `yaml:"volumes,omitempty"`
+	Healthcheck *ProjectCustomHealthcheck `yaml:"healthcheck,omitempty"`
+}
+
+type ProjectCustomHealthcheck struct {
+	// Command is executed with the shell of the container, exit code 0 means healthy
+	Command string `yaml:"command"`
+	// Interval between the checks in seconds
+	Interval int `yaml:"interval,omitempty"`
+	Retries  int `yaml:"retries,omitempty"`
+}
+
+// ProjectServiceExpose publishes a HTTP port of the service through kamal-proxy
+type ProjectServiceExpose struct {
+	Host string `yaml:"host"`
+	Port int    `yaml:"port"`
+	// HealthCheckPath has to answer with a 2xx status, defaults to /
+	HealthCheckPath string `yaml:"health_check_path,omitempty"`
+	// BasicAuth protects the service, the password is generated and stored as secret SERVICE_<NAME>_BASIC_AUTH_PASSWORD
+	BasicAuth *ProjectServiceBasicAuth `yaml:"basic_auth,omitempty"`
+}
+
+type ProjectServiceBasicAuth struct {
+	// Username defaults to admin
+	Username string `yaml:"username,omitempty"`
+}
+
+// ProjectServiceBackup dumps the service on a schedule into the backups volume on the server
+type ProjectServiceBackup struct {
+	Schedule string `yaml:"schedule"`
+	// Keep is the amount of backups kept, defaults to 7
+	Keep int `yaml:"keep,omitempty"`
+	// MaxAge removes backups older than the given seconds
+	MaxAge int `yaml:"max_age,omitempty"`
 }
 
+// ProjectGenericSecrets are the sources of secret environment variables. When a variable is set by multiple sources,
+// the later one wins: env, from_env, from_stored, from_env_file, from_sops, from_age, initial_secrets, onepassword
 type ProjectGenericSecrets struct {
 	FromEnv     ProjectFromEnv `yaml:"from_env,omitempty"`
 	FromEnvFile []string       `yaml:"from_env_file,omitempty"`
 	FromStored  ProjectFromEnv `yaml:"from_stored,omitempty"`
+	// FromSops are SOPS encrypted files relative to the config file, they are decrypted locally with the sops binary and the keys of the user
+	FromSops []string `yaml:"from_sops,omitempty"`
+	// FromAge are age encrypted files relative to the config file, they override the values of from_sops
+	FromAge     []ProjectAgeFile `yaml:"from_age,omitempty"`
 	OnePassword struct {
 		Secret []ProjectOnePassword `yaml:"items,omitempty"`
 	} `yaml:"onepassword,omitempty"`
 }
 
+// JSONSchemaExtend documents the precedence of the sources, the comments of the fields are not part of the schema
+func (s ProjectGenericSecrets) JSONSchemaExtend(schema *jsonschema.Schema) {
+	schema.Description = "When a variable is set by multiple sources, the later one wins: env, from_env, from_stored, from_env_file, from_sops, from_age, initial_secrets, onepassword"
+
+	if sops, ok := schema.Properties.Get("from_sops"); ok {
+		sops.Description = "SOPS encrypted files relative to the config file"
+	}
+
+	if age, ok := schema.Properties.Get("from_age"); ok {
+		age.Description = "age encrypted files relative to the config file"
+	}
+}
+
 func (e ProjectService) JSONSchema() *jsonschema.Schema {
 	return serviceSchema
 }
@@ -166,7 +431,7 @@ func SetServiceSchema(schema *jsonschema.Schema) {
 	serviceSchema = schema
 }
 
-func CreateConfig(file string) (*ProjectConfig, error) {
+func CreateConfig(file string, environment string) (*ProjectConfig, error) {
 	var cfg ProjectConfig
 
 	if _, err := os.Stat(file); os.IsNotExist(err) {
@@ -203,7 +468,14 @@ func CreateConfig(file string) (*ProjectConfig, error) {
 		}
 	}
 
+	if environment != "" {
+		if err := cfg.applyEnvironment(environment); err != nil {
+			return nil, err
+		}
+	}
+
 	cfg.FillDefaults()
+	cfg.resolveSecretFiles(filepath.Dir(file))
 
 	if err := validateConfig(&cfg); err != nil {
 		return nil, err
@@ -213,9 +485,81 @@ func CreateConfig(file string) (*ProjectConfig, error) {
 		return nil, err
 	}
 
+	if err := validateServers(cfg.AllServers()); err != nil {
+		return nil, err
+	}
+
+	if err := validateServicePublishing(&cfg); err != nil {
+		return nil, err
+	}
+
 	return &cfg, nil
 }
 
+// resolveSecretFiles makes the paths of the encrypted secret files relative to the directory of the config file, so the
+// command works the same from every working directory
+func (p *ProjectConfig) resolveSecretFiles(dir string) {
+	p.App.Secrets = p.App.Secrets.resolveFiles(dir)
+
+	for name, service := range p.Services {
+		service.Secrets = service.Secrets.resolveFiles(dir)
+		p.Services[name] = service
+	}
+
+	p.Build.Secrets.FromSops = resolvePaths(dir, p.Build.Secrets.FromSops)
+	p.Build.Secrets.FromAge = resolveAgeFiles(dir, p.Build.Secrets.FromAge)
+}
+
+func (s ProjectGenericSecrets) resolveFiles(dir string) ProjectGenericSecrets {
+	s.FromSops = resolvePaths(dir, s.FromSops)
+	s.FromAge = resolveAgeFiles(dir, s.FromAge)
+
+	return s
+}
+
+func resolveAgeFiles(dir string, files []ProjectAgeFile) []ProjectAgeFile {
+	if len(files) == 0 {
+		return files
+	}
+
+	resolved := make([]ProjectAgeFile, 0, len(files))
+
+	for _, file := range files {
+		file.File = resolvePath(dir, file.File)
+
+		if file.Identity != "" {
+			file.Identity = resolvePath(dir, file.Identity)
+		}
+
+		resolved = append(resolved, file)
+	}
+
+	return resolved
+}
+
+func resolvePaths(dir string, paths []string) []string {
+	if len(paths) == 0 {
+		return paths
+	}
+
+	resolved := make([]string, 0, len(paths))
+
+	for _, path := range paths {
+		resolved = append(resolved, resolvePath(dir, path))
+	}
+
+	return resolved
+}
+
+// resolvePath keeps absolute paths and paths starting with ~/, which are expanded to the home directory later
+func resolvePath(dir, path string) string {
+	if filepath.IsAbs(path) || strings.HasPrefix(path, "~/") {
+		return path
+	}
+
+	return filepath.Join(dir, path)
+}
+
 func validateConfig(projectConfig *ProjectConfig) error {
 	if projectConfig.Name == "" {
 		return fmt.Errorf("missing project name")
@@ -237,6 +581,211 @@ func validateConfig(projectConfig *ProjectConfig) error {
 		return fmt.Errorf("the project name cannot contain special symbols as this needs to be resolable with DNS")
 	}
 
+	if projectConfig.Rollout != "sequential" && projectConfig.Rollout != "parallel" {
+		return fmt.Errorf("unknown rollout %s, allowed are sequential and parallel", projectConfig.Rollout)
+	}
+
+	if projectConfig.App.Replicas < 1 {
+		return fmt.Errorf("app.replicas must be at least 1")
+	}
+
+	if err := validateProxyVerify(projectConfig.Proxy.Verify); err != nil {
+		return err
+	}
+
+	for serviceName, service := range projectConfig.Services {
+		if service.Expose != nil {
+			if !validExposeHost.MatchString(service.Expose.Host) {
+				return fmt.Errorf("services.%s.expose.host: %q is not a valid host name", serviceName, service.Expose.Host)
+			}
+
+			if service.Expose.Port < 1 || service.Expose.Port > 65535 {
+				return fmt.Errorf("services.%s.expose.port: must be between 1 and 65535", serviceName)
+			}
+
+			if service.Expose.Host == projectConfig.Proxy.Host {
+				return fmt.Errorf("services.%s.expose.host: the host is already used by the app", serviceName)
+			}
+		}
+
+		if service.Backup == nil {
+			continue
+		}
+
+		if _, err := cron.ParseStandard(service.Backup.Schedule); err != nil {
+			return fmt.Errorf("services.%s.backup.schedule: %w", serviceName, err)
+		}
+
+		if service.Backup.Keep < 0 || service.Backup.MaxAge < 0 {
+			return fmt.Errorf("services.%s.backup: keep and max_age cannot be negative", serviceName)
+		}
+	}
+
+	for _, serviceName := range projectConfig.Preview.Seed {
+		if _, ok := projectConfig.Services[serviceName]; !ok {
+			return fmt.Errorf("preview.seed: service %s is not defined", serviceName)
+		}
+	}
+
+	for _, allowIP := range projectConfig.Proxy.Maintenance.AllowIPs {
+		if err := ValidateIPOrCIDR(allowIP); err != nil {
+			return fmt.Errorf("proxy.maintenance.allow_ips: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (p *ProjectConfig) applyEnvironment(name string) error {
+	env, ok := p.Environments[name]
+
+	if !ok {
+		return fmt.Errorf("environment %s is not defined in the config", name)
+	}
+
+	if !validHostName.MatchString(name) {
+		return fmt.Errorf("the environment name %s cannot contain special symbols as this needs to be resolable with DNS", name)
+	}
+
+	p.Environment = name
+
+	if env.Server != nil {
+		if env.Server.Address != "" {
+			p.Server.Address = env.Server.Address
+		}
+
+		if env.Server.Username != "" {
+			p.Server.Username = env.Server.Username
+		}
+
+		if env.Server.Port != 0 {
+			p.Server.Port = env.Server.Port
+		}
+	}
+
+	if env.Proxy.Host != "" {
+		p.Proxy.Host = env.Proxy.Host
+	}
+
+	if len(env.Env) > 0 && p.App.Environment == nil {
+		p.App.Environment = make(map[string]ProjectEnvironment)
+	}
+
+	for key, value := range env.Env {
+		p.App.Environment[key] = value
+	}
+
+	if len(env.Services) > 0 && p.Services == nil {
+		p.Services = make(map[string]ProjectService)
+	}
+
+	for key, value := range env.Services {
+		p.Services[key] = value
+	}
+
+	if len(env.Workers) > 0 && p.App.Workers == nil {
+		p.App.Workers = make(map[string]ProjectWorker)
+	}
+
+	for key, value := range env.Workers {
+		p.App.Workers[key] = value
+	}
+
+	return nil
+}
+
+func validateServers(servers []ProjectServer) error {
+	webServers := 0
+	cronServers := 0
+
+	for i, server := range servers {
+		if server.Address == "" {
+			return fmt.Errorf("servers[%d]: missing address", i)
+		}
+
+		for _, role := range server.Roles {
+			if !slices.Contains(validServerRoles, role) {
+				return fmt.Errorf("server %s: unknown role %s, allowed are %v", server.Address, role, validServerRoles)
+			}
+		}
+
+		if server.HasRole(ServerRoleWeb) {
+			webServers++
+		}
+
+		if server.HasRole(ServerRoleCron) {
+			cronServers++
+		}
+	}
+
+	if webServers == 0 {
+		return fmt.Errorf("at least one server needs the %s role", ServerRoleWeb)
+	}
+
+	if cronServers > 1 {
+		return fmt.Errorf("only one server can have the %s role, otherwise cronjobs would run multiple times. Set the roles of the servers explicitly", ServerRoleCron)
+	}
+
+	return nil
+}
+
+// validateServicePublishing makes sure services are only reachable through the private network when app containers run on other servers
+func validateServicePublishing(cfg *ProjectConfig) error {
+	if len(cfg.Servers) == 0 || len(cfg.Services) == 0 {
+		return nil
+	}
+
+	if net.ParseIP(cfg.Server.PrivateAddress) == nil {
+		return fmt.Errorf("server.private_address has to be an IP address when the project has multiple servers, the services are only published on the private network")
+	}
+
+	usedPorts := map[int]string{}
+
+	for _, name := range slices.Sorted(maps.Keys(cfg.Services)) {
+		hostPort := cfg.Services[name].HostPort
+
+		if hostPort < 1 || hostPort > 65535 {
+			return fmt.Errorf("service %s: host_port is required when the project has multiple servers", name)
+		}
+
+		if other, ok := usedPorts[hostPort]; ok {
+			return fmt.Errorf("service %s: host_port %d is already used by service %s", name, hostPort, other)
+		}
+
+		usedPorts[hostPort] = name
+	}
+
+	return nil
+}
+
+func validateProxyVerify(verify *ProjectProxyVerify) error {
+	if verify == nil {
+		return nil
+	}
+
+	if len(verify.Probes) == 0 && verify.Command == "" {
+		return fmt.Errorf("proxy.verify needs probes or a command")
+	}
+
+	for i, probe := range verify.Probes {
+		if !strings.HasPrefix(probe.Path, "/") {
+			return fmt.Errorf("proxy.verify.probes[%d]: path has to start with /", i)
+		}
+	}
+
+	return nil
+}
+
+// ValidateIPOrCIDR checks that the value is an IP address or a CIDR range
+func ValidateIPOrCIDR(value string) error {
+	if net.ParseIP(value) != nil {
+		return nil
+	}
+
+	if _, _, err := net.ParseCIDR(value); err != nil {
+		return fmt.Errorf("%s is neither an IP address nor a CIDR range", value)
+	}
+
 	return nil
 }
 
@@ -271,6 +820,34 @@ func (p *ProjectConfig) FillDefaults() {
 		p.Server.Username = "root"
 	}
 
+	for i := range p.Servers {
+		if p.Servers[i].Port == 0 {
+			p.Servers[i].Port = 22
+		}
+
+		if p.Servers[i].Username == "" {
+			p.Servers[i].Username = "root"
+		}
+	}
+
+	if p.Rollout == "" {
+		p.Rollout = "sequential"
+	}
+
+	if p.App.Replicas == 0 {
+		p.App.Replicas = 1
+	}
+
+	if p.Proxy.Verify != nil {
+		if p.Proxy.Verify.GracePeriod == 0 {
+			p.Proxy.Verify.GracePeriod = 30
+		}
+
+		if p.Proxy.Verify.Interval == 0 {
+			p.Proxy.Verify.Interval = 5
+		}
+	}
+
 	if p.Proxy.HealthCheck.Path == "" {
 		p.Proxy.HealthCheck.Path = "/"
 	}
@@ -291,6 +868,24 @@ func (p *ProjectConfig) FillDefaults() {
 		p.KeepVersions = 5
 	}
 
+	for _, service := range p.Services {
+		if service.Backup != nil && service.Backup.Keep == 0 {
+			service.Backup.Keep = 7
+		}
+
+		if service.Expose != nil && service.Expose.HealthCheckPath == "" {
+			service.Expose.HealthCheckPath = "/"
+		}
+
+		if service.Expose != nil && service.Expose.BasicAuth != nil && service.Expose.BasicAuth.Username == "" {
+			service.Expose.BasicAuth.Username = "admin"
+		}
+	}
+
+	if p.Preview.ExpireAfter == 0 {
+		p.Preview.ExpireAfter = 7 * 24 * 60 * 60
+	}
+
 	if p.Build.BuildPack != nil && p.Build.BuildPack.Settings == nil {
 		p.Build.BuildPack.Settings = make(buildpack.ConfigSettings)
 	}
@@ -31,7 +31,7 @@ func TestProxyGetService(t *testing.T) {
 }
 
 func TestConfigLoadWithoutExistence(t *testing.T) {
-	_, err := CreateConfig("nonexistent.yml")
+	_, err := CreateConfig("nonexistent.yml", "")
 
 	assert.Error(t, err)
 
@@ -44,7 +44,7 @@ func TestConfigLoadWithInvalidYAML(t *testing.T) {
 
 	assert.NoError(t, os.WriteFile(filepath.Join(tmpDir, "invalid.yml"), []byte("invalid"), 0644))
 
-	_, err := CreateConfig(filepath.Join(tmpDir, "invalid.yml"))
+	_, err := CreateConfig(filepath.Join(tmpDir, "invalid.yml"), "")
 
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "cannot unmarshal")
@@ -55,7 +55,7 @@ func TestConfigLoadWithValidYAML(t *testing.T) {
 
 	assert.NoError(t, os.WriteFile(filepath.Join(tmpDir, "valid.yml"), []byte("name: blaa\nimage: blaa\nserver:\n  address: localhost\nproxy:\n  host: foo.com"), 0644))
 
-	cfg, err := CreateConfig(filepath.Join(tmpDir, "valid.yml"))
+	cfg, err := CreateConfig(filepath.Join(tmpDir, "valid.yml"), "")
 
 	assert.NoError(t, err)
 	assert.Equal(t, "blaa", cfg.Name)
@@ -73,7 +73,7 @@ func TestConfigIncludes(t *testing.T) {
 	assert.NoError(t, os.WriteFile(filepath.Join(tmpDir, "base.yml"), []byte("name: blaa\nimage: blaa\nserver:\n  address: localhost\nproxy:\n  host: foo.com"), 0644))
 	assert.NoError(t, os.WriteFile(filepath.Join(tmpDir, "valid.yml"), []byte("include:\n  - base.yml"), 0644))
 
-	cfg, err := CreateConfig(filepath.Join(tmpDir, "valid.yml"))
+	cfg, err := CreateConfig(filepath.Join(tmpDir, "valid.yml"), "")
 
 	assert.NoError(t, err)
 	assert.Equal(t, "blaa", cfg.Name)
@@ -86,7 +86,7 @@ func TestConfigIncludeFileMissing(t *testing.T) {
 
 	assert.NoError(t, os.WriteFile(filepath.Join(tmpDir, "invalid.yml"), []byte("include:\n  - missing.yml"), 0644))
 
-	_, err := CreateConfig(filepath.Join(tmpDir, "invalid.yml"))
+	_, err := CreateConfig(filepath.Join(tmpDir, "invalid.yml"), "")
 
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "failed to read include file missing.yml")
@@ -104,10 +104,228 @@ func TestConfigIncludeFileInvalidYaml(t *testing.T) {
 	assert.NoError(t, os.WriteFile(filepath.Join(tmpDir, "base.yml"), []byte("!!!!!!!"), 0644))
 	assert.NoError(t, os.WriteFile(filepath.Join(tmpDir, "valid.yml"), []byte("include:\n  - base.yml"), 0644))
 
-	cfg, err := CreateConfig(filepath.Join(tmpDir, "valid.yml"))
+	cfg, err := CreateConfig(filepath.Join(tmpDir, "valid.yml"), "")
 
 	assert.Error(t, err)
 	assert.Nil(t, cfg)
 
 	assert.NoError(t, os.Chdir(currentDir))
 }
+
+func TestConfigEnvironment(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	assert.NoError(t, os.WriteFile(filepath.Join(tmpDir, "valid.yml"), []byte("name: blaa\nimage: blaa\nserver:\n  address: localhost\nproxy:\n  host: foo.com\napp:\n  env:\n    APP_ENV:\n      value: prod\nenvironments:\n  staging:\n    server:\n      address: staging.local\n    proxy:\n      host: staging.foo.com\n    env:\n      APP_ENV:\n        value: staging\n    services:\n      database:\n        type: mysql:8.0"), 0644))
+
+	cfg, err := CreateConfig(filepath.Join(tmpDir, "valid.yml"), "")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "blaa", cfg.Identifier())
+	assert.Equal(t, "localhost", cfg.Server.Address)
+
+	cfg, err = CreateConfig(filepath.Join(tmpDir, "valid.yml"), "staging")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "staging", cfg.Environment)
+	assert.Equal(t, "blaa_staging", cfg.Identifier())
+	// a project named like the environment of another project gets its own scope
+	assert.NotEqual(t, (&ProjectConfig{Name: "blaa-staging"}).Identifier(), cfg.Identifier())
+	assert.Equal(t, "staging.local", cfg.Server.Address)
+	assert.Equal(t, 22, cfg.Server.Port)
+	assert.Equal(t, "staging.foo.com", cfg.Proxy.Host)
+	assert.Equal(t, "staging", cfg.App.Environment["APP_ENV"].Value)
+	assert.Equal(t, "mysql:8.0", cfg.Services["database"].Type)
+
+	_, err = CreateConfig(filepath.Join(tmpDir, "valid.yml"), "production")
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "environment production is not defined")
+}
+
+func TestConfigServerRoles(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	assert.NoError(t, os.WriteFile(filepath.Join(tmpDir, "valid.yml"), []byte("name: blaa\nimage: blaa\nserver:\n  address: 10.0.0.1\n  roles: [web, cron]\nservers:\n  - address: 10.0.0.2\n    roles: [web, worker]\nproxy:\n  host: foo.com"), 0644))
+
+	cfg, err := CreateConfig(filepath.Join(tmpDir, "valid.yml"), "")
+
+	assert.NoError(t, err)
+	assert.Len(t, cfg.AllServers(), 2)
+	assert.Equal(t, 22, cfg.Servers[0].Port)
+	assert.Equal(t, "sequential", cfg.Rollout)
+	assert.True(t, cfg.Server.HasRole(ServerRoleCron))
+	assert.False(t, cfg.Server.HasRole(ServerRoleWorker))
+	assert.False(t, cfg.Servers[0].HasRole(ServerRoleCron))
+
+	assert.NoError(t, os.WriteFile(filepath.Join(tmpDir, "invalid.yml"), []byte("name: blaa\nimage: blaa\nserver:\n  address: 10.0.0.1\nservers:\n  - address: 10.0.0.2\nproxy:\n  host: foo.com"), 0644))
+
+	_, err = CreateConfig(filepath.Join(tmpDir, "invalid.yml"), "")
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "only one server can have the cron role")
+}
+
+func TestConfigServicePublishing(t *testing.T) {
+	tmpDir := t.TempDir()
+	base := "name: blaa\nimage: blaa\nproxy:\n  host: foo.com\nservers:\n  - address: 10.0.0.2\n    roles: [web, worker]\nservices:\n  db:\n    type: mysql:8.0\n"
+
+	assert.NoError(t, os.WriteFile(filepath.Join(tmpDir, "valid.yml"), []byte(base+"    host_port: 13306\n  cache:\n    type: valkey:8.0\n    host_port: 16379\nserver:\n  address: foo.com\n  private_address: 10.0.0.1\n  roles: [web, cron]"), 0644))
+
+	cfg, err := CreateConfig(filepath.Join(tmpDir, "valid.yml"), "")
+
+	assert.NoError(t, err)
+	assert.Equal(t, 13306, cfg.Services["db"].HostPort)
+
+	assert.NoError(t, os.WriteFile(filepath.Join(tmpDir, "public.yml"), []byte(base+"    host_port: 13306\nserver:\n  address: 10.0.0.1\n  roles: [web, cron]"), 0644))
+
+	_, err = CreateConfig(filepath.Join(tmpDir, "public.yml"), "")
+
+	assert.ErrorContains(t, err, "server.private_address has to be an IP address")
+
+	assert.NoError(t, os.WriteFile(filepath.Join(tmpDir, "port.yml"), []byte(base+"server:\n  address: 10.0.0.1\n  private_address: 10.0.0.1\n  roles: [web, cron]"), 0644))
+
+	_, err = CreateConfig(filepath.Join(tmpDir, "port.yml"), "")
+
+	assert.ErrorContains(t, err, "service db: host_port is required")
+
+	assert.NoError(t, os.WriteFile(filepath.Join(tmpDir, "duplicate.yml"), []byte(base+"    host_port: 13306\n  cache:\n    type: valkey:8.0\n    host_port: 13306\nserver:\n  address: 10.0.0.1\n  private_address: 10.0.0.1\n  roles: [web, cron]"), 0644))
+
+	_, err = CreateConfig(filepath.Join(tmpDir, "duplicate.yml"), "")
+
+	assert.ErrorContains(t, err, "service db: host_port 13306 is already used by service cache")
+}
+
+func TestValidateIPOrCIDR(t *testing.T) {
+	assert.NoError(t, ValidateIPOrCIDR("203.0.113.10"))
+	assert.NoError(t, ValidateIPOrCIDR("10.0.0.0/8"))
+	assert.NoError(t, ValidateIPOrCIDR("2001:db8::/32"))
+	assert.Error(t, ValidateIPOrCIDR("example.com"))
+	assert.Error(t, ValidateIPOrCIDR("10.0.0.0/33"))
+}
+
+func TestConfigForPreview(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	assert.NoError(t, os.WriteFile(filepath.Join(tmpDir, "valid.yml"), []byte("name: blaa\nimage: blaa\nserver:\n  address: 10.0.0.1\n  roles: [web]\nservers:\n  - address: 10.0.0.2\n    roles: [worker, cron]\nproxy:\n  host: foo.com"), 0644))
+
+	cfg, err := CreateConfig(filepath.Join(tmpDir, "valid.yml"), "")
+
+	assert.NoError(t, err)
+
+	preview, err := cfg.ForPreview("pr-123")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "blaa__pr-123", preview.Identifier())
+	assert.Equal(t, "blaa", preview.PreviewParent())
+	assert.Equal(t, "pr-123.foo.com", preview.Proxy.Host)
+	assert.Len(t, preview.AllServers(), 1)
+	assert.True(t, preview.Server.HasRole(ServerRoleCron))
+	assert.Equal(t, "blaa", cfg.Identifier())
+	assert.Equal(t, "foo.com", cfg.Proxy.Host)
+
+	cfg.Preview.Host = "{name}.preview.foo.com"
+
+	preview, err = cfg.ForPreview("pr-123")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "pr-123.preview.foo.com", preview.Proxy.Host)
+
+	_, err = preview.ForPreview("pr-124")
+
+	assert.Error(t, err)
+
+	_, err = cfg.ForPreview("PR_123")
+
+	assert.Error(t, err)
+}
+
+func TestConfigForPreviewIsIndependent(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	assert.NoError(t, os.WriteFile(filepath.Join(tmpDir, "valid.yml"), []byte("name: blaa\nimage: blaa\nserver:\n  address: 10.0.0.1\nproxy:\n  host: foo.com\napp:\n  workers:\n    queue:\n      command: consume\nservices:\n  database:\n    type: mysql:8.0\n    settings:\n      max_connections: '100'"), 0644))
+
+	cfg, err := CreateConfig(filepath.Join(tmpDir, "valid.yml"), "")
+
+	assert.NoError(t, err)
+
+	preview, err := cfg.ForPreview("pr-123")
+
+	assert.NoError(t, err)
+
+	worker := preview.App.Workers["queue"]
+	worker.Replicas = 5
+	preview.App.Workers["queue"] = worker
+	preview.App.Workers["mail"] = ProjectWorker{Command: "mail"}
+	preview.Services["database"].Settings["max_connections"] = "10"
+	preview.App.Environment["FOO"] = ProjectEnvironment{Value: "bar"}
+
+	assert.Equal(t, 0, cfg.App.Workers["queue"].Replicas)
+	assert.NotContains(t, cfg.App.Workers, "mail")
+	assert.Equal(t, "100", cfg.Services["database"].Settings["max_connections"])
+	assert.NotContains(t, cfg.App.Environment, "FOO")
+}
+
+func TestConfigServiceBackup(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	assert.NoError(t, os.WriteFile(filepath.Join(tmpDir, "valid.yml"), []byte("name: blaa\nimage: blaa\nserver:\n  address: localhost\nproxy:\n  host: foo.com\nservices:\n  database:\n    type: mysql:8.0\n    backup:\n      schedule: '@daily'"), 0644))
+
+	cfg, err := CreateConfig(filepath.Join(tmpDir, "valid.yml"), "")
+
+	assert.NoError(t, err)
+	assert.Equal(t, 7, cfg.Services["database"].Backup.Keep)
+
+	assert.NoError(t, os.WriteFile(filepath.Join(tmpDir, "invalid.yml"), []byte("name: blaa\nimage: blaa\nserver:\n  address: localhost\nproxy:\n  host: foo.com\nservices:\n  database:\n    type: mysql:8.0\n    backup:\n      schedule: 'daily'"), 0644))
+
+	_, err = CreateConfig(filepath.Join(tmpDir, "invalid.yml"), "")
+
+	assert.ErrorContains(t, err, "services.database.backup.schedule")
+}
+
+func TestConfigServiceExpose(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	assert.NoError(t, os.WriteFile(filepath.Join(tmpDir, "valid.yml"), []byte("name: blaa\nimage: blaa\nserver:\n  address: localhost\nproxy:\n  host: foo.com\nservices:\n  queue:\n    type: rabbitmq:4\n    expose:\n      host: queue.foo.com\n      port: 15672\n      basic_auth: {}"), 0644))
+
+	cfg, err := CreateConfig(filepath.Join(tmpDir, "valid.yml"), "")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "/", cfg.Services["queue"].Expose.HealthCheckPath)
+	assert.Equal(t, "admin", cfg.Services["queue"].Expose.BasicAuth.Username)
+
+	assert.NoError(t, os.WriteFile(filepath.Join(tmpDir, "invalid.yml"), []byte("name: blaa\nimage: blaa\nserver:\n  address: localhost\nproxy:\n  host: foo.com\nservices:\n  queue:\n    type: rabbitmq:4\n    expose:\n      host: foo.com\n      port: 15672"), 0644))
+
+	_, err = CreateConfig(filepath.Join(tmpDir, "invalid.yml"), "")
+
+	assert.ErrorContains(t, err, "services.queue.expose.host")
+}
+
+func TestConfigCustomService(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	assert.NoError(t, os.WriteFile(filepath.Join(tmpDir, "valid.yml"), []byte("name: blaa\nimage: blaa\nserver:\n  address: localhost\nproxy:\n  host: foo.com\nservices:\n  pdf:\n    type: custom\n    image: gotenberg/gotenberg:8\n    ports: [3000]\n    volumes:\n      cache: /tmp/cache\n    healthcheck:\n      command: curl -f localhost:3000/health"), 0644))
+
+	cfg, err := CreateConfig(filepath.Join(tmpDir, "valid.yml"), "")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "gotenberg/gotenberg:8", cfg.Services["pdf"].Custom.Image)
+	assert.Equal(t, []int{3000}, cfg.Services["pdf"].Custom.Ports)
+	assert.Equal(t, "/tmp/cache", cfg.Services["pdf"].Custom.Volumes["cache"])
+	assert.Equal(t, "curl -f localhost:3000/health", cfg.Services["pdf"].Custom.Healthcheck.Command)
+}
+
+func TestConfigSecretFilesRelativeToConfig(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	assert.NoError(t, os.WriteFile(filepath.Join(tmpDir, "valid.yml"), []byte("name: blaa\nimage: blaa\nserver:\n  address: localhost\nproxy:\n  host: foo.com\napp:\n  secrets:\n    from_sops: [secrets.yaml, /etc/secrets.yaml]\n    from_age:\n      - file: secrets.age\n        identity: ~/.config/age/deploy.txt\nbuild:\n  secrets:\n    from_age:\n      - file: build.age\n        identity: keys.txt\nservices:\n  cache:\n    type: valkey:8\n    secrets:\n      from_sops: [cache.yaml]"), 0644))
+
+	cfg, err := CreateConfig(filepath.Join(tmpDir, "valid.yml"), "")
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(tmpDir, "secrets.yaml"), "/etc/secrets.yaml"}, cfg.App.Secrets.FromSops)
+	assert.Equal(t, filepath.Join(tmpDir, "secrets.age"), cfg.App.Secrets.FromAge[0].File)
+	assert.Equal(t, "~/.config/age/deploy.txt", cfg.App.Secrets.FromAge[0].Identity)
+	assert.Equal(t, filepath.Join(tmpDir, "build.age"), cfg.Build.Secrets.FromAge[0].File)
+	assert.Equal(t, filepath.Join(tmpDir, "keys.txt"), cfg.Build.Secrets.FromAge[0].Identity)
+	assert.Equal(t, []string{filepath.Join(tmpDir, "cache.yaml")}, cfg.Services["cache"].Secrets.FromSops)
+}
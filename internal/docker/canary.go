@@ -0,0 +1,421 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/errdefs"
+	"github.com/gosimple/slug"
+	"github.com/pterm/pterm"
+	"github.com/shyim/tanjun/internal/config"
+)
+
+// canaryLabel marks app containers started by a canary deployment
+const canaryLabel = "tanjun.canary"
+
+// CanaryState is stored in the kv store while a canary deployment receives a part of the traffic
+type CanaryState struct {
+	Version    string    `json:"version"`
+	Percentage int       `json:"percentage"`
+	User       string    `json:"user"`
+	StartedAt  time.Time `json:"started_at"`
+	// Containers maps the server address to the canary app container running on it
+	Containers map[string]string `json:"containers"`
+}
+
+func canaryKey(name string) string {
+	cfg := DeployConfiguration{Name: slug.Make(name)}
+
+	return cfg.ContainerPrefix() + "_canary"
+}
+
+// GetCanaryState returns the running canary deployment of the project or nil when there is none
+func GetCanaryState(kv *KvClient, name string) (*CanaryState, error) {
+	value, err := kv.Get(canaryKey(name))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if value == "" {
+		return nil, nil
+	}
+
+	var state CanaryState
+
+	if err := json.Unmarshal([]byte(value), &state); err != nil {
+		return nil, fmt.Errorf("could not parse canary state: %w", err)
+	}
+
+	return &state, nil
+}
+
+func setCanaryState(kv *KvClient, name string, state CanaryState) error {
+	encoded, err := json.Marshal(state)
+
+	if err != nil {
+		return err
+	}
+
+	if err := kv.Set(canaryKey(name), string(encoded)); err != nil {
+		return fmt.Errorf("could not set canary state: %w", err)
+	}
+
+	return nil
+}
+
+func ensureNoCanary(deployCfg DeployConfiguration) error {
+	state, err := GetCanaryState(deployCfg.storage, deployCfg.Name)
+
+	if err != nil {
+		return err
+	}
+
+	if state != nil {
+		return fmt.Errorf("a canary deployment of version %s is running, run `tanjun rollout promote` or `tanjun rollout abort` first", state.Version)
+	}
+
+	return nil
+}
+
+// ValidateCanaryPercentage checks the share of the traffic routed to a canary, all or none of it is a normal deployment
+func ValidateCanaryPercentage(percentage int) error {
+	if percentage < 1 || percentage > 99 {
+		return fmt.Errorf("the canary percentage must be between 1 and 99, got %d", percentage)
+	}
+
+	return nil
+}
+
+// DeployCanary starts the version next to the current one and routes the given percentage of the traffic to it.
+// Workers and cronjobs keep running the current version until the canary is promoted
+func DeployCanary(ctx context.Context, client *client.Client, projectConfig *config.ProjectConfig, version string, percentage int) error {
+	if err := ValidateCanaryPercentage(percentage); err != nil {
+		return err
+	}
+
+	deployCfg, done, err := beginDeployment(ctx, client, projectConfig, version)
+
+	if err != nil {
+		return err
+	}
+
+	defer done()
+
+	if err := ensureNoCanary(deployCfg); err != nil {
+		return err
+	}
+
+	if err := ensureNoMaintenance(deployCfg); err != nil {
+		return err
+	}
+
+	deployments, err := createServerDeployments(ctx, client, deployCfg)
+
+	if err != nil {
+		return err
+	}
+
+	defer closeServerDeployments(deployments)
+
+	if err := rolloutServerDeployments(deployCfg, deployments, func(d *serverDeployment) error {
+		return d.prepareServer(ctx, client)
+	}); err != nil {
+		return err
+	}
+
+	for _, d := range deployments {
+		if d.server.HasRole(config.ServerRoleWeb) && len(d.beforeContainers) == 0 {
+			return fmt.Errorf("there is no running version on server %s to split the traffic with, deploy without --canary first", d.server.Address)
+		}
+	}
+
+	if len(deployCfg.ProjectConfig.App.Hooks.Deploy) > 0 {
+		log.Infof("Running deploy hook")
+		if err := runHookInContainer(ctx, client, deployCfg, deployCfg.ProjectConfig.App.Hooks.Deploy); err != nil {
+			return err
+		}
+	}
+
+	if err := rolloutServerDeployments(deployCfg, deployments, func(d *serverDeployment) error {
+		return d.startCanary(ctx, percentage)
+	}); err != nil {
+		return stopServerCanaries(ctx, deployments, err)
+	}
+
+	state := CanaryState{
+		Version:    version,
+		Percentage: percentage,
+		User:       currentUsername(),
+		StartedAt:  time.Now().UTC(),
+		Containers: make(map[string]string),
+	}
+
+	for _, d := range deployments {
+		if len(d.newContainerIDs) > 0 {
+			state.Containers[d.server.Address] = d.newContainerIDs[0]
+		}
+	}
+
+	if err := setCanaryState(deployCfg.storage, deployCfg.Name, state); err != nil {
+		return stopServerCanaries(ctx, deployments, err)
+	}
+
+	log.Infof("Version %s receives %d%% of the traffic, run `tanjun rollout promote` to route all traffic to it or `tanjun rollout abort` to remove it", version, percentage)
+
+	return nil
+}
+
+// PromoteCanary routes all traffic to the canary version and replaces the old app containers, workers and cronjobs
+func PromoteCanary(ctx context.Context, client *client.Client, projectConfig *config.ProjectConfig) (*CanaryState, error) {
+	state, err := loadCanaryState(ctx, client, projectConfig.Identifier())
+
+	if err != nil {
+		return nil, err
+	}
+
+	deployCfg, done, err := beginDeployment(ctx, client, projectConfig, state.Version)
+
+	if err != nil {
+		return state, err
+	}
+
+	defer done()
+
+	deployments, err := createServerDeployments(ctx, client, deployCfg)
+
+	if err != nil {
+		return state, err
+	}
+
+	defer closeServerDeployments(deployments)
+
+	if err := rolloutServerDeployments(deployCfg, deployments, func(d *serverDeployment) error {
+		if err := d.prepare(ctx, client); err != nil {
+			return err
+		}
+
+		d.beforeContainers = slices.DeleteFunc(d.beforeContainers, func(c container.Summary) bool {
+			return c.ID == state.Containers[d.server.Address]
+		})
+
+		return nil
+	}); err != nil {
+		return state, revertServerDeployments(ctx, deployments, err)
+	}
+
+	if err := rolloutServerDeployments(deployCfg, deployments, func(d *serverDeployment) error {
+		return d.promoteCanary(ctx, state.Containers[d.server.Address])
+	}); err != nil {
+		return state, revertServerDeployments(ctx, deployments, err)
+	}
+
+	if err := rolloutServerDeployments(deployCfg, deployments, func(d *serverDeployment) error {
+		return d.finish(ctx)
+	}); err != nil {
+		return state, err
+	}
+
+	if err := deployCfg.storage.Delete(canaryKey(deployCfg.Name)); err != nil {
+		return state, err
+	}
+
+	if len(deployCfg.ProjectConfig.App.Hooks.PostDeploy) > 0 {
+		log.Infof("Running post deploy hook")
+		if err := runHookInContainer(ctx, client, deployCfg, deployCfg.ProjectConfig.App.Hooks.PostDeploy); err != nil {
+			return state, err
+		}
+	}
+
+	log.Infof("Promoted version %s, all traffic is routed to it", state.Version)
+
+	for _, d := range deployments {
+		if err := VersionDrain(ctx, d.client, deployCfg.ProjectConfig); err != nil {
+			return state, err
+		}
+	}
+
+	return state, nil
+}
+
+// AbortCanary routes all traffic back to the current version and removes the canary app containers
+func AbortCanary(ctx context.Context, client *client.Client, projectConfig *config.ProjectConfig) (*CanaryState, error) {
+	state, err := loadCanaryState(ctx, client, projectConfig.Identifier())
+
+	if err != nil {
+		return nil, err
+	}
+
+	deployCfg := newDeployConfiguration(projectConfig, fmt.Sprintf("%s:%s", projectConfig.Image, state.Version))
+
+	deployCfg.storage, err = CreateKVConnection(ctx, client)
+
+	if err != nil {
+		return state, err
+	}
+
+	defer deployCfg.storage.Close()
+
+	unlock, err := lockProject(ctx, client, deployCfg.storage, deployCfg.Name)
+
+	if err != nil {
+		return state, err
+	}
+
+	defer unlock()
+
+	deployments, err := createServerDeployments(ctx, client, deployCfg)
+
+	if err != nil {
+		return state, err
+	}
+
+	defer closeServerDeployments(deployments)
+
+	for _, d := range deployments {
+		if containerID, ok := state.Containers[d.server.Address]; ok {
+			d.newContainerIDs = []string{containerID}
+			d.switched = true
+		}
+	}
+
+	if err := stopServerCanaries(ctx, deployments, nil); err != nil {
+		return state, err
+	}
+
+	if err := deployCfg.storage.Delete(canaryKey(deployCfg.Name)); err != nil {
+		return state, err
+	}
+
+	log.Infof("Aborted canary deployment of version %s, all traffic is routed to the previous version", state.Version)
+
+	return state, nil
+}
+
+func loadCanaryState(ctx context.Context, client *client.Client, name string) (*CanaryState, error) {
+	kv, err := CreateKVConnection(ctx, client)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer kv.Close()
+
+	state, err := GetCanaryState(kv, name)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if state == nil {
+		return nil, fmt.Errorf("there is no canary deployment running for project %s", name)
+	}
+
+	return state, nil
+}
+
+// stopServerCanaries removes the canary from all servers and returns the cause enriched with all errors happening while doing so
+func stopServerCanaries(ctx context.Context, deployments []*serverDeployment, cause error) error {
+	for _, d := range deployments {
+		if err := d.stopCanary(ctx); err != nil {
+			if cause == nil {
+				cause = fmt.Errorf("could not stop canary on server %s: %w", d.server.Address, err)
+				continue
+			}
+
+			cause = fmt.Errorf("%w and could not stop canary on server %s: %s", cause, d.server.Address, err)
+		}
+	}
+
+	return cause
+}
+
+func (d *serverDeployment) startCanary(ctx context.Context, percentage int) error {
+	if !d.server.HasRole(config.ServerRoleWeb) {
+		return nil
+	}
+
+	if err := d.startAppContainer(ctx, map[string]string{canaryLabel: "true"}); err != nil {
+		return err
+	}
+
+	spinnerInfo, err := pterm.DefaultSpinner.Start(fmt.Sprintf("Routing %d%% of the traffic to new container%s", percentage, d.describe()))
+
+	if err != nil {
+		return err
+	}
+
+	target, err := getContainerProxyTarget(ctx, d.client, d.deployCfg, d.newContainerIDs[0])
+
+	if err != nil {
+		spinnerInfo.Fail(err)
+		return err
+	}
+
+	if err := configureKamalService(ctx, d.client, []string{"kamal-proxy", "rollout", "deploy", d.deployCfg.Name, "--target", target}); err != nil {
+		spinnerInfo.Fail(err)
+		return err
+	}
+
+	d.switched = true
+
+	if err := configureKamalService(ctx, d.client, []string{"kamal-proxy", "rollout", "set", d.deployCfg.Name, "--percentage", strconv.Itoa(percentage)}); err != nil {
+		spinnerInfo.Fail(err)
+		return err
+	}
+
+	spinnerInfo.Success(fmt.Sprintf("Routing %d%% of the traffic to new container%s", percentage, d.describe()))
+
+	return nil
+}
+
+// promoteCanary points the proxy to the canary container and the missing replicas next to it.
+// The canary container itself is not tracked as new container, so a revert keeps it running for the canary
+func (d *serverDeployment) promoteCanary(ctx context.Context, containerID string) error {
+	if !d.server.HasRole(config.ServerRoleWeb) || containerID == "" {
+		return nil
+	}
+
+	for range d.deployCfg.ProjectConfig.App.Replicas - 1 {
+		if err := d.startAppContainer(ctx, nil); err != nil {
+			return err
+		}
+	}
+
+	targets, err := getContainerProxyTargets(ctx, d.client, d.deployCfg, append([]string{containerID}, d.newContainerIDs...))
+
+	if err != nil {
+		return err
+	}
+
+	if err := configureKamalService(ctx, d.client, getKamalDeployCommand(d.deployCfg, targets...)); err != nil {
+		return err
+	}
+
+	d.switched = true
+
+	return configureKamalService(ctx, d.client, []string{"kamal-proxy", "rollout", "stop", d.deployCfg.Name})
+}
+
+func (d *serverDeployment) stopCanary(ctx context.Context) error {
+	if d.switched {
+		if err := configureKamalService(ctx, d.client, []string{"kamal-proxy", "rollout", "stop", d.deployCfg.Name}); err != nil {
+			return err
+		}
+	}
+
+	for _, containerID := range d.newContainerIDs {
+		if err := d.client.ContainerRemove(ctx, containerID, container.RemoveOptions{Force: true}); err != nil && !errdefs.IsNotFound(err) {
+			return fmt.Errorf("could not remove the canary container: %w", err)
+		}
+	}
+
+	return nil
+}
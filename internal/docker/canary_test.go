@@ -0,0 +1,136 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/docker/docker/client"
+	"github.com/shyim/tanjun/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestDockerClient returns a client talking to a fake Docker API without a kamal-proxy container. It records the
+// removed containers, removing a container named gone fails with not found
+func newTestDockerClient(t *testing.T) (*client.Client, func() []string) {
+	var lock sync.Mutex
+	var removed []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := r.URL.Path[strings.Index(r.URL.Path[1:], "/")+1:]
+
+		switch {
+		case r.Method == http.MethodGet && path == "/containers/json":
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte("[]"))
+		case r.Method == http.MethodDelete && strings.HasPrefix(path, "/containers/"):
+			id := strings.TrimPrefix(path, "/containers/")
+
+			if id == "gone" {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusNotFound)
+				_, _ = w.Write([]byte(`{"message":"No such container: gone"}`))
+				return
+			}
+
+			lock.Lock()
+			removed = append(removed, id+"?force="+r.URL.Query().Get("force"))
+			lock.Unlock()
+
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNotImplemented)
+		}
+	}))
+
+	t.Cleanup(server.Close)
+
+	dockerClient, err := client.NewClientWithOpts(client.WithHost("tcp://"+server.Listener.Addr().String()), client.WithVersion("1.47"))
+
+	assert.NoError(t, err)
+
+	t.Cleanup(func() {
+		_ = dockerClient.Close()
+	})
+
+	return dockerClient, func() []string {
+		lock.Lock()
+		defer lock.Unlock()
+
+		return removed
+	}
+}
+
+func TestValidateCanaryPercentage(t *testing.T) {
+	cases := []struct {
+		percentage int
+		valid      bool
+	}{
+		{0, false},
+		{1, true},
+		{50, true},
+		{99, true},
+		{100, false},
+		{-5, false},
+	}
+
+	for _, c := range cases {
+		err := ValidateCanaryPercentage(c.percentage)
+
+		if c.valid {
+			assert.NoError(t, err, c.percentage)
+		} else {
+			assert.Error(t, err, c.percentage)
+		}
+	}
+}
+
+func TestCanaryState(t *testing.T) {
+	deployCfg := DeployConfiguration{Name: "app", storage: newTestKvClient(t)}
+
+	assert.NoError(t, ensureNoCanary(deployCfg))
+
+	assert.NoError(t, setCanaryState(deployCfg.storage, "app", CanaryState{Version: "v2", Percentage: 10, Containers: map[string]string{"10.0.0.1": "canary"}}))
+
+	state, err := GetCanaryState(deployCfg.storage, "app")
+
+	assert.NoError(t, err)
+	assert.Equal(t, 10, state.Percentage)
+	assert.Equal(t, "canary", state.Containers["10.0.0.1"])
+	assert.ErrorContains(t, ensureNoCanary(deployCfg), "a canary deployment of version v2 is running")
+
+	assert.NoError(t, deployCfg.storage.Delete(canaryKey("app")))
+
+	assert.NoError(t, ensureNoCanary(deployCfg))
+}
+
+func TestStopServerCanaries(t *testing.T) {
+	dockerClient, removed := newTestDockerClient(t)
+	deployCfg := DeployConfiguration{Name: "app"}
+
+	deployments := []*serverDeployment{
+		{server: config.ProjectServer{Address: "10.0.0.1"}, client: dockerClient, deployCfg: deployCfg, newContainerIDs: []string{"canary-1"}},
+		{server: config.ProjectServer{Address: "10.0.0.2"}, client: dockerClient, deployCfg: deployCfg, newContainerIDs: []string{"gone"}},
+		{server: config.ProjectServer{Address: "10.0.0.3", Roles: []string{config.ServerRoleWorker}}, client: dockerClient, deployCfg: deployCfg},
+	}
+
+	// the canary containers are removed, already removed ones are no error
+	assert.NoError(t, stopServerCanaries(context.Background(), deployments, nil))
+	assert.Equal(t, []string{"canary-1?force=1"}, removed())
+
+	// the traffic cannot be routed back without kamal-proxy, all servers are reported together with the cause
+	for _, d := range deployments {
+		d.switched = true
+	}
+
+	err := stopServerCanaries(context.Background(), deployments, errors.New("healthcheck failed"))
+
+	assert.ErrorContains(t, err, "healthcheck failed")
+	assert.ErrorContains(t, err, "could not stop canary on server 10.0.0.1: kamal proxy container not found")
+	assert.ErrorContains(t, err, "could not stop canary on server 10.0.0.3")
+	assert.Equal(t, []string{"canary-1?force=1"}, removed())
+}
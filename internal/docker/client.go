@@ -8,7 +8,11 @@ import (
 )
 
 func CreateClientFromConfig(config *config.ProjectConfig) (*client.Client, error) {
-	if config.Server.Address == "127.0.0.1" {
+	return CreateClientForServer(config.Server)
+}
+
+func CreateClientForServer(server config.ProjectServer) (*client.Client, error) {
+	if server.Address == "127.0.0.1" {
 		c, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
 
 		if err != nil {
@@ -18,7 +22,7 @@ func CreateClientFromConfig(config *config.ProjectConfig) (*client.Client, error
 		return c, nil
 	}
 
-	hostScheme := fmt.Sprintf("ssh://%s@%s:%d", config.Server.Username, config.Server.Address, config.Server.Port)
+	hostScheme := fmt.Sprintf("ssh://%s@%s:%d", server.Username, server.Address, server.Port)
 	helper, err := connhelper.GetConnectionHelperWithSSHOpts(hostScheme, []string{"-o", "ServerAliveInterval=10"})
 	if err != nil {
 		return nil, err
@@ -16,7 +16,7 @@ func RunCronjobCommand(ctx context.Context, client *client.Client, config *confi
 		Filters: filters.NewArgs(),
 	}
 
-	opts.Filters.Add("label", "tanjun.project="+config.Name)
+	opts.Filters.Add("label", "tanjun.project="+config.Identifier())
 	opts.Filters.Add("label", "tanjun.cronjob=scheduler")
 
 	containers, err := client.ContainerList(ctx, opts)
@@ -26,7 +26,7 @@ func RunCronjobCommand(ctx context.Context, client *client.Client, config *confi
 	}
 
 	if len(containers) == 0 {
-		return fmt.Errorf("no scheduler container found for project %s, did you configured cronjobs", config.Name)
+		return fmt.Errorf("no scheduler container found for project %s, did you configured cronjobs", config.Identifier())
 	}
 
 	for _, c := range containers {
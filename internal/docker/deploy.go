@@ -3,10 +3,7 @@ package docker
 import (
 	"context"
 	"fmt"
-	"math/rand/v2"
-	"strings"
-
-	"github.com/pterm/pterm"
+	"slices"
 
 	"github.com/charmbracelet/log"
 
@@ -22,7 +19,8 @@ import (
 
 func newDeployConfiguration(config *config.ProjectConfig, version string) DeployConfiguration {
 	return DeployConfiguration{
-		Name:                 config.Name,
+		Name:                 config.Identifier(),
+		Environment:          config.Environment,
 		ImageName:            version,
 		ProjectConfig:        config,
 		environmentVariables: make(map[string]string),
@@ -32,6 +30,7 @@ func newDeployConfiguration(config *config.ProjectConfig, version string) Deploy
 
 type DeployConfiguration struct {
 	Name                 string
+	Environment          string
 	ImageName            string
 	ProjectConfig        *config.ProjectConfig
 	environmentVariables map[string]string
@@ -39,12 +38,24 @@ type DeployConfiguration struct {
 	serviceConfig        map[string]interface{}
 	imageConfig          *dockerspec.DockerOCIImageConfig
 	storedSecrets        map[string]string
+	// serviceHostIP is the private address of the primary server, it is set when the services are running on another server than the app and reached through a relay
+	serviceHostIP string
+	// dryRun prevents persisting generated secrets while planning a deployment
+	dryRun bool
+	// maintenance is the state of a maintenance enabled with `tanjun maintenance on`, the page stays in front of the new version
+	maintenance *MaintenanceState
 }
 
 func (c DeployConfiguration) ContainerPrefix() string {
 	return fmt.Sprintf("tanjun_%s", c.Name)
 }
 
+func (c DeployConfiguration) addEnvironmentLabel(labels map[string]string) {
+	if c.Environment != "" {
+		labels["tanjun.environment"] = c.Environment
+	}
+}
+
 func (c DeployConfiguration) GetEnvironmentVariables() []string {
 	var env []string
 
@@ -55,7 +66,18 @@ func (c DeployConfiguration) GetEnvironmentVariables() []string {
 	return env
 }
 
+// getEnvironmentContainers returns the app containers of the deployed version, containers kept warm for a rollback are skipped
 func getEnvironmentContainers(ctx context.Context, client *client.Client, projectName string) ([]container.Summary, error) {
+	containers, err := listAppContainers(ctx, client, projectName)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return slices.DeleteFunc(containers, isWarmContainer), nil
+}
+
+func listAppContainers(ctx context.Context, client *client.Client, projectName string) ([]container.Summary, error) {
 	options := container.ListOptions{
 		Filters: filters.NewArgs(),
 		All:     true,
@@ -107,6 +129,8 @@ func getAppContainerConfiguration(deployCfg DeployConfiguration) (*container.Con
 		Env: deployCfg.GetEnvironmentVariables(),
 	}
 
+	deployCfg.addEnvironmentLabel(containerCfg.Labels)
+
 	hostCfg := &container.HostConfig{
 		RestartPolicy: container.RestartPolicy{
 			Name: container.RestartPolicyUnlessStopped,
@@ -125,54 +149,86 @@ func getAppContainerConfiguration(deployCfg DeployConfiguration) (*container.Con
 	return containerCfg, hostCfg, networkCfg
 }
 
-func Deploy(ctx context.Context, client *client.Client, projectConfig *config.ProjectConfig, version string) error {
+// beginDeployment locks the project and brings everything the app containers depend on in place: the image, the network, the services and the environment variables.
+// The returned function releases the lock again
+func beginDeployment(ctx context.Context, client *client.Client, projectConfig *config.ProjectConfig, version string) (DeployConfiguration, func(), error) {
 	deployCfg := newDeployConfiguration(projectConfig, fmt.Sprintf("%s:%s", projectConfig.Image, version))
 
-	if err := PullImageIfNotThere(ctx, client, deployCfg.ImageName); err != nil {
-		return err
+	var err error
+
+	deployCfg.storage, err = CreateKVConnection(ctx, client)
+
+	if err != nil {
+		return deployCfg, nil, err
 	}
 
-	image, err := client.ImageInspect(ctx, deployCfg.ImageName)
+	unlock, err := lockProject(ctx, client, deployCfg.storage, deployCfg.Name)
 
 	if err != nil {
-		return err
+		deployCfg.storage.Close()
+		return deployCfg, nil, err
 	}
 
-	deployCfg.imageConfig = image.Config
+	done := func() {
+		unlock()
+		deployCfg.storage.Close()
+	}
 
-	deployCfg.storage, err = CreateKVConnection(ctx, client)
+	deployCfg.maintenance, err = GetMaintenanceState(deployCfg.storage, deployCfg.Name)
+
+	if err != nil {
+		done()
+		return deployCfg, nil, err
+	}
+
+	if err := prepareDeployment(ctx, client, &deployCfg); err != nil {
+		done()
+		return deployCfg, nil, err
+	}
+
+	return deployCfg, done, nil
+}
+
+func prepareDeployment(ctx context.Context, client *client.Client, deployCfg *DeployConfiguration) error {
+	if err := PullImageIfNotThere(ctx, client, deployCfg.ImageName); err != nil {
+		return err
+	}
+
+	image, err := client.ImageInspect(ctx, deployCfg.ImageName)
 
 	if err != nil {
 		return err
 	}
 
+	deployCfg.imageConfig = image.Config
+
 	deployCfg.storedSecrets, err = ListProjectSecrets(deployCfg.storage, deployCfg.Name)
 
 	if err != nil {
 		return err
 	}
 
-	defer deployCfg.storage.Close()
+	scale, err := GetScale(deployCfg.storage, deployCfg.Name)
 
-	if err := createEnvironmentNetwork(ctx, client, deployCfg); err != nil {
+	if err != nil {
 		return err
 	}
 
-	beforeContainers, err := getEnvironmentContainers(ctx, client, deployCfg.Name)
+	applyScale(deployCfg.ProjectConfig, scale)
 
-	if err != nil {
+	if err := createEnvironmentNetwork(ctx, client, *deployCfg); err != nil {
 		return err
 	}
 
-	if err := createAppServerVolumes(ctx, client, deployCfg); err != nil {
+	if err := startServices(ctx, client, *deployCfg); err != nil {
 		return err
 	}
 
-	if err := startServices(ctx, client, deployCfg); err != nil {
+	if err := startBackupScheduler(ctx, client, *deployCfg); err != nil {
 		return err
 	}
 
-	environmentVariables, err := getEnvironmentVariables(ctx, deployCfg, deployCfg.ProjectConfig.App.Environment, deployCfg.ProjectConfig.App.Secrets, deployCfg.ProjectConfig.App.InitialSecrets)
+	environmentVariables, err := getEnvironmentVariables(ctx, *deployCfg, deployCfg.ProjectConfig.App.Environment, deployCfg.ProjectConfig.App.Secrets, deployCfg.ProjectConfig.App.InitialSecrets)
 
 	if err != nil {
 		return err
@@ -180,70 +236,102 @@ func Deploy(ctx context.Context, client *client.Client, projectConfig *config.Pr
 
 	deployCfg.environmentVariables = environmentVariables
 
-	beforeWorkers, err := getWorkerEnvironmentContainers(ctx, client, deployCfg.Name)
+	return nil
+}
+
+func Deploy(ctx context.Context, client *client.Client, projectConfig *config.ProjectConfig, version string) error {
+	deployCfg, done, err := beginDeployment(ctx, client, projectConfig, version)
 
 	if err != nil {
 		return err
 	}
 
-	beforeCronjobs, err := getCronjobEnvironmentContainers(ctx, client, deployCfg.Name)
+	defer done()
+
+	if err := ensureNoCanary(deployCfg); err != nil {
+		return err
+	}
+
+	deployments, err := createServerDeployments(ctx, client, deployCfg)
 
 	if err != nil {
 		return err
 	}
 
-	allSideContainers := append(beforeWorkers, beforeCronjobs...)
+	defer closeServerDeployments(deployments)
 
-	if len(allSideContainers) > 0 {
-		spinnerInfo, err := pterm.DefaultSpinner.Start("Draining old side containers like workers and cronjobs")
+	if err := rolloutServerDeployments(deployCfg, deployments, func(d *serverDeployment) error {
+		return d.prepare(ctx, client)
+	}); err != nil {
+		return revertServerDeployments(ctx, deployments, err)
+	}
 
-		if err != nil {
-			return err
-		}
+	if len(deployCfg.ProjectConfig.App.Hooks.Deploy) > 0 && deployCfg.ProjectConfig.Proxy.Maintenance.DuringDeploy && deployCfg.maintenance == nil {
+		state := newMaintenanceState(deployCfg.ProjectConfig.Proxy.Maintenance)
 
-		if err := stopContainers(ctx, client, allSideContainers); err != nil {
-			return err
-		}
+		if err := rolloutServerDeployments(deployCfg, deployments, func(d *serverDeployment) error {
+			if len(d.beforeContainers) == 0 {
+				return nil
+			}
 
-		spinnerInfo.Success("Drained old side containers")
+			return d.enableMaintenance(ctx, state, containerIDs(d.beforeContainers))
+		}); err != nil {
+			return revertServerDeployments(ctx, deployments, err)
+		}
 	}
 
 	if len(deployCfg.ProjectConfig.App.Hooks.Deploy) > 0 {
 		log.Infof("Running deploy hook")
 		if err := runHookInContainer(ctx, client, deployCfg, deployCfg.ProjectConfig.App.Hooks.Deploy); err != nil {
-			return err
+			return revertServerDeployments(ctx, deployments, err)
 		}
 	}
 
-	containerName := fmt.Sprintf("%s_app_%d", deployCfg.ContainerPrefix(), rand.IntN(1000000))
-
-	containerCfg, hostCfg, networkCfg := getAppContainerConfiguration(deployCfg)
+	if err := rolloutServerDeployments(deployCfg, deployments, func(d *serverDeployment) error {
+		return d.switchTraffic(ctx)
+	}); err != nil {
+		return revertServerDeployments(ctx, deployments, err)
+	}
 
-	resp, err := client.ContainerCreate(ctx, containerCfg, hostCfg, networkCfg, nil, containerName)
+	if deployCfg.ProjectConfig.Proxy.Verify != nil && deployCfg.maintenance == nil {
+		if err := verifyDeployment(ctx, deployCfg, deployments); err != nil {
+			return revertServerDeployments(ctx, deployments, fmt.Errorf("verification of the new version failed: %w", err))
+		}
+	}
 
-	if err != nil {
+	if err := rolloutServerDeployments(deployCfg, deployments, func(d *serverDeployment) error {
+		return d.finish(ctx)
+	}); err != nil {
 		return err
 	}
 
-	if err := client.ContainerStart(ctx, resp.ID, container.StartOptions{}); err != nil {
-		return err
+	if len(deployCfg.ProjectConfig.App.Hooks.PostDeploy) > 0 {
+		log.Infof("Running post deploy hook")
+		if err := runHookInContainer(ctx, client, deployCfg, deployCfg.ProjectConfig.App.Hooks.PostDeploy); err != nil {
+			return err
+		}
 	}
 
-	containerInspect, err := client.ContainerInspect(ctx, resp.ID)
+	log.Infof("Deployed successful, website is reachable at %s", deployCfg.ProjectConfig.Proxy.GetURL())
 
-	if err != nil {
-		return err
+	for _, d := range deployments {
+		if len(d.beforeContainers) > 0 {
+			log.Infof("You can rollback to the previous version with tanjun deploy --rollback")
+			break
+		}
 	}
 
-	spinnerInfo, err := pterm.DefaultSpinner.Start("Routing new traffic to new container")
-
-	if err != nil {
-		return err
+	for _, d := range deployments {
+		if err := VersionDrain(ctx, d.client, deployCfg.ProjectConfig); err != nil {
+			return err
+		}
 	}
 
-	proxyHost := containerInspect.NetworkSettings.Networks["tanjun-public"].IPAddress
-	proxyPort := findPortMapping(deployCfg, &containerInspect)
+	return nil
+}
 
+// getKamalDeployCommand returns the command routing the traffic to the given targets, kamal-proxy balances the load between them
+func getKamalDeployCommand(deployCfg DeployConfiguration, targets ...string) []string {
 	kamalCmd := []string{
 		"kamal-proxy",
 		"deploy",
@@ -252,10 +340,17 @@ func Deploy(ctx context.Context, client *client.Client, projectConfig *config.Pr
 		"--health-check-path", deployCfg.ProjectConfig.Proxy.HealthCheck.Path,
 		"--health-check-interval", fmt.Sprintf("%ds", deployCfg.ProjectConfig.Proxy.HealthCheck.Interval),
 		"--health-check-timeout", fmt.Sprintf("%ds", deployCfg.ProjectConfig.Proxy.HealthCheck.Timeout),
-		"--target", fmt.Sprintf("%s:%s", proxyHost, proxyPort), deployCfg.Name,
-		"--target-timeout", fmt.Sprintf("%ds", deployCfg.ProjectConfig.Proxy.ResponseTimeout),
 	}
 
+	for _, target := range targets {
+		kamalCmd = append(kamalCmd, "--target", target)
+	}
+
+	kamalCmd = append(kamalCmd,
+		deployCfg.Name,
+		"--target-timeout", fmt.Sprintf("%ds", deployCfg.ProjectConfig.Proxy.ResponseTimeout),
+	)
+
 	if deployCfg.ProjectConfig.Proxy.SSL {
 		kamalCmd = append(kamalCmd, "--tls")
 	}
@@ -280,59 +375,7 @@ func Deploy(ctx context.Context, client *client.Client, projectConfig *config.Pr
 		kamalCmd = append(kamalCmd, "--buffer-memory", fmt.Sprintf("%d", deployCfg.ProjectConfig.Proxy.Buffering.Memory))
 	}
 
-	log.Debugf("Kamal command: %s", strings.Join(kamalCmd, " "))
-
-	removalContainers := append(beforeContainers, beforeWorkers...)
-
-	if err := configureKamalService(ctx, client, kamalCmd); err != nil {
-		spinnerInfo.Fail(err)
-
-		if len(removalContainers) > 0 {
-			// If we fail to configure kamal, we should stop the container and remove it
-			if restoreErr := client.ContainerKill(ctx, resp.ID, "SIGKILL"); restoreErr != nil {
-				return fmt.Errorf("kamal configure failed: %w and could not stop the new container: %s", err, restoreErr)
-			}
-
-			if restoreErr := client.ContainerRemove(ctx, resp.ID, container.RemoveOptions{}); restoreErr != nil {
-				return fmt.Errorf("kamal configure failed: %w and could not remove the new container: %s", err, restoreErr)
-			}
-
-			if restoreErr := startContainers(ctx, client, removalContainers); restoreErr != nil {
-				return fmt.Errorf("kamal configure failed: %w and could not start the old workers / cronjobs: %s", err, restoreErr)
-			}
-		}
-
-		return err
-	}
-
-	spinnerInfo.Success("Routing new traffic to new container successful")
-
-	if err := removeContainers(ctx, client, append(removalContainers, beforeCronjobs...)); err != nil {
-		return err
-	}
-
-	if err := startWorkers(ctx, client, deployCfg); err != nil {
-		return err
-	}
-
-	if err := startCronjobs(ctx, client, deployCfg); err != nil {
-		return err
-	}
-
-	if len(deployCfg.ProjectConfig.App.Hooks.PostDeploy) > 0 {
-		log.Infof("Running post deploy hook")
-		if err := runHookInContainer(ctx, client, deployCfg, deployCfg.ProjectConfig.App.Hooks.PostDeploy); err != nil {
-			return err
-		}
-	}
-
-	log.Infof("Deployed successful, website is reachable at %s", deployCfg.ProjectConfig.Proxy.GetURL())
-
-	if len(beforeContainers) > 0 {
-		log.Infof("You can rollback to the previous version with tanjun deploy --rollback")
-	}
-
-	return VersionDrain(ctx, client, deployCfg.ProjectConfig)
+	return kamalCmd
 }
 
 func createAppServerVolumes(ctx context.Context, client *client.Client, deployCfg DeployConfiguration) error {
@@ -450,7 +493,7 @@ func createEnvironmentNetwork(ctx context.Context, c *client.Client, deployCfg D
 		return err
 	}
 
-	if len(networks) > 0 {
+	if hasNetwork(networks, deployCfg.Name) {
 		return nil
 	}
 
@@ -463,3 +506,10 @@ func createEnvironmentNetwork(ctx context.Context, c *client.Client, deployCfg D
 
 	return err
 }
+
+// hasNetwork checks for the exact name, the name filter of docker also matches networks containing the name like the ones of other environments
+func hasNetwork(networks []network.Summary, name string) bool {
+	return slices.ContainsFunc(networks, func(n network.Summary) bool {
+		return n.Name == name
+	})
+}
@@ -12,6 +12,8 @@ import (
 	"github.com/docker/docker/client"
 )
 
+const schedulerImage = "ghcr.io/shyim/tanjun/scheduler:v2"
+
 func startCronjobs(ctx context.Context, client *client.Client, deployConfig DeployConfiguration) error {
 	if len(deployConfig.ProjectConfig.App.Cronjobs) == 0 {
 		return nil
@@ -27,6 +29,8 @@ func startCronjobs(ctx context.Context, client *client.Client, deployConfig Depl
 		"ofelia.enabled":             "true",
 	}
 
+	deployConfig.addEnvironmentLabel(containerCfg.Labels)
+
 	containerCfg.Entrypoint = []string{"sh"}
 	containerCfg.Cmd = []string{}
 	containerCfg.Tty = true
@@ -56,12 +60,12 @@ func startCronjobs(ctx context.Context, client *client.Client, deployConfig Depl
 }
 
 func startScheduler(ctx context.Context, client *client.Client, deployConfig DeployConfiguration, schedulerConfig string) error {
-	if err := PullImageIfNotThere(ctx, client, "ghcr.io/shyim/tanjun/scheduler:v1"); err != nil {
+	if err := PullImageIfNotThere(ctx, client, schedulerImage); err != nil {
 		return err
 	}
 
 	cfg := &container.Config{
-		Image: "ghcr.io/shyim/tanjun/scheduler:v1",
+		Image: schedulerImage,
 		Labels: map[string]string{
 			"com.docker.compose.project": deployConfig.ContainerPrefix(),
 			"com.docker.compose.service": "scheduler",
@@ -72,6 +76,8 @@ func startScheduler(ctx context.Context, client *client.Client, deployConfig Dep
 		Env: []string{"SCHEDULER_CONFIG=" + schedulerConfig},
 	}
 
+	deployConfig.addEnvironmentLabel(cfg.Labels)
+
 	hostCfg := &container.HostConfig{
 		RestartPolicy: container.RestartPolicy{
 			Name: container.RestartPolicyUnlessStopped,
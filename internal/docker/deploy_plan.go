@@ -0,0 +1,229 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/volume"
+	"github.com/docker/docker/client"
+	dockerspec "github.com/moby/docker-image-spec/specs-go/v1"
+	"github.com/shyim/tanjun/internal/config"
+)
+
+// planProxyTarget is shown in the kamal command instead of the address of the app container, which exists only after applying
+const planProxyTarget = "<new app container>"
+
+// DeployPlan describes what a deployment would change. Computing it does not touch any container, volume or secret
+type DeployPlan struct {
+	Version     string
+	Services    []*ServicePlan
+	Environment []string
+	Hooks       []string
+	Servers     []ServerDeployPlan
+}
+
+type ServerDeployPlan struct {
+	Address           string
+	CreateNetwork     bool
+	CreateVolumes     []string
+	ReplaceContainers []string
+	ReplaceWorkers    []string
+	ReplaceCronjobs   []string
+	StartContainers   int
+	StartWorkers      []string
+	StartCronjobs     []string
+	KamalCommand      []string
+}
+
+func PlanDeploy(ctx context.Context, client *client.Client, projectConfig *config.ProjectConfig, version string) (*DeployPlan, error) {
+	deployCfg := newDeployConfiguration(projectConfig, fmt.Sprintf("%s:%s", projectConfig.Image, version))
+	deployCfg.dryRun = true
+	deployCfg.imageConfig = &dockerspec.DockerOCIImageConfig{}
+
+	// The image of a version which is not built yet is unknown, only relative mount paths depend on it
+	if image, err := client.ImageInspect(ctx, deployCfg.ImageName); err == nil && image.Config != nil {
+		deployCfg.imageConfig = image.Config
+	}
+
+	var err error
+
+	deployCfg.storage, err = CreateKVConnection(ctx, client)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer deployCfg.storage.Close()
+
+	deployCfg.storedSecrets, err = ListProjectSecrets(deployCfg.storage, deployCfg.Name)
+
+	if err != nil {
+		return nil, err
+	}
+
+	scale, err := GetScale(deployCfg.storage, deployCfg.Name)
+
+	if err != nil {
+		return nil, err
+	}
+
+	applyScale(deployCfg.ProjectConfig, scale)
+
+	plan := &DeployPlan{Version: version}
+
+	plan.Services, err = planServices(ctx, client, deployCfg)
+
+	if err != nil {
+		return nil, err
+	}
+
+	environmentVariables, err := getEnvironmentVariables(ctx, deployCfg, deployCfg.ProjectConfig.App.Environment, deployCfg.ProjectConfig.App.Secrets, deployCfg.ProjectConfig.App.InitialSecrets)
+
+	if err != nil {
+		return nil, err
+	}
+
+	deployCfg.environmentVariables = environmentVariables
+	plan.Environment = slices.Sorted(maps.Keys(environmentVariables))
+
+	if deployCfg.ProjectConfig.App.Hooks.Deploy != "" {
+		plan.Hooks = append(plan.Hooks, "deploy")
+	}
+
+	if deployCfg.ProjectConfig.App.Hooks.PostDeploy != "" {
+		plan.Hooks = append(plan.Hooks, "post_deploy")
+	}
+
+	deployments, err := createServerDeployments(ctx, client, deployCfg)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer closeServerDeployments(deployments)
+
+	for _, d := range deployments {
+		serverPlan, err := d.plan(ctx, plan.Services)
+
+		if err != nil {
+			return nil, fmt.Errorf("server %s: %w", d.server.Address, err)
+		}
+
+		plan.Servers = append(plan.Servers, serverPlan)
+	}
+
+	return plan, nil
+}
+
+// plan collects the changes the deployment would do on this server
+func (d *serverDeployment) plan(ctx context.Context, services []*ServicePlan) (ServerDeployPlan, error) {
+	serverPlan := ServerDeployPlan{Address: d.server.Address}
+
+	networkOptions := network.ListOptions{Filters: filters.NewArgs()}
+	networkOptions.Filters.Add("name", d.deployCfg.Name)
+
+	networks, err := d.client.NetworkList(ctx, networkOptions)
+
+	if err != nil {
+		return serverPlan, err
+	}
+
+	serverPlan.CreateNetwork = !hasNetwork(networks, d.deployCfg.Name)
+
+	volumeOptions := volume.ListOptions{Filters: filters.NewArgs()}
+	volumeOptions.Filters.Add("label", fmt.Sprintf("tanjun.project=%s", d.deployCfg.Name))
+
+	volumes, err := d.client.VolumeList(ctx, volumeOptions)
+
+	if err != nil {
+		return serverPlan, err
+	}
+
+	wantedVolumes := []string{}
+
+	for mountName := range d.deployCfg.ProjectConfig.App.Mounts {
+		wantedVolumes = append(wantedVolumes, fmt.Sprintf("%s_app_%s", d.deployCfg.ContainerPrefix(), mountName))
+	}
+
+	if d.primary {
+		for _, service := range services {
+			if service.Action != ServiceActionKeep {
+				wantedVolumes = append(wantedVolumes, service.Volumes()...)
+			}
+		}
+	}
+
+	for _, wantedVolume := range wantedVolumes {
+		if !slices.ContainsFunc(volumes.Volumes, func(v *volume.Volume) bool { return v.Name == wantedVolume }) {
+			serverPlan.CreateVolumes = append(serverPlan.CreateVolumes, wantedVolume)
+		}
+	}
+
+	slices.Sort(serverPlan.CreateVolumes)
+
+	beforeContainers, err := getEnvironmentContainers(ctx, d.client, d.deployCfg.Name)
+
+	if err != nil {
+		return serverPlan, err
+	}
+
+	beforeWorkers, err := getWorkerEnvironmentContainers(ctx, d.client, d.deployCfg.Name)
+
+	if err != nil {
+		return serverPlan, err
+	}
+
+	beforeCronjobs, err := getCronjobEnvironmentContainers(ctx, d.client, d.deployCfg.Name)
+
+	if err != nil {
+		return serverPlan, err
+	}
+
+	serverPlan.ReplaceContainers = containerNames(beforeContainers)
+	serverPlan.ReplaceWorkers = containerNames(beforeWorkers)
+	serverPlan.ReplaceCronjobs = containerNames(beforeCronjobs)
+
+	if d.server.HasRole(config.ServerRoleWeb) {
+		serverPlan.StartContainers = d.deployCfg.ProjectConfig.App.Replicas
+		serverPlan.KamalCommand = getKamalDeployCommand(d.deployCfg, slices.Repeat([]string{planProxyTarget}, serverPlan.StartContainers)...)
+	}
+
+	if d.server.HasRole(config.ServerRoleWorker) {
+		for _, workerName := range slices.Sorted(maps.Keys(d.deployCfg.ProjectConfig.App.Workers)) {
+			replicas := WorkerReplicas(d.deployCfg.ProjectConfig.App.Workers[workerName])
+
+			serverPlan.StartWorkers = append(serverPlan.StartWorkers, fmt.Sprintf("%s (%d replicas)", workerName, replicas))
+		}
+	}
+
+	if d.server.HasRole(config.ServerRoleCron) {
+		for _, cronjob := range d.deployCfg.ProjectConfig.App.Cronjobs {
+			serverPlan.StartCronjobs = append(serverPlan.StartCronjobs, fmt.Sprintf("%s (%s)", cronjob.Name, cronjob.Schedule))
+		}
+	}
+
+	return serverPlan, nil
+}
+
+func containerNames(containers []container.Summary) []string {
+	names := make([]string, 0, len(containers))
+
+	for _, c := range containers {
+		if len(c.Names) == 0 {
+			names = append(names, c.ID[:12])
+			continue
+		}
+
+		names = append(names, strings.TrimPrefix(c.Names[0], "/"))
+	}
+
+	slices.Sort(names)
+
+	return names
+}
@@ -0,0 +1,443 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"math/rand/v2"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/log"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+	"github.com/pterm/pterm"
+	"github.com/shyim/tanjun/internal/config"
+	"golang.org/x/sync/errgroup"
+)
+
+// serverDeployment tracks the rollout of one version to one server, so a failed rollout can be reverted on all servers
+type serverDeployment struct {
+	server    config.ProjectServer
+	client    *client.Client
+	primary   bool
+	multiple  bool
+	deployCfg DeployConfiguration
+
+	beforeContainers []container.Summary
+	beforeWorkers    []container.Summary
+	beforeCronjobs   []container.Summary
+
+	drained         bool
+	newContainerIDs []string
+	switched        bool
+	// reusedWarm are the warm containers of newContainerIDs, a revert puts them back into the pool instead of removing them
+	reusedWarm map[string]container.Summary
+	// maintenance is set while a maintenance container of the project receives the traffic
+	maintenance bool
+}
+
+func createServerDeployments(ctx context.Context, primaryClient *client.Client, deployCfg DeployConfiguration) ([]*serverDeployment, error) {
+	servers := deployCfg.ProjectConfig.AllServers()
+	deployments := make([]*serverDeployment, 0, len(servers))
+
+	serviceHostIP := ""
+
+	if len(servers) > 1 && len(deployCfg.ProjectConfig.Services) > 0 {
+		serviceHostIP = deployCfg.ProjectConfig.Server.PrivateAddress
+	}
+
+	for i, server := range servers {
+		deployment := &serverDeployment{
+			server:    server,
+			client:    primaryClient,
+			primary:   i == 0,
+			multiple:  len(servers) > 1,
+			deployCfg: deployCfg,
+		}
+
+		if !deployment.primary {
+			c, err := CreateClientForServer(server)
+
+			if err != nil {
+				closeServerDeployments(deployments)
+				return nil, err
+			}
+
+			deployment.client = c
+			deployment.deployCfg.serviceHostIP = serviceHostIP
+		}
+
+		deployments = append(deployments, deployment)
+	}
+
+	return deployments, nil
+}
+
+func closeServerDeployments(deployments []*serverDeployment) {
+	for _, d := range deployments {
+		if d.primary {
+			continue
+		}
+
+		if err := d.client.Close(); err != nil {
+			log.Warnf("Failed to close docker client of server %s: %s", d.server.Address, err)
+		}
+	}
+}
+
+// rolloutServerDeployments runs one step of the deployment on all servers, either one after another or all at once
+func rolloutServerDeployments(deployCfg DeployConfiguration, deployments []*serverDeployment, step func(d *serverDeployment) error) error {
+	run := func(d *serverDeployment) error {
+		if err := step(d); err != nil {
+			if d.multiple {
+				return fmt.Errorf("server %s: %w", d.server.Address, err)
+			}
+
+			return err
+		}
+
+		return nil
+	}
+
+	if deployCfg.ProjectConfig.Rollout == "parallel" {
+		var group errgroup.Group
+
+		for _, d := range deployments {
+			group.Go(func() error {
+				return run(d)
+			})
+		}
+
+		return group.Wait()
+	}
+
+	for _, d := range deployments {
+		if err := run(d); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// revertServerDeployments brings every server back to the previous deployment and returns the cause enriched with all errors happening while reverting
+func revertServerDeployments(ctx context.Context, deployments []*serverDeployment, cause error) error {
+	for _, d := range deployments {
+		if err := d.revert(ctx); err != nil {
+			cause = fmt.Errorf("%w and could not restore server %s: %s", cause, d.server.Address, err)
+		}
+	}
+
+	return cause
+}
+
+func (d *serverDeployment) prepare(ctx context.Context, primaryClient *client.Client) error {
+	if err := d.prepareServer(ctx, primaryClient); err != nil {
+		return err
+	}
+
+	sideContainers := d.sideContainers()
+
+	if len(sideContainers) > 0 {
+		spinnerInfo, err := pterm.DefaultSpinner.Start("Draining old side containers like workers and cronjobs" + d.describe())
+
+		if err != nil {
+			return err
+		}
+
+		d.drained = true
+
+		if err := stopContainers(ctx, d.client, sideContainers); err != nil {
+			spinnerInfo.Fail(err)
+			return err
+		}
+
+		spinnerInfo.Success("Drained old side containers" + d.describe())
+	}
+
+	return nil
+}
+
+// prepareServer makes the image, network and volumes available and remembers the containers of the previous deployment
+func (d *serverDeployment) prepareServer(ctx context.Context, primaryClient *client.Client) error {
+	var err error
+
+	if !d.primary {
+		if err := d.ensureImage(ctx, primaryClient); err != nil {
+			return err
+		}
+
+		if err := createEnvironmentNetwork(ctx, d.client, d.deployCfg); err != nil {
+			return err
+		}
+
+		if d.deployCfg.serviceHostIP != "" {
+			if err := ensureServiceRelays(ctx, d.client, d.deployCfg); err != nil {
+				return err
+			}
+		}
+	}
+
+	d.beforeContainers, err = getEnvironmentContainers(ctx, d.client, d.deployCfg.Name)
+
+	if err != nil {
+		return err
+	}
+
+	if err := createAppServerVolumes(ctx, d.client, d.deployCfg); err != nil {
+		return err
+	}
+
+	d.beforeWorkers, err = getWorkerEnvironmentContainers(ctx, d.client, d.deployCfg.Name)
+
+	if err != nil {
+		return err
+	}
+
+	d.beforeCronjobs, err = getCronjobEnvironmentContainers(ctx, d.client, d.deployCfg.Name)
+
+	return err
+}
+
+func (d *serverDeployment) switchTraffic(ctx context.Context) error {
+	if !d.server.HasRole(config.ServerRoleWeb) {
+		return nil
+	}
+
+	if err := d.reuseWarmContainers(ctx); err != nil {
+		return err
+	}
+
+	for len(d.newContainerIDs) < d.deployCfg.ProjectConfig.App.Replicas {
+		if err := d.startAppContainer(ctx, nil); err != nil {
+			return err
+		}
+	}
+
+	if d.deployCfg.maintenance != nil {
+		if err := d.enableMaintenance(ctx, *d.deployCfg.maintenance, d.newContainerIDs); err != nil {
+			return err
+		}
+
+		d.switched = true
+
+		return nil
+	}
+
+	spinnerInfo, err := pterm.DefaultSpinner.Start("Routing new traffic to new container" + d.describe())
+
+	if err != nil {
+		return err
+	}
+
+	targets, err := getContainerProxyTargets(ctx, d.client, d.deployCfg, d.newContainerIDs)
+
+	if err != nil {
+		spinnerInfo.Fail(err)
+		return err
+	}
+
+	kamalCmd := getKamalDeployCommand(d.deployCfg, targets...)
+
+	log.Debugf("Kamal command: %s", strings.Join(kamalCmd, " "))
+
+	if err := configureKamalService(ctx, d.client, kamalCmd); err != nil {
+		spinnerInfo.Fail(err)
+		return err
+	}
+
+	d.switched = true
+
+	spinnerInfo.Success("Routing new traffic to new container successful" + d.describe())
+
+	if d.maintenance {
+		return d.removeMaintenanceContainers(ctx)
+	}
+
+	return nil
+}
+
+// startAppContainer creates and starts the app container of the new version with additional labels
+func (d *serverDeployment) startAppContainer(ctx context.Context, labels map[string]string) error {
+	containerName := fmt.Sprintf("%s_app_%d", d.deployCfg.ContainerPrefix(), rand.IntN(1000000))
+
+	containerCfg, hostCfg, networkCfg := getAppContainerConfiguration(d.deployCfg)
+
+	maps.Copy(containerCfg.Labels, labels)
+
+	resp, err := d.client.ContainerCreate(ctx, containerCfg, hostCfg, networkCfg, nil, containerName)
+
+	if err != nil {
+		return err
+	}
+
+	d.newContainerIDs = append(d.newContainerIDs, resp.ID)
+
+	return d.client.ContainerStart(ctx, resp.ID, container.StartOptions{})
+}
+
+func (d *serverDeployment) finish(ctx context.Context) error {
+	if err := d.removeExpiredWarmContainers(ctx); err != nil {
+		return err
+	}
+
+	oldContainers := slices.Concat(d.beforeContainers, d.beforeWorkers, d.beforeCronjobs)
+
+	if d.deployCfg.ProjectConfig.App.KeepWarm.Duration > 0 {
+		if err := d.keepWarm(ctx); err != nil {
+			return err
+		}
+
+		oldContainers = d.sideContainers()
+	}
+
+	if err := removeContainers(ctx, d.client, oldContainers); err != nil {
+		return err
+	}
+
+	if d.server.HasRole(config.ServerRoleWorker) {
+		if err := startWorkers(ctx, d.client, d.deployCfg); err != nil {
+			return err
+		}
+	}
+
+	if d.server.HasRole(config.ServerRoleCron) {
+		if err := startCronjobs(ctx, d.client, d.deployCfg); err != nil {
+			return err
+		}
+	}
+
+	if d.deployCfg.maintenance != nil && d.deployCfg.maintenance.PausedWorkers {
+		return d.pauseSideContainers(ctx)
+	}
+
+	return nil
+}
+
+// revert routes the traffic back to the previous containers, removes the new ones and restarts the drained workers and cronjobs.
+// On the first deployment to a server there is nothing to go back to, so the new container is kept for debugging.
+func (d *serverDeployment) revert(ctx context.Context) error {
+	sideContainers := d.sideContainers()
+
+	if len(d.beforeContainers) == 0 && len(sideContainers) == 0 {
+		return nil
+	}
+
+	log.Warnf("Restoring the previous deployment%s", d.describe())
+
+	if d.deployCfg.maintenance != nil && d.switched {
+		if err := d.startMaintenanceContainer(ctx, *d.deployCfg.maintenance, containerIDs(d.beforeContainers)); err != nil {
+			return fmt.Errorf("could not route the traffic back to the previous container: %w", err)
+		}
+	} else if (d.switched || d.maintenance) && len(d.beforeContainers) > 0 {
+		targets, err := getContainerProxyTargets(ctx, d.client, d.deployCfg, containerIDs(d.beforeContainers))
+
+		if err != nil {
+			return err
+		}
+
+		if err := configureKamalService(ctx, d.client, getKamalDeployCommand(d.deployCfg, targets...)); err != nil {
+			return fmt.Errorf("could not route the traffic back to the previous container: %w", err)
+		}
+
+		if d.maintenance {
+			if err := d.removeMaintenanceContainers(ctx); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, containerID := range d.newContainerIDs {
+		if warm, ok := d.reusedWarm[containerID]; ok {
+			if err := d.returnWarmContainer(ctx, warm); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		if err := d.client.ContainerKill(ctx, containerID, "SIGKILL"); err != nil {
+			return fmt.Errorf("could not stop the new container: %w", err)
+		}
+
+		if err := d.client.ContainerRemove(ctx, containerID, container.RemoveOptions{}); err != nil {
+			return fmt.Errorf("could not remove the new container: %w", err)
+		}
+	}
+
+	if d.drained {
+		if err := startContainers(ctx, d.client, sideContainers); err != nil {
+			return fmt.Errorf("could not start the old workers / cronjobs: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// ensureImage makes the image available on a secondary server. Remote built images exist only on the primary server, so they are copied over
+func (d *serverDeployment) ensureImage(ctx context.Context, primaryClient *client.Client) error {
+	if !d.deployCfg.ProjectConfig.Build.RemoteBuild {
+		return PullImageIfNotThere(ctx, d.client, d.deployCfg.ImageName)
+	}
+
+	if _, err := d.client.ImageInspect(ctx, d.deployCfg.ImageName); err == nil {
+		return nil
+	}
+
+	return transferImage(ctx, primaryClient, d.client, d.deployCfg.ImageName)
+}
+
+func (d *serverDeployment) sideContainers() []container.Summary {
+	return slices.Concat(d.beforeWorkers, d.beforeCronjobs)
+}
+
+func (d *serverDeployment) describe() string {
+	if !d.multiple {
+		return ""
+	}
+
+	return fmt.Sprintf(" on %s", d.server.Address)
+}
+
+func getContainerProxyTarget(ctx context.Context, client *client.Client, deployCfg DeployConfiguration, containerID string) (string, error) {
+	containerInspect, err := client.ContainerInspect(ctx, containerID)
+
+	if err != nil {
+		return "", err
+	}
+
+	publicNetwork, ok := containerInspect.NetworkSettings.Networks[kamalNetworkName]
+
+	if !ok {
+		return "", fmt.Errorf("container %s is not connected to the %s network", containerID, kamalNetworkName)
+	}
+
+	return fmt.Sprintf("%s:%s", publicNetwork.IPAddress, findPortMapping(deployCfg, &containerInspect)), nil
+}
+
+func getContainerProxyTargets(ctx context.Context, client *client.Client, deployCfg DeployConfiguration, containerIDs []string) ([]string, error) {
+	targets := make([]string, 0, len(containerIDs))
+
+	for _, containerID := range containerIDs {
+		target, err := getContainerProxyTarget(ctx, client, deployCfg, containerID)
+
+		if err != nil {
+			return nil, err
+		}
+
+		targets = append(targets, target)
+	}
+
+	return targets, nil
+}
+
+func containerIDs(containers []container.Summary) []string {
+	ids := make([]string, 0, len(containers))
+
+	for _, c := range containers {
+		ids = append(ids, c.ID)
+	}
+
+	return ids
+}
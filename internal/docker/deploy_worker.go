@@ -61,6 +61,8 @@ func startWorker(ctx context.Context, client *client.Client, deployCfg DeployCon
 				"tanjun.project":             deployCfg.Name,
 			}
 
+			deployCfg.addEnvironmentLabel(containerConfig.Labels)
+
 			hostConfig.RestartPolicy = container.RestartPolicy{
 				Name: container.RestartPolicyUnlessStopped,
 			}
@@ -13,7 +13,24 @@ import (
 )
 
 func DestroyProject(ctx context.Context, client *client.Client, name string) error {
-	containerOpts := container.ListOptions{Filters: filters.NewArgs()}
+	kv, err := CreateKVConnection(ctx, client)
+
+	if err != nil {
+		return err
+	}
+
+	defer kv.Close()
+
+	// A running deployment would start the containers again, so the project is only removed while holding the lock
+	unlock, err := lockProject(ctx, client, kv, name)
+
+	if err != nil {
+		return err
+	}
+
+	defer unlock()
+
+	containerOpts := container.ListOptions{All: true, Filters: filters.NewArgs()}
 
 	containerOpts.Filters.Add("label", fmt.Sprintf("tanjun.project=%s", slug.Make(name)))
 
@@ -23,9 +40,16 @@ func DestroyProject(ctx context.Context, client *client.Client, name string) err
 		return err
 	}
 
+	if err := removeServiceRoutes(ctx, client, containers); err != nil {
+		return err
+	}
+
 	for _, c := range containers {
-		if err := client.ContainerKill(ctx, c.ID, "SIGKILL"); err != nil {
-			return err
+		// stopped containers like warm app containers, paused workers or finished one-off tasks are removed as well
+		if c.State == "running" {
+			if err := client.ContainerKill(ctx, c.ID, "SIGKILL"); err != nil {
+				return err
+			}
 		}
 
 		if err := client.ContainerRemove(ctx, c.ID, container.RemoveOptions{Force: true}); err != nil {
@@ -63,17 +87,21 @@ func DestroyProject(ctx context.Context, client *client.Client, name string) err
 		}
 	}
 
-	kv, err := CreateKVConnection(ctx, client)
+	cfg := DeployConfiguration{Name: slug.Make(name)}
 
-	if err != nil {
+	if err := deleteAllProjectSecrets(kv, name); err != nil {
 		return err
 	}
 
-	defer kv.Close()
+	if err := kv.Delete(canaryKey(name)); err != nil {
+		return err
+	}
 
-	cfg := DeployConfiguration{Name: slug.Make(name)}
+	if err := kv.Delete(scaleKey(name)); err != nil {
+		return err
+	}
 
-	if err := kv.Delete(cfg.ContainerPrefix() + "_secrets"); err != nil {
+	if err := kv.Delete(maintenanceKey(name)); err != nil {
 		return err
 	}
 
@@ -10,6 +10,7 @@ import (
 	"github.com/joho/godotenv"
 	"github.com/shyim/tanjun/internal/config"
 	"github.com/shyim/tanjun/internal/onepassword"
+	"github.com/shyim/tanjun/internal/secretfile"
 
 	"github.com/expr-lang/expr"
 )
@@ -51,6 +52,10 @@ func getEnvironmentVariables(ctx context.Context, cfg DeployConfiguration, envir
 		return nil, err
 	}
 
+	if err := resolveEncryptedFiles(ctx, returnSecrets, genericSecret); err != nil {
+		return nil, err
+	}
+
 	if err := resolveInitialSecrets(returnSecrets, cfg, context, initialSecrets); err != nil {
 		return nil, err
 	}
@@ -80,6 +85,35 @@ func resolveFromStoredSecrets(returnSecrets map[string]string, cfg DeployConfigu
 	return nil
 }
 
+// resolveEncryptedFiles decrypts the SOPS and age files locally, the decrypted values are only sent to the server as environment of the containers
+func resolveEncryptedFiles(ctx context.Context, returnSecrets map[string]string, genericSecrets config.ProjectGenericSecrets) error {
+	for _, file := range genericSecrets.FromSops {
+		secrets, err := secretfile.ResolveSops(ctx, file)
+
+		if err != nil {
+			return err
+		}
+
+		for key, value := range secrets {
+			returnSecrets[key] = value
+		}
+	}
+
+	for _, file := range genericSecrets.FromAge {
+		secrets, err := secretfile.ResolveAge(ctx, file)
+
+		if err != nil {
+			return err
+		}
+
+		for key, value := range secrets {
+			returnSecrets[key] = value
+		}
+	}
+
+	return nil
+}
+
 func resolveOnePasswordSecrets(ctx context.Context, returnSecrets map[string]string, genericSecrets config.ProjectGenericSecrets) error {
 	for _, secret := range genericSecrets.OnePassword.Secret {
 		onePasswordSecrets, err := onepassword.ResolveSecrets(ctx, secret)
@@ -118,6 +152,17 @@ func resolveEnvFromExpression(cfg DeployConfiguration, returnSecrets map[string]
 	return nil
 }
 
+// ReadEnvFile parses a dotenv file like .env.production
+func ReadEnvFile(fileName string) (map[string]string, error) {
+	envMap, err := godotenv.Read(fileName)
+
+	if err != nil {
+		return nil, fmt.Errorf("error reading environment file %s: %w", fileName, err)
+	}
+
+	return envMap, nil
+}
+
 func resolveEnvFromFile(returnSecrets map[string]string, genericSecrets config.ProjectGenericSecrets) error {
 	for _, fileName := range genericSecrets.FromEnvFile {
 		if _, err := os.Stat(fileName); os.IsNotExist(err) {
@@ -126,10 +171,10 @@ func resolveEnvFromFile(returnSecrets map[string]string, genericSecrets config.P
 			continue
 		}
 
-		envMap, err := godotenv.Read(fileName)
+		envMap, err := ReadEnvFile(fileName)
 
 		if err != nil {
-			return fmt.Errorf("error reading environment file %s: %w", fileName, err)
+			return err
 		}
 
 		for key, value := range envMap {
@@ -141,7 +186,7 @@ func resolveEnvFromFile(returnSecrets map[string]string, genericSecrets config.P
 }
 
 func resolveInitialSecrets(returnSecrets map[string]string, cfg DeployConfiguration, context map[string]interface{}, initialSecrets map[string]config.ProjectInitialSecrets) error {
-	changed := false
+	created := map[string]string{}
 
 	for key, value := range initialSecrets {
 		if _, ok := returnSecrets[key]; ok {
@@ -162,12 +207,11 @@ func resolveInitialSecrets(returnSecrets map[string]string, cfg DeployConfigurat
 
 		returnSecrets[key] = output.(string)
 		cfg.storedSecrets[key] = output.(string)
-
-		changed = true
+		created[key] = output.(string)
 	}
 
-	if changed {
-		if err := SetProjectSecrets(cfg.storage, cfg.Name, cfg.storedSecrets); err != nil {
+	if len(created) > 0 && !cfg.dryRun {
+		if err := SetProjectSecrets(cfg.storage, cfg.Name, created); err != nil {
 			return err
 		}
 	}
@@ -5,17 +5,20 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"fmt"
 	"io"
 	"os"
 	"slices"
 	"strings"
 
+	"github.com/charmbracelet/log"
 	"github.com/docker/cli/cli/config"
 	"github.com/docker/cli/cli/config/configfile"
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/image"
 	"github.com/docker/docker/client"
 	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/pterm/pterm"
 )
 
 var configFile *configfile.ConfigFile
@@ -127,3 +130,46 @@ func determineUidOfAppContainer(ctx context.Context, client *client.Client, imag
 
 	return scanner.Text(), nil
 }
+
+func transferImage(ctx context.Context, source *client.Client, target *client.Client, imageName string) error {
+	spinnerInfo, err := pterm.DefaultSpinner.Start(fmt.Sprintf("Copying image %s to server", imageName))
+
+	if err != nil {
+		return err
+	}
+
+	reader, err := source.ImageSave(ctx, []string{imageName})
+
+	if err != nil {
+		spinnerInfo.Fail(err)
+		return err
+	}
+
+	defer func() {
+		if err := reader.Close(); err != nil {
+			log.Warnf("Failed to close image stream: %s", err)
+		}
+	}()
+
+	resp, err := target.ImageLoad(ctx, reader)
+
+	if err != nil {
+		spinnerInfo.Fail(err)
+		return err
+	}
+
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			log.Warnf("Failed to close response body: %s", err)
+		}
+	}()
+
+	if _, err := io.ReadAll(resp.Body); err != nil {
+		spinnerInfo.Fail(err)
+		return err
+	}
+
+	spinnerInfo.Success(fmt.Sprintf("Copied image %s to server", imageName))
+
+	return nil
+}
@@ -0,0 +1,157 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/docker/docker/client"
+	"github.com/gosimple/slug"
+)
+
+const (
+	HistoryActionDeploy         = "deploy"
+	HistoryActionRollback       = "rollback"
+	HistoryActionCanary         = "canary"
+	HistoryActionPromote        = "rollout promote"
+	HistoryActionAbort          = "rollout abort"
+	HistoryActionScale          = "scale"
+	HistoryActionMaintenanceOn  = "maintenance on"
+	HistoryActionMaintenanceOff = "maintenance off"
+	HistoryActionPreviewDeploy  = "preview deploy"
+	HistoryActionPreviewDestroy = "preview destroy"
+	HistoryActionSecretSet      = "secret set"
+	HistoryActionSecretDel      = "secret del"
+	HistoryActionSecretRollback = "secret rollback"
+	HistoryActionSecretImport   = "secret import"
+	HistoryActionSecretSync     = "secret sync"
+	HistoryActionDestroy        = "destroy"
+)
+
+// historyLimit is the amount of entries kept per project, older entries are dropped
+const historyLimit = 100
+
+type HistoryEntry struct {
+	Action    string        `json:"action"`
+	Version   string        `json:"version,omitempty"`
+	GitCommit string        `json:"git_commit,omitempty"`
+	Details   string        `json:"details,omitempty"`
+	User      string        `json:"user"`
+	Host      string        `json:"host"`
+	StartedAt time.Time     `json:"started_at"`
+	Duration  time.Duration `json:"duration"`
+	Success   bool          `json:"success"`
+	Error     string        `json:"error,omitempty"`
+}
+
+func NewHistoryEntry(action string) *HistoryEntry {
+	return &HistoryEntry{
+		Action:    action,
+		GitCommit: currentGitCommit(),
+		User:      currentUsername(),
+		Host:      currentHostname(),
+		StartedAt: time.Now().UTC(),
+	}
+}
+
+// Finish sets the outcome of the action
+func (e *HistoryEntry) Finish(err error) {
+	e.Duration = time.Since(e.StartedAt).Round(time.Millisecond)
+	e.Success = err == nil
+
+	if err != nil {
+		e.Error = err.Error()
+	}
+}
+
+func historyKey(name string) string {
+	cfg := DeployConfiguration{Name: slug.Make(name)}
+
+	return cfg.ContainerPrefix() + "_history"
+}
+
+// ListHistory returns the history of the project, newest entry first
+func ListHistory(kv *KvClient, name string) ([]HistoryEntry, error) {
+	value, err := kv.Get(historyKey(name))
+
+	if err != nil {
+		return nil, err
+	}
+
+	return parseHistory(value)
+}
+
+func parseHistory(value string) ([]HistoryEntry, error) {
+	if value == "" {
+		return []HistoryEntry{}, nil
+	}
+
+	var entries []HistoryEntry
+
+	if err := json.Unmarshal([]byte(value), &entries); err != nil {
+		return nil, fmt.Errorf("could not parse history: %w", err)
+	}
+
+	return entries, nil
+}
+
+func AppendHistory(kv *KvClient, name string, entry HistoryEntry) error {
+	err := kv.Update(historyKey(name), func(value string) (string, error) {
+		return prependHistory(value, entry)
+	})
+
+	if err != nil {
+		return fmt.Errorf("could not set history: %w", err)
+	}
+
+	return nil
+}
+
+// prependHistory adds the entry in front of the stored history and drops the entries above historyLimit
+func prependHistory(value string, entry HistoryEntry) (string, error) {
+	entries, err := parseHistory(value)
+
+	if err != nil {
+		return "", err
+	}
+
+	entries = append([]HistoryEntry{entry}, entries...)
+
+	if len(entries) > historyLimit {
+		entries = entries[:historyLimit]
+	}
+
+	encoded, err := json.Marshal(entries)
+
+	return string(encoded), err
+}
+
+// RecordHistory stores the entry in the history of the project. Failing to do so should never fail the action itself, so errors are only logged
+func RecordHistory(ctx context.Context, client *client.Client, name string, entry *HistoryEntry) {
+	kv, err := CreateKVConnection(ctx, client)
+
+	if err != nil {
+		log.Warnf("Could not record history: %s", err)
+		return
+	}
+
+	defer kv.Close()
+
+	if err := AppendHistory(kv, name, *entry); err != nil {
+		log.Warnf("Could not record history: %s", err)
+	}
+}
+
+func currentGitCommit() string {
+	output, err := exec.Command("git", "rev-parse", "HEAD").Output()
+
+	if err != nil {
+		return ""
+	}
+
+	return strings.TrimSpace(string(output))
+}
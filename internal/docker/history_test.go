@@ -0,0 +1,80 @@
+package docker
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHistoryEntrySerialization(t *testing.T) {
+	entry := HistoryEntry{
+		Action:    HistoryActionDeploy,
+		Version:   "v1",
+		User:      "shyim",
+		Host:      "laptop",
+		StartedAt: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		Duration:  1500 * time.Millisecond,
+		Success:   true,
+	}
+
+	value, err := prependHistory("", entry)
+
+	assert.NoError(t, err)
+	assert.JSONEq(t, `[{"action":"deploy","version":"v1","user":"shyim","host":"laptop","started_at":"2024-01-02T03:04:05Z","duration":1500000000,"success":true}]`, value)
+
+	entries, err := parseHistory(value)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []HistoryEntry{entry}, entries)
+}
+
+func TestPrependHistory(t *testing.T) {
+	value := ""
+
+	for i := 0; i < historyLimit+5; i++ {
+		var err error
+
+		value, err = prependHistory(value, HistoryEntry{Action: HistoryActionDeploy, Version: fmt.Sprintf("v%d", i)})
+
+		assert.NoError(t, err)
+	}
+
+	entries, err := parseHistory(value)
+
+	assert.NoError(t, err)
+	assert.Len(t, entries, historyLimit)
+	assert.Equal(t, fmt.Sprintf("v%d", historyLimit+4), entries[0].Version)
+	assert.Equal(t, "v5", entries[historyLimit-1].Version)
+
+	_, err = prependHistory("not json", HistoryEntry{Action: HistoryActionDeploy})
+
+	assert.ErrorContains(t, err, "could not parse history")
+}
+
+func TestParseHistoryEmpty(t *testing.T) {
+	entries, err := parseHistory("")
+
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestHistoryEntryFinish(t *testing.T) {
+	entry := NewHistoryEntry(HistoryActionScale)
+
+	entry.Finish(nil)
+
+	assert.True(t, entry.Success)
+	assert.Empty(t, entry.Error)
+
+	entry.Finish(errors.New("connection lost"))
+
+	assert.False(t, entry.Success)
+	assert.Equal(t, "connection lost", entry.Error)
+}
+
+func TestHistoryKey(t *testing.T) {
+	assert.Equal(t, "tanjun_my-app_history", historyKey("My App"))
+}
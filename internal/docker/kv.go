@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 
@@ -15,103 +16,152 @@ import (
 	kvstore "github.com/shyim/tanjun/kv-store"
 )
 
+// ErrKVConflict is returned by CompareAndSwap when the key was changed in the meantime
+var ErrKVConflict = errors.New("the key was changed concurrently")
+
+// kvUpdateAttempts limits how often Update retries after a conflict
+const kvUpdateAttempts = 5
+
 type KvClient struct {
-	resp types.HijackedResponse
-	pr   *io.PipeReader
+	resp        types.HijackedResponse
+	pr          *io.PipeReader
+	containerID string
+	// keys are the master keys encrypting the project secrets, nil when the server was set up before encryption was introduced
+	keys *masterKeys
 }
 
-func (c KvClient) Get(key string) (string, error) {
-	payload := kvstore.KVInput{Operation: "get", Key: key}
-
+func (c KvClient) send(payload kvstore.KVInput) (*kvstore.KVResponse, error) {
 	encoded, err := json.Marshal(payload)
 
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	encoded = append(encoded, []byte("\n")...)
 
 	if _, err := c.resp.Conn.Write(encoded); err != nil {
-		return "", err
+		return nil, err
 	}
 
 	scanner := bufio.NewScanner(c.pr)
-
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
 	scanner.Scan()
 
 	var res kvstore.KVResponse
 
 	if err := json.Unmarshal(scanner.Bytes(), &res); err != nil {
-		return "", err
+		return nil, err
 	}
 
 	if res.ErrorMessage != "" {
-		return "", fmt.Errorf("%s", res.ErrorMessage)
+		return nil, fmt.Errorf("%s", res.ErrorMessage)
 	}
 
-	return res.Value, nil
+	return &res, nil
 }
 
-func (c KvClient) Delete(key string) error {
-	payload := kvstore.KVInput{Operation: "del", Key: key}
-
-	encoded, err := json.Marshal(payload)
+func (c KvClient) Get(key string) (string, error) {
+	res, err := c.send(kvstore.KVInput{Operation: "get", Key: key})
 
 	if err != nil {
-		return err
+		return "", err
 	}
 
-	encoded = append(encoded, []byte("\n")...)
+	return res.Value, nil
+}
 
-	if _, err := c.resp.Conn.Write(encoded); err != nil {
-		return err
+// GetRevision returns the value and its revision, the revision is 0 when the key does not exist
+func (c KvClient) GetRevision(key string) (string, int64, error) {
+	res, err := c.send(kvstore.KVInput{Operation: "get", Key: key})
+
+	if err != nil {
+		return "", 0, err
 	}
 
-	scanner := bufio.NewScanner(c.pr)
-	scanner.Scan()
+	return res.Value, res.Revision, nil
+}
 
-	var res kvstore.KVResponse
+// MGet returns the values of all existing keys
+func (c KvClient) MGet(keys []string) (map[string]string, error) {
+	res, err := c.send(kvstore.KVInput{Operation: "mget", Keys: keys})
 
-	if err := json.Unmarshal(scanner.Bytes(), &res); err != nil {
-		return err
+	if err != nil {
+		return nil, err
 	}
 
-	if res.ErrorMessage != "" {
-		return fmt.Errorf("%s", res.ErrorMessage)
+	if res.Values == nil {
+		return map[string]string{}, nil
 	}
 
-	return nil
+	return res.Values, nil
 }
 
-func (c KvClient) Set(key string, value string) error {
-	payload := kvstore.KVInput{Operation: "set", Key: key, Value: value}
+// MSet stores all values in one transaction
+func (c KvClient) MSet(values map[string]string) error {
+	_, err := c.send(kvstore.KVInput{Operation: "mset", Values: values})
 
-	encoded, err := json.Marshal(payload)
+	return err
+}
+
+// CompareAndSwap sets the value only when the key still has the revision, use revision 0 for a key which must not exist yet
+func (c KvClient) CompareAndSwap(key, value string, revision int64) error {
+	res, err := c.send(kvstore.KVInput{Operation: "cas", Key: key, Value: value, Revision: revision})
 
 	if err != nil {
 		return err
 	}
 
-	encoded = append(encoded, []byte("\n")...)
-
-	if _, err := c.resp.Conn.Write(encoded); err != nil {
-		return err
+	if res.Type == "conflict" {
+		return ErrKVConflict
 	}
 
-	scanner := bufio.NewScanner(c.pr)
-	scanner.Scan()
+	return nil
+}
 
-	var res kvstore.KVResponse
+// Update replaces the value with the result of fn, fn is called again when another client changed the key in the meantime
+func (c KvClient) Update(key string, fn func(value string) (string, error)) error {
+	for attempt := 0; attempt < kvUpdateAttempts; attempt++ {
+		value, revision, err := c.GetRevision(key)
 
-	if err := json.Unmarshal(scanner.Bytes(), &res); err != nil {
-		return err
+		if err != nil {
+			return err
+		}
+
+		updated, err := fn(value)
+
+		if err != nil {
+			return err
+		}
+
+		if err := c.CompareAndSwap(key, updated, revision); !errors.Is(err, ErrKVConflict) {
+			return err
+		}
 	}
 
-	if res.ErrorMessage != "" {
-		return fmt.Errorf("%s", res.ErrorMessage)
+	return fmt.Errorf("could not update %s: %w", key, ErrKVConflict)
+}
+
+func (c KvClient) Delete(key string) error {
+	_, err := c.send(kvstore.KVInput{Operation: "del", Key: key})
+
+	return err
+}
+
+func (c KvClient) Set(key string, value string) error {
+	_, err := c.send(kvstore.KVInput{Operation: "set", Key: key, Value: value})
+
+	return err
+}
+
+// List returns all keys starting with the prefix
+func (c KvClient) List(prefix string) ([]string, error) {
+	res, err := c.send(kvstore.KVInput{Operation: "list", Key: prefix})
+
+	if err != nil {
+		return nil, err
 	}
 
-	return nil
+	return res.Keys, nil
 }
 
 func (c KvClient) Close() {
@@ -138,6 +188,11 @@ func CreateKVConnection(ctx context.Context, client *client.Client) (*KvClient,
 		return nil, fmt.Errorf("expected 1 kv container, got %d, did you forgot to run tanjun setup", len(containers))
 	}
 
+	// Older kv stores do not know all operations used by this version, like cas, only tanjun setup replaces the container
+	if containers[0].Image != tanjunKVImage {
+		return nil, fmt.Errorf("the kv store of the server runs %s instead of %s, run tanjun setup to upgrade the kv store", containers[0].Image, tanjunKVImage)
+	}
+
 	execId, err := client.ContainerExecCreate(ctx, containers[0].ID, container.ExecOptions{
 		Tty:          false,
 		AttachStdin:  true,
@@ -162,8 +217,23 @@ func CreateKVConnection(ctx context.Context, client *client.Client) (*KvClient,
 		_, _ = stdcopy.StdCopy(pw, io.Discard, resp.Reader)
 	}()
 
-	return &KvClient{
-		resp: resp,
-		pr:   pr,
-	}, nil
+	kv := &KvClient{
+		resp:        resp,
+		pr:          pr,
+		containerID: containers[0].ID,
+	}
+
+	if hasMasterKeyMount(containers[0]) {
+		keys, err := readMasterKeys(ctx, client, containers[0].ID)
+
+		if err != nil {
+			kv.Close()
+
+			return nil, fmt.Errorf("could not read master key: %w", err)
+		}
+
+		kv.keys = keys
+	}
+
+	return kv, nil
 }
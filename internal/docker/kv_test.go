@@ -0,0 +1,113 @@
+package docker
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	kvstore "github.com/shyim/tanjun/kv-store"
+)
+
+type memoryKVEntry struct {
+	value    string
+	revision int64
+}
+
+// newTestKvClient returns a client talking to an in-memory kv store implementing the protocol of kv-store
+func newTestKvClient(t *testing.T) *KvClient {
+	clientConn, serverConn := net.Pipe()
+	pr, pw := io.Pipe()
+
+	var mu sync.Mutex
+	entries := map[string]memoryKVEntry{}
+
+	go func() {
+		scanner := bufio.NewScanner(serverConn)
+		scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+		for scanner.Scan() {
+			var input kvstore.KVInput
+
+			if err := json.Unmarshal(scanner.Bytes(), &input); err != nil {
+				return
+			}
+
+			mu.Lock()
+			response := handleMemoryKV(entries, input)
+			mu.Unlock()
+
+			encoded, _ := json.Marshal(response)
+
+			if _, err := pw.Write(append(encoded, '\n')); err != nil {
+				return
+			}
+		}
+	}()
+
+	kv := &KvClient{resp: types.HijackedResponse{Conn: clientConn}, pr: pr}
+
+	t.Cleanup(kv.Close)
+
+	return kv
+}
+
+func handleMemoryKV(entries map[string]memoryKVEntry, input kvstore.KVInput) kvstore.KVResponse {
+	set := func(key, value string) int64 {
+		entry := memoryKVEntry{value: value, revision: entries[key].revision + 1}
+		entries[key] = entry
+
+		return entry.revision
+	}
+
+	switch input.Operation {
+	case "get":
+		entry := entries[input.Key]
+
+		return kvstore.KVResponse{Type: "success", Value: entry.value, Revision: entry.revision}
+	case "set":
+		return kvstore.KVResponse{Type: "success", Revision: set(input.Key, input.Value)}
+	case "del":
+		delete(entries, input.Key)
+
+		return kvstore.KVResponse{Type: "success"}
+	case "list":
+		var keys []string
+
+		for key := range entries {
+			if strings.HasPrefix(key, input.Key) {
+				keys = append(keys, key)
+			}
+		}
+
+		return kvstore.KVResponse{Type: "success", Keys: keys}
+	case "mget":
+		values := map[string]string{}
+
+		for _, key := range input.Keys {
+			if entry, ok := entries[key]; ok {
+				values[key] = entry.value
+			}
+		}
+
+		return kvstore.KVResponse{Type: "success", Values: values}
+	case "mset":
+		for key, value := range input.Values {
+			set(key, value)
+		}
+
+		return kvstore.KVResponse{Type: "success"}
+	case "cas":
+		if entries[input.Key].revision != input.Revision {
+			return kvstore.KVResponse{Type: "conflict", Revision: entries[input.Key].revision}
+		}
+
+		return kvstore.KVResponse{Type: "success", Revision: set(input.Key, input.Value)}
+	}
+
+	return kvstore.KVResponse{Type: "error", ErrorMessage: "unknown operation " + input.Operation}
+}
@@ -0,0 +1,273 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/user"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/docker/docker/client"
+	"github.com/gosimple/slug"
+)
+
+// DeployLockTTL is the time after a lock is considered stale, e.g. when the deploying machine lost the connection.
+// It counts from the last refresh, a running deployment refreshes its lock every deployLockRefreshInterval
+const DeployLockTTL = 30 * time.Minute
+
+const deployLockRefreshInterval = 5 * time.Minute
+
+// errDeployLockLost is returned by refreshDeployLock when the lock was released or taken over by someone else
+var errDeployLockLost = errors.New("the deploy lock was released or taken over")
+
+type DeployLock struct {
+	User        string    `json:"user"`
+	Host        string    `json:"host"`
+	CreatedAt   time.Time `json:"created_at"`
+	RefreshedAt time.Time `json:"refreshed_at,omitempty"`
+}
+
+func (l DeployLock) Expired() bool {
+	lastSeen := l.CreatedAt
+
+	if l.RefreshedAt.After(lastSeen) {
+		lastSeen = l.RefreshedAt
+	}
+
+	return time.Since(lastSeen) > DeployLockTTL
+}
+
+func (l DeployLock) sameAs(other DeployLock) bool {
+	return l.User == other.User && l.Host == other.Host && l.CreatedAt.Equal(other.CreatedAt)
+}
+
+func (l DeployLock) String() string {
+	if !l.RefreshedAt.IsZero() {
+		return fmt.Sprintf("%s on %s since %s, refreshed at %s", l.User, l.Host, l.CreatedAt.Format(time.RFC3339), l.RefreshedAt.Format(time.RFC3339))
+	}
+
+	return fmt.Sprintf("%s on %s since %s", l.User, l.Host, l.CreatedAt.Format(time.RFC3339))
+}
+
+func deployLockKey(name string) string {
+	cfg := DeployConfiguration{Name: slug.Make(name)}
+
+	return cfg.ContainerPrefix() + "_lock"
+}
+
+// GetDeployLock returns the current lock of the project or nil when the project is not locked
+func GetDeployLock(kv *KvClient, name string) (*DeployLock, error) {
+	value, err := kv.Get(deployLockKey(name))
+
+	if err != nil {
+		return nil, err
+	}
+
+	return parseDeployLock(value)
+}
+
+func parseDeployLock(value string) (*DeployLock, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	var lock DeployLock
+
+	if err := json.Unmarshal([]byte(value), &lock); err != nil {
+		return nil, fmt.Errorf("could not parse deploy lock: %w", err)
+	}
+
+	return &lock, nil
+}
+
+// AcquireDeployLock locks the project for the current user. Stale locks are taken over.
+// The lock is set with compare and swap, so only one of two concurrent deployments gets it
+func AcquireDeployLock(kv *KvClient, name string) (*DeployLock, error) {
+	value, revision, err := kv.GetRevision(deployLockKey(name))
+
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := parseDeployLock(value)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if existing != nil && !existing.Expired() {
+		return nil, deployLockedError(name, existing)
+	}
+
+	lock := DeployLock{
+		User:      currentUsername(),
+		Host:      currentHostname(),
+		CreatedAt: time.Now().UTC(),
+	}
+
+	encoded, err := json.Marshal(lock)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// revision is 0 without a lock, otherwise the expired lock is only replaced when nobody took it over in the meantime
+	if err := kv.CompareAndSwap(deployLockKey(name), string(encoded), revision); err != nil {
+		if errors.Is(err, ErrKVConflict) {
+			current, getErr := GetDeployLock(kv, name)
+
+			if getErr != nil || current == nil {
+				return nil, fmt.Errorf("project %s was locked by another deployment in the meantime", name)
+			}
+
+			return nil, deployLockedError(name, current)
+		}
+
+		return nil, fmt.Errorf("could not set deploy lock: %w", err)
+	}
+
+	return &lock, nil
+}
+
+func deployLockedError(name string, lock *DeployLock) error {
+	return fmt.Errorf("project %s is locked by %s, run `tanjun lock release` when this deployment is not running anymore", name, lock)
+}
+
+// refreshDeployLock extends the owned lock, it fails with errDeployLockLost when the lock does not belong to us anymore
+func refreshDeployLock(kv *KvClient, name string, owned *DeployLock) error {
+	value, revision, err := kv.GetRevision(deployLockKey(name))
+
+	if err != nil {
+		return err
+	}
+
+	existing, err := parseDeployLock(value)
+
+	if err != nil {
+		return err
+	}
+
+	if existing == nil || !existing.sameAs(*owned) {
+		return errDeployLockLost
+	}
+
+	existing.RefreshedAt = time.Now().UTC()
+
+	encoded, err := json.Marshal(existing)
+
+	if err != nil {
+		return err
+	}
+
+	if err := kv.CompareAndSwap(deployLockKey(name), string(encoded), revision); err != nil {
+		if errors.Is(err, ErrKVConflict) {
+			return errDeployLockLost
+		}
+
+		return err
+	}
+
+	owned.RefreshedAt = existing.RefreshedAt
+
+	return nil
+}
+
+// lockProject acquires the deploy lock and refreshes it in the background, so long running hooks do not let the lock expire.
+// The returned function stops the refresh and releases the lock
+func lockProject(ctx context.Context, client *client.Client, kv *KvClient, name string) (func(), error) {
+	lock, err := AcquireDeployLock(kv, name)
+
+	if err != nil {
+		return nil, err
+	}
+
+	heartbeatCtx, stop := context.WithCancel(ctx)
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+		keepDeployLock(heartbeatCtx, client, name, lock)
+	}()
+
+	return func() {
+		stop()
+		<-stopped
+
+		if err := ReleaseDeployLock(kv, name, lock); err != nil {
+			log.Warnf("Failed to release deploy lock: %s", err)
+		}
+	}, nil
+}
+
+func keepDeployLock(ctx context.Context, client *client.Client, name string, lock *DeployLock) {
+	ticker := time.NewTicker(deployLockRefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		// The connection of the deployment is busy, one kv connection handles only one request at a time
+		kv, err := CreateKVConnection(ctx, client)
+
+		if err != nil {
+			log.Warnf("Could not refresh deploy lock: %s", err)
+			continue
+		}
+
+		err = refreshDeployLock(kv, name, lock)
+		kv.Close()
+
+		if errors.Is(err, errDeployLockLost) {
+			log.Warnf("The deploy lock of %s was released or taken over by someone else, check tanjun lock status", name)
+			return
+		}
+
+		if err != nil {
+			log.Warnf("Could not refresh deploy lock: %s", err)
+		}
+	}
+}
+
+// ReleaseDeployLock removes the lock of the project. When owned is given, the lock is only removed when it is still the same
+func ReleaseDeployLock(kv *KvClient, name string, owned *DeployLock) error {
+	if owned != nil {
+		existing, err := GetDeployLock(kv, name)
+
+		if err != nil {
+			return err
+		}
+
+		if existing == nil || !existing.sameAs(*owned) {
+			return nil
+		}
+	}
+
+	return kv.Delete(deployLockKey(name))
+}
+
+func currentUsername() string {
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username
+	}
+
+	if username := os.Getenv("USER"); username != "" {
+		return username
+	}
+
+	return "unknown"
+}
+
+func currentHostname() string {
+	if hostname, err := os.Hostname(); err == nil {
+		return hostname
+	}
+
+	return "unknown"
+}
@@ -0,0 +1,93 @@
+package docker
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAcquireDeployLock(t *testing.T) {
+	kv := newTestKvClient(t)
+
+	lock, err := AcquireDeployLock(kv, "app")
+
+	assert.NoError(t, err)
+	assert.NotNil(t, lock)
+
+	_, err = AcquireDeployLock(kv, "app")
+
+	assert.ErrorContains(t, err, "project app is locked by")
+
+	// other projects have their own lock
+	_, err = AcquireDeployLock(kv, "other")
+
+	assert.NoError(t, err)
+}
+
+func TestAcquireExpiredDeployLock(t *testing.T) {
+	kv := newTestKvClient(t)
+
+	stale, _ := json.Marshal(DeployLock{User: "ci", Host: "runner", CreatedAt: time.Now().Add(-DeployLockTTL - time.Minute)})
+
+	assert.NoError(t, kv.Set(deployLockKey("app"), string(stale)))
+
+	lock, err := AcquireDeployLock(kv, "app")
+
+	assert.NoError(t, err)
+
+	current, err := GetDeployLock(kv, "app")
+
+	assert.NoError(t, err)
+	assert.True(t, current.sameAs(*lock))
+}
+
+func TestRefreshDeployLock(t *testing.T) {
+	kv := newTestKvClient(t)
+
+	old, _ := json.Marshal(DeployLock{User: "ci", Host: "runner", CreatedAt: time.Now().Add(-DeployLockTTL - time.Minute)})
+
+	assert.NoError(t, kv.Set(deployLockKey("app"), string(old)))
+
+	var lock DeployLock
+
+	assert.NoError(t, json.Unmarshal(old, &lock))
+	assert.True(t, lock.Expired())
+
+	// a refreshed lock is not expired, even when it was created before the TTL
+	assert.NoError(t, refreshDeployLock(kv, "app", &lock))
+	assert.False(t, lock.Expired())
+
+	_, err := AcquireDeployLock(kv, "app")
+
+	assert.ErrorContains(t, err, "is locked by")
+
+	assert.NoError(t, ReleaseDeployLock(kv, "app", &lock))
+	assert.ErrorIs(t, refreshDeployLock(kv, "app", &lock), errDeployLockLost)
+}
+
+func TestReleaseDeployLock(t *testing.T) {
+	kv := newTestKvClient(t)
+
+	lock, err := AcquireDeployLock(kv, "app")
+
+	assert.NoError(t, err)
+
+	other := DeployLock{User: "someone", Host: "else", CreatedAt: time.Now()}
+
+	// the lock was taken over by someone else, so it is kept
+	assert.NoError(t, ReleaseDeployLock(kv, "app", &other))
+
+	current, err := GetDeployLock(kv, "app")
+
+	assert.NoError(t, err)
+	assert.NotNil(t, current)
+
+	assert.NoError(t, ReleaseDeployLock(kv, "app", lock))
+
+	current, err = GetDeployLock(kv, "app")
+
+	assert.NoError(t, err)
+	assert.Nil(t, current)
+}
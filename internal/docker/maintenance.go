@@ -0,0 +1,406 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/client"
+	"github.com/gosimple/slug"
+	"github.com/pterm/pterm"
+	"github.com/shyim/tanjun/internal/config"
+)
+
+const maintenanceImage = "ghcr.io/shyim/tanjun/maintenance:v1"
+
+// MaintenanceState is stored in the kv store while the maintenance page is served
+type MaintenanceState struct {
+	Message       string    `json:"message,omitempty"`
+	AllowIPs      []string  `json:"allow_ips,omitempty"`
+	PausedWorkers bool      `json:"paused_workers"`
+	User          string    `json:"user"`
+	StartedAt     time.Time `json:"started_at"`
+}
+
+func newMaintenanceState(maintenanceConfig config.ProjectMaintenance) MaintenanceState {
+	return MaintenanceState{
+		Message:   maintenanceConfig.Message,
+		AllowIPs:  maintenanceConfig.AllowIPs,
+		User:      currentUsername(),
+		StartedAt: time.Now().UTC(),
+	}
+}
+
+func maintenanceKey(name string) string {
+	cfg := DeployConfiguration{Name: slug.Make(name)}
+
+	return cfg.ContainerPrefix() + "_maintenance"
+}
+
+// GetMaintenanceState returns the maintenance state of the project or nil when the app is not in maintenance
+func GetMaintenanceState(kv *KvClient, name string) (*MaintenanceState, error) {
+	value, err := kv.Get(maintenanceKey(name))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if value == "" {
+		return nil, nil
+	}
+
+	var state MaintenanceState
+
+	if err := json.Unmarshal([]byte(value), &state); err != nil {
+		return nil, fmt.Errorf("could not parse maintenance state: %w", err)
+	}
+
+	return &state, nil
+}
+
+func setMaintenanceState(kv *KvClient, name string, state MaintenanceState) error {
+	encoded, err := json.Marshal(state)
+
+	if err != nil {
+		return err
+	}
+
+	if err := kv.Set(maintenanceKey(name), string(encoded)); err != nil {
+		return fmt.Errorf("could not set maintenance state: %w", err)
+	}
+
+	return nil
+}
+
+func ensureNoMaintenance(deployCfg DeployConfiguration) error {
+	state, err := GetMaintenanceState(deployCfg.storage, deployCfg.Name)
+
+	if err != nil {
+		return err
+	}
+
+	if state != nil {
+		return fmt.Errorf("project %s is in maintenance, run `tanjun maintenance off` first", deployCfg.Name)
+	}
+
+	return nil
+}
+
+// EnableMaintenance serves the maintenance page instead of the app, only the allowed IPs can still reach the app
+func EnableMaintenance(ctx context.Context, client *client.Client, projectConfig *config.ProjectConfig, message string, allowIPs []string, pauseWorkers bool) error {
+	return withMaintenanceDeployments(ctx, client, projectConfig, func(deployCfg DeployConfiguration, deployments []*serverDeployment, state *MaintenanceState) error {
+		newState := newMaintenanceState(projectConfig.Proxy.Maintenance)
+		newState.PausedWorkers = pauseWorkers
+
+		if message != "" {
+			newState.Message = message
+		}
+
+		if len(allowIPs) > 0 {
+			newState.AllowIPs = allowIPs
+		}
+
+		if state != nil {
+			newState.PausedWorkers = newState.PausedWorkers || state.PausedWorkers
+		}
+
+		if err := rolloutServerDeployments(deployCfg, deployments, func(d *serverDeployment) error {
+			appContainers, err := getEnvironmentContainers(ctx, d.client, d.deployCfg.Name)
+
+			if err != nil {
+				return err
+			}
+
+			if err := d.enableMaintenance(ctx, newState, containerIDs(appContainers)); err != nil {
+				return err
+			}
+
+			if newState.PausedWorkers {
+				return d.pauseSideContainers(ctx)
+			}
+
+			return nil
+		}); err != nil {
+			return err
+		}
+
+		return setMaintenanceState(deployCfg.storage, deployCfg.Name, newState)
+	})
+}
+
+// DisableMaintenance routes the traffic to the app again and resumes paused workers and cronjobs
+func DisableMaintenance(ctx context.Context, client *client.Client, projectConfig *config.ProjectConfig) error {
+	return withMaintenanceDeployments(ctx, client, projectConfig, func(deployCfg DeployConfiguration, deployments []*serverDeployment, state *MaintenanceState) error {
+		if state == nil {
+			return fmt.Errorf("project %s is not in maintenance", deployCfg.Name)
+		}
+
+		if err := rolloutServerDeployments(deployCfg, deployments, func(d *serverDeployment) error {
+			appContainers, err := getEnvironmentContainers(ctx, d.client, d.deployCfg.Name)
+
+			if err != nil {
+				return err
+			}
+
+			if err := d.disableMaintenance(ctx, containerIDs(appContainers)); err != nil {
+				return err
+			}
+
+			if state.PausedWorkers {
+				return d.resumeSideContainers(ctx)
+			}
+
+			return nil
+		}); err != nil {
+			return err
+		}
+
+		return deployCfg.storage.Delete(maintenanceKey(deployCfg.Name))
+	})
+}
+
+func withMaintenanceDeployments(ctx context.Context, client *client.Client, projectConfig *config.ProjectConfig, fn func(deployCfg DeployConfiguration, deployments []*serverDeployment, state *MaintenanceState) error) error {
+	deployCfg := newDeployConfiguration(projectConfig, "")
+
+	var err error
+
+	deployCfg.storage, err = CreateKVConnection(ctx, client)
+
+	if err != nil {
+		return err
+	}
+
+	defer deployCfg.storage.Close()
+
+	unlock, err := lockProject(ctx, client, deployCfg.storage, deployCfg.Name)
+
+	if err != nil {
+		return err
+	}
+
+	defer unlock()
+
+	if err := ensureNoCanary(deployCfg); err != nil {
+		return err
+	}
+
+	state, err := GetMaintenanceState(deployCfg.storage, deployCfg.Name)
+
+	if err != nil {
+		return err
+	}
+
+	deployments, err := createServerDeployments(ctx, client, deployCfg)
+
+	if err != nil {
+		return err
+	}
+
+	defer closeServerDeployments(deployments)
+
+	return fn(deployCfg, deployments, state)
+}
+
+func getMaintenanceContainers(ctx context.Context, client *client.Client, projectName string) ([]container.Summary, error) {
+	options := container.ListOptions{
+		Filters: filters.NewArgs(),
+		All:     true,
+	}
+
+	options.Filters.Add("label", fmt.Sprintf("tanjun.project=%s", projectName))
+	options.Filters.Add("label", "tanjun.maintenance=true")
+
+	return client.ContainerList(ctx, options)
+}
+
+// enableMaintenance starts a maintenance container in front of the given app containers and routes the traffic to it
+func (d *serverDeployment) enableMaintenance(ctx context.Context, state MaintenanceState, appContainerIDs []string) error {
+	if !d.server.HasRole(config.ServerRoleWeb) {
+		return nil
+	}
+
+	spinnerInfo, err := pterm.DefaultSpinner.Start("Enabling maintenance page" + d.describe())
+
+	if err != nil {
+		return err
+	}
+
+	if err := d.startMaintenanceContainer(ctx, state, appContainerIDs); err != nil {
+		spinnerInfo.Fail(err)
+		return err
+	}
+
+	spinnerInfo.Success("Maintenance page is served" + d.describe())
+
+	return nil
+}
+
+func (d *serverDeployment) startMaintenanceContainer(ctx context.Context, state MaintenanceState, appContainerIDs []string) error {
+	if err := PullImageIfNotThere(ctx, d.client, maintenanceImage); err != nil {
+		return err
+	}
+
+	previousContainers, err := getMaintenanceContainers(ctx, d.client, d.deployCfg.Name)
+
+	if err != nil {
+		return err
+	}
+
+	upstreams, err := getContainerProxyTargets(ctx, d.client, d.deployCfg, appContainerIDs)
+
+	if err != nil {
+		return err
+	}
+
+	containerCfg := &container.Config{
+		Image: maintenanceImage,
+		Env: []string{
+			"MAINTENANCE_MESSAGE=" + state.Message,
+			"MAINTENANCE_ALLOW_IPS=" + strings.Join(state.AllowIPs, ","),
+			"MAINTENANCE_UPSTREAMS=" + strings.Join(upstreams, ","),
+			"MAINTENANCE_HEALTH_PATH=" + d.deployCfg.ProjectConfig.Proxy.HealthCheck.Path,
+		},
+		Labels: map[string]string{
+			"com.docker.compose.project": d.deployCfg.ContainerPrefix(),
+			"com.docker.compose.service": "maintenance",
+			"tanjun":                     "true",
+			"tanjun.project":             d.deployCfg.Name,
+			"tanjun.maintenance":         "true",
+		},
+	}
+
+	d.deployCfg.addEnvironmentLabel(containerCfg.Labels)
+
+	hostCfg := &container.HostConfig{
+		RestartPolicy: container.RestartPolicy{
+			Name: container.RestartPolicyUnlessStopped,
+		},
+	}
+
+	networkCfg := &network.NetworkingConfig{
+		EndpointsConfig: map[string]*network.EndpointSettings{
+			kamalNetworkName: {},
+		},
+	}
+
+	c, err := d.client.ContainerCreate(ctx, containerCfg, hostCfg, networkCfg, nil, fmt.Sprintf("%s_maintenance_%d", d.deployCfg.ContainerPrefix(), rand.IntN(1000000)))
+
+	if err != nil {
+		return err
+	}
+
+	if err := d.client.ContainerStart(ctx, c.ID, container.StartOptions{}); err != nil {
+		return err
+	}
+
+	inspect, err := d.client.ContainerInspect(ctx, c.ID)
+
+	if err != nil {
+		return err
+	}
+
+	publicNetwork, ok := inspect.NetworkSettings.Networks[kamalNetworkName]
+
+	if !ok {
+		return fmt.Errorf("maintenance container is not connected to the %s network", kamalNetworkName)
+	}
+
+	if err := configureKamalService(ctx, d.client, getKamalDeployCommand(d.deployCfg, publicNetwork.IPAddress+":80")); err != nil {
+		return err
+	}
+
+	d.maintenance = true
+
+	return removeContainers(ctx, d.client, previousContainers)
+}
+
+// disableMaintenance routes the traffic to the given app containers and removes the maintenance container
+func (d *serverDeployment) disableMaintenance(ctx context.Context, appContainerIDs []string) error {
+	if !d.server.HasRole(config.ServerRoleWeb) {
+		return nil
+	}
+
+	spinnerInfo, err := pterm.DefaultSpinner.Start("Disabling maintenance page" + d.describe())
+
+	if err != nil {
+		return err
+	}
+
+	if len(appContainerIDs) > 0 {
+		targets, err := getContainerProxyTargets(ctx, d.client, d.deployCfg, appContainerIDs)
+
+		if err != nil {
+			spinnerInfo.Fail(err)
+			return err
+		}
+
+		if err := configureKamalService(ctx, d.client, getKamalDeployCommand(d.deployCfg, targets...)); err != nil {
+			spinnerInfo.Fail(err)
+			return err
+		}
+	}
+
+	if err := d.removeMaintenanceContainers(ctx); err != nil {
+		spinnerInfo.Fail(err)
+		return err
+	}
+
+	spinnerInfo.Success("Traffic is routed to the app again" + d.describe())
+
+	return nil
+}
+
+func (d *serverDeployment) removeMaintenanceContainers(ctx context.Context) error {
+	maintenanceContainers, err := getMaintenanceContainers(ctx, d.client, d.deployCfg.Name)
+
+	if err != nil {
+		return err
+	}
+
+	d.maintenance = false
+
+	return removeContainers(ctx, d.client, maintenanceContainers)
+}
+
+func (d *serverDeployment) pauseSideContainers(ctx context.Context) error {
+	containers, err := d.listSideContainers(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	return stopContainers(ctx, d.client, containers)
+}
+
+func (d *serverDeployment) resumeSideContainers(ctx context.Context) error {
+	containers, err := d.listSideContainers(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	return startContainers(ctx, d.client, containers)
+}
+
+func (d *serverDeployment) listSideContainers(ctx context.Context) ([]container.Summary, error) {
+	workers, err := getWorkerEnvironmentContainers(ctx, d.client, d.deployCfg.Name)
+
+	if err != nil {
+		return nil, err
+	}
+
+	cronjobs, err := getCronjobEnvironmentContainers(ctx, d.client, d.deployCfg.Name)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return slices.Concat(workers, cronjobs), nil
+}
@@ -0,0 +1,334 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/api/types/volume"
+	"github.com/docker/docker/client"
+	"github.com/gosimple/slug"
+	"github.com/pterm/pterm"
+	"github.com/shyim/tanjun/internal/config"
+)
+
+// PreviewState is tracked per preview in the kv store of the project the preview was derived from
+type PreviewState struct {
+	Name       string    `json:"name"`
+	Host       string    `json:"host"`
+	Version    string    `json:"version"`
+	User       string    `json:"user"`
+	CreatedAt  time.Time `json:"created_at"`
+	DeployedAt time.Time `json:"deployed_at"`
+}
+
+// ExpiresAt is the time after which the preview can be removed with tanjun preview list --prune
+func (s PreviewState) ExpiresAt(expireAfter int) time.Time {
+	return s.DeployedAt.Add(time.Duration(expireAfter) * time.Second)
+}
+
+func previewsKey(name string) string {
+	cfg := DeployConfiguration{Name: slug.Make(name)}
+
+	return cfg.ContainerPrefix() + "_previews"
+}
+
+// ListPreviews returns the previews of the project sorted by name
+func ListPreviews(kv *KvClient, name string) ([]PreviewState, error) {
+	previews, err := getPreviews(kv, name)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return slices.SortedFunc(maps.Values(previews), func(a, b PreviewState) int {
+		return strings.Compare(a.Name, b.Name)
+	}), nil
+}
+
+func getPreviews(kv *KvClient, name string) (map[string]PreviewState, error) {
+	value, err := kv.Get(previewsKey(name))
+
+	if err != nil {
+		return nil, err
+	}
+
+	return parsePreviews(value)
+}
+
+func parsePreviews(value string) (map[string]PreviewState, error) {
+	previews := make(map[string]PreviewState)
+
+	if value == "" {
+		return previews, nil
+	}
+
+	if err := json.Unmarshal([]byte(value), &previews); err != nil {
+		return nil, fmt.Errorf("could not parse previews: %w", err)
+	}
+
+	return previews, nil
+}
+
+// updatePreviews changes the tracked previews of the project, fn is called again when another preview was deployed or
+// destroyed in the meantime
+func updatePreviews(kv *KvClient, name string, fn func(previews map[string]PreviewState)) error {
+	err := kv.Update(previewsKey(name), func(value string) (string, error) {
+		previews, err := parsePreviews(value)
+
+		if err != nil {
+			return "", err
+		}
+
+		fn(previews)
+
+		if len(previews) == 0 {
+			return "", nil
+		}
+
+		encoded, err := json.Marshal(previews)
+
+		return string(encoded), err
+	})
+
+	if err != nil {
+		return fmt.Errorf("could not set previews: %w", err)
+	}
+
+	return nil
+}
+
+// DeployPreview deploys the version as preview of the project. A new preview gets the stored secrets of the project
+// and the data of the services configured in preview.seed
+func DeployPreview(ctx context.Context, client *client.Client, previewConfig *config.ProjectConfig, version string) error {
+	parent := previewConfig.PreviewParent()
+
+	kv, err := CreateKVConnection(ctx, client)
+
+	if err != nil {
+		return err
+	}
+
+	previews, err := getPreviews(kv, parent)
+
+	kv.Close()
+
+	if err != nil {
+		return err
+	}
+
+	state, exists := previews[previewConfig.PreviewName]
+
+	if !exists {
+		if err := createPreview(ctx, client, previewConfig); err != nil {
+			return err
+		}
+
+		state = PreviewState{
+			Name:      previewConfig.PreviewName,
+			CreatedAt: time.Now().UTC(),
+		}
+	}
+
+	if err := Deploy(ctx, client, previewConfig, version); err != nil {
+		return err
+	}
+
+	state.Host = previewConfig.Proxy.Host
+	state.Version = version
+	state.User = currentUsername()
+	state.DeployedAt = time.Now().UTC()
+
+	kv, err = CreateKVConnection(ctx, client)
+
+	if err != nil {
+		return err
+	}
+
+	defer kv.Close()
+
+	return updatePreviews(kv, parent, func(previews map[string]PreviewState) {
+		previews[state.Name] = state
+	})
+}
+
+// DestroyPreview removes all resources of the preview and stops tracking it
+func DestroyPreview(ctx context.Context, client *client.Client, previewConfig *config.ProjectConfig) error {
+	if err := DestroyProject(ctx, client, previewConfig.Identifier()); err != nil {
+		return err
+	}
+
+	kv, err := CreateKVConnection(ctx, client)
+
+	if err != nil {
+		return err
+	}
+
+	defer kv.Close()
+
+	return updatePreviews(kv, previewConfig.PreviewParent(), func(previews map[string]PreviewState) {
+		delete(previews, previewConfig.PreviewName)
+	})
+}
+
+func createPreview(ctx context.Context, client *client.Client, previewConfig *config.ProjectConfig) error {
+	kv, err := CreateKVConnection(ctx, client)
+
+	if err != nil {
+		return err
+	}
+
+	defer kv.Close()
+
+	secrets, err := ListProjectSecrets(kv, previewConfig.PreviewParent())
+
+	if err != nil {
+		return err
+	}
+
+	if len(secrets) > 0 {
+		if err := SetProjectSecrets(kv, previewConfig.Identifier(), secrets); err != nil {
+			return err
+		}
+	}
+
+	previewCfg := newDeployConfiguration(previewConfig, "")
+
+	for _, serviceName := range previewConfig.Preview.Seed {
+		if err := seedPreviewService(ctx, client, slug.Make(previewConfig.PreviewParent()), previewCfg, serviceName); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// seedPreviewService copies the volumes of the service from the project into the preview.
+// The service container of the project is paused meanwhile, so the copy is a consistent snapshot
+func seedPreviewService(ctx context.Context, client *client.Client, sourceName string, previewCfg DeployConfiguration, serviceName string) error {
+	options := container.ListOptions{Filters: filters.NewArgs()}
+	options.Filters.Add("label", fmt.Sprintf("tanjun.project=%s", sourceName))
+	options.Filters.Add("label", fmt.Sprintf("tanjun.service=%s", serviceName))
+
+	containers, err := client.ContainerList(ctx, options)
+
+	if err != nil {
+		return err
+	}
+
+	if len(containers) == 0 {
+		return fmt.Errorf("cannot seed the preview, service %s of %s is not running", serviceName, sourceName)
+	}
+
+	spinnerInfo, err := pterm.DefaultSpinner.Start(fmt.Sprintf("Seeding service %s from %s", serviceName, sourceName))
+
+	if err != nil {
+		return err
+	}
+
+	if err := PullImageIfNotThere(ctx, client, "alpine:latest"); err != nil {
+		spinnerInfo.Fail(err)
+		return err
+	}
+
+	if err := client.ContainerPause(ctx, containers[0].ID); err != nil {
+		spinnerInfo.Fail(err)
+		return fmt.Errorf("could not pause service %s: %w", serviceName, err)
+	}
+
+	defer func() {
+		if err := client.ContainerUnpause(context.WithoutCancel(ctx), containers[0].ID); err != nil {
+			pterm.Error.Printfln("Could not unpause service %s of %s: %s", serviceName, sourceName, err)
+		}
+	}()
+
+	sourcePrefix := DeployConfiguration{Name: sourceName}.ContainerPrefix()
+
+	for _, m := range containers[0].Mounts {
+		if m.Type != mount.TypeVolume || !strings.HasPrefix(m.Name, sourcePrefix+"_") {
+			continue
+		}
+
+		target := previewCfg.ContainerPrefix() + strings.TrimPrefix(m.Name, sourcePrefix)
+
+		_, err := client.VolumeCreate(ctx, volume.CreateOptions{
+			Name: target,
+			Labels: map[string]string{
+				"tanjun":         "true",
+				"tanjun.project": previewCfg.Name,
+				"tanjun.service": serviceName,
+			},
+		})
+
+		if err != nil {
+			spinnerInfo.Fail(err)
+			return err
+		}
+
+		if err := copyVolume(ctx, client, m.Name, target); err != nil {
+			spinnerInfo.Fail(err)
+			return fmt.Errorf("could not copy volume %s: %w", m.Name, err)
+		}
+	}
+
+	spinnerInfo.Success(fmt.Sprintf("Seeded service %s from %s", serviceName, sourceName))
+
+	return nil
+}
+
+func copyVolume(ctx context.Context, client *client.Client, source, target string) error {
+	containerCfg := &container.Config{
+		Image: "alpine:latest",
+		Cmd:   []string{"cp", "-a", "/source/.", "/target/"},
+	}
+
+	hostCfg := &container.HostConfig{
+		Mounts: []mount.Mount{
+			{
+				Type:     mount.TypeVolume,
+				Source:   source,
+				Target:   "/source",
+				ReadOnly: true,
+			},
+			{
+				Type:   mount.TypeVolume,
+				Source: target,
+				Target: "/target",
+			},
+		},
+	}
+
+	c, err := client.ContainerCreate(ctx, containerCfg, hostCfg, nil, nil, "")
+
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		_ = client.ContainerRemove(context.WithoutCancel(ctx), c.ID, container.RemoveOptions{Force: true})
+	}()
+
+	if err := client.ContainerStart(ctx, c.ID, container.StartOptions{}); err != nil {
+		return err
+	}
+
+	statusCh, errCh := client.ContainerWait(ctx, c.ID, container.WaitConditionNotRunning)
+
+	select {
+	case err := <-errCh:
+		return err
+	case status := <-statusCh:
+		if status.StatusCode != 0 {
+			return fmt.Errorf("copy exited with status code %d", status.StatusCode)
+		}
+	}
+
+	return nil
+}
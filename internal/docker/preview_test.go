@@ -0,0 +1,36 @@
+package docker
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpdatePreviews(t *testing.T) {
+	kv := newTestKvClient(t)
+
+	assert.NoError(t, updatePreviews(kv, "app", func(previews map[string]PreviewState) {
+		previews["pr-1"] = PreviewState{Name: "pr-1"}
+	}))
+
+	assert.NoError(t, updatePreviews(kv, "app", func(previews map[string]PreviewState) {
+		previews["pr-2"] = PreviewState{Name: "pr-2"}
+	}))
+
+	previews, err := ListPreviews(kv, "app")
+
+	assert.NoError(t, err)
+	assert.Len(t, previews, 2)
+	assert.Equal(t, "pr-1", previews[0].Name)
+
+	for _, name := range []string{"pr-1", "pr-2"} {
+		assert.NoError(t, updatePreviews(kv, "app", func(previews map[string]PreviewState) {
+			delete(previews, name)
+		}))
+	}
+
+	previews, err = ListPreviews(kv, "app")
+
+	assert.NoError(t, err)
+	assert.Empty(t, previews)
+}
@@ -0,0 +1,313 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"math/rand/v2"
+	"slices"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/client"
+	"github.com/gosimple/slug"
+	"github.com/pterm/pterm"
+	"github.com/shyim/tanjun/internal/config"
+)
+
+// ScaleWeb is the name of the app containers for tanjun scale, all other names are workers
+const ScaleWeb = "web"
+
+func scaleKey(name string) string {
+	cfg := DeployConfiguration{Name: slug.Make(name)}
+
+	return cfg.ContainerPrefix() + "_scale"
+}
+
+// GetScale returns the replicas set with tanjun scale, they take precedence over the configuration
+func GetScale(kv *KvClient, name string) (map[string]int, error) {
+	value, err := kv.Get(scaleKey(name))
+
+	if err != nil {
+		return nil, err
+	}
+
+	scale := make(map[string]int)
+
+	if value == "" {
+		return scale, nil
+	}
+
+	if err := json.Unmarshal([]byte(value), &scale); err != nil {
+		return nil, fmt.Errorf("could not parse scale: %w", err)
+	}
+
+	return scale, nil
+}
+
+func setScale(kv *KvClient, name string, scale map[string]int) error {
+	encoded, err := json.Marshal(scale)
+
+	if err != nil {
+		return err
+	}
+
+	if err := kv.Set(scaleKey(name), string(encoded)); err != nil {
+		return fmt.Errorf("could not set scale: %w", err)
+	}
+
+	return nil
+}
+
+// applyScale overwrites the configured replicas with the ones set by tanjun scale, a difference to the configuration is
+// printed as the changed replicas of the configuration would have no effect otherwise
+func applyScale(projectConfig *config.ProjectConfig, scale map[string]int) {
+	for _, name := range slices.Sorted(maps.Keys(scale)) {
+		replicas := scale[name]
+
+		if name == ScaleWeb {
+			if projectConfig.App.Replicas != replicas {
+				pterm.Info.Printfln("The app runs %d replicas set by tanjun scale instead of the configured %d, change it with tanjun scale %s=%d", replicas, projectConfig.App.Replicas, name, projectConfig.App.Replicas)
+			}
+
+			projectConfig.App.Replicas = replicas
+			continue
+		}
+
+		if worker, ok := projectConfig.App.Workers[name]; ok {
+			if configured := WorkerReplicas(worker); configured != replicas {
+				pterm.Info.Printfln("Worker %s runs %d replicas set by tanjun scale instead of the configured %d, change it with tanjun scale %s=%d", name, replicas, configured, name, configured)
+			}
+
+			worker.Replicas = replicas
+			projectConfig.App.Workers[name] = worker
+		}
+	}
+}
+
+// WorkerReplicas returns the amount of containers started for the worker, a worker without replicas runs one
+func WorkerReplicas(worker config.ProjectWorker) int {
+	return max(worker.Replicas, 1)
+}
+
+func validateScale(projectConfig *config.ProjectConfig, scale map[string]int) error {
+	for name, replicas := range scale {
+		if replicas < 1 {
+			return fmt.Errorf("%s needs at least one replica", name)
+		}
+
+		if _, ok := projectConfig.App.Workers[name]; name != ScaleWeb && !ok {
+			return fmt.Errorf("unknown worker %s, allowed are %s and the workers %v", name, ScaleWeb, slices.Sorted(maps.Keys(projectConfig.App.Workers)))
+		}
+	}
+
+	return nil
+}
+
+// Scale changes the amount of running app containers and workers without deploying a new version. The replicas are remembered for the next deployments
+func Scale(ctx context.Context, client *client.Client, projectConfig *config.ProjectConfig, scale map[string]int) error {
+	if err := validateScale(projectConfig, scale); err != nil {
+		return err
+	}
+
+	deployCfg := newDeployConfiguration(projectConfig, "")
+
+	var err error
+
+	deployCfg.storage, err = CreateKVConnection(ctx, client)
+
+	if err != nil {
+		return err
+	}
+
+	defer deployCfg.storage.Close()
+
+	unlock, err := lockProject(ctx, client, deployCfg.storage, deployCfg.Name)
+
+	if err != nil {
+		return err
+	}
+
+	defer unlock()
+
+	if err := ensureNoCanary(deployCfg); err != nil {
+		return err
+	}
+
+	if err := ensureNoMaintenance(deployCfg); err != nil {
+		return err
+	}
+
+	deployments, err := createServerDeployments(ctx, client, deployCfg)
+
+	if err != nil {
+		return err
+	}
+
+	defer closeServerDeployments(deployments)
+
+	if err := rolloutServerDeployments(deployCfg, deployments, func(d *serverDeployment) error {
+		return d.scale(ctx, scale)
+	}); err != nil {
+		return err
+	}
+
+	storedScale, err := GetScale(deployCfg.storage, deployCfg.Name)
+
+	if err != nil {
+		return err
+	}
+
+	maps.Copy(storedScale, scale)
+
+	return setScale(deployCfg.storage, deployCfg.Name, storedScale)
+}
+
+func (d *serverDeployment) scale(ctx context.Context, scale map[string]int) error {
+	for _, name := range slices.Sorted(maps.Keys(scale)) {
+		if name == ScaleWeb {
+			if !d.server.HasRole(config.ServerRoleWeb) {
+				continue
+			}
+
+			if err := d.scaleApp(ctx, scale[name]); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		if !d.server.HasRole(config.ServerRoleWorker) {
+			continue
+		}
+
+		if err := d.scaleWorker(ctx, name, scale[name]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (d *serverDeployment) scaleApp(ctx context.Context, replicas int) error {
+	containers, err := getEnvironmentContainers(ctx, d.client, d.deployCfg.Name)
+
+	if err != nil {
+		return err
+	}
+
+	if len(containers) == 0 {
+		return fmt.Errorf("there is no deployment yet, run tanjun deploy first")
+	}
+
+	if len(containers) == replicas {
+		return nil
+	}
+
+	spinnerInfo, err := pterm.DefaultSpinner.Start(fmt.Sprintf("Scaling app from %d to %d containers%s", len(containers), replicas, d.describe()))
+
+	if err != nil {
+		return err
+	}
+
+	ids := containerIDs(containers)
+
+	for len(ids) < replicas {
+		id, err := cloneContainer(ctx, d.client, containers[0].ID, fmt.Sprintf("%s_app_%d", d.deployCfg.ContainerPrefix(), rand.IntN(1000000)))
+
+		if err != nil {
+			spinnerInfo.Fail(err)
+			return err
+		}
+
+		ids = append(ids, id)
+	}
+
+	targets, err := getContainerProxyTargets(ctx, d.client, d.deployCfg, ids[:replicas])
+
+	if err != nil {
+		spinnerInfo.Fail(err)
+		return err
+	}
+
+	if err := configureKamalService(ctx, d.client, getKamalDeployCommand(d.deployCfg, targets...)); err != nil {
+		spinnerInfo.Fail(err)
+		return err
+	}
+
+	if len(containers) > replicas {
+		if err := removeContainers(ctx, d.client, containers[replicas:]); err != nil {
+			spinnerInfo.Fail(err)
+			return err
+		}
+	}
+
+	spinnerInfo.Success(fmt.Sprintf("Scaled app to %d containers%s", replicas, d.describe()))
+
+	return nil
+}
+
+func (d *serverDeployment) scaleWorker(ctx context.Context, name string, replicas int) error {
+	options := container.ListOptions{
+		Filters: filters.NewArgs(),
+		All:     true,
+	}
+
+	options.Filters.Add("label", fmt.Sprintf("tanjun.project=%s", d.deployCfg.Name))
+	options.Filters.Add("label", fmt.Sprintf("tanjun.worker=%s", name))
+
+	containers, err := d.client.ContainerList(ctx, options)
+
+	if err != nil {
+		return err
+	}
+
+	if len(containers) == 0 {
+		return fmt.Errorf("worker %s is not running, run tanjun deploy first", name)
+	}
+
+	for i := len(containers); i < replicas; i++ {
+		if _, err := cloneContainer(ctx, d.client, containers[0].ID, fmt.Sprintf("%s_%s_%d_%d", d.deployCfg.ContainerPrefix(), name, i, rand.IntN(1000000))); err != nil {
+			return err
+		}
+	}
+
+	if len(containers) > replicas {
+		return removeContainers(ctx, d.client, containers[replicas:])
+	}
+
+	return nil
+}
+
+// cloneContainer starts another container with the configuration of the given one
+func cloneContainer(ctx context.Context, client *client.Client, containerID, name string) (string, error) {
+	inspect, err := client.ContainerInspect(ctx, containerID)
+
+	if err != nil {
+		return "", err
+	}
+
+	containerCfg := inspect.Config
+	containerCfg.Hostname = ""
+	delete(containerCfg.Labels, canaryLabel)
+
+	networkCfg := &network.NetworkingConfig{EndpointsConfig: map[string]*network.EndpointSettings{}}
+
+	for networkName := range inspect.NetworkSettings.Networks {
+		networkCfg.EndpointsConfig[networkName] = &network.EndpointSettings{}
+	}
+
+	resp, err := client.ContainerCreate(ctx, containerCfg, inspect.HostConfig, networkCfg, nil, name)
+
+	if err != nil {
+		return "", err
+	}
+
+	if err := client.ContainerStart(ctx, resp.ID, container.StartOptions{}); err != nil {
+		return "", err
+	}
+
+	return resp.ID, nil
+}
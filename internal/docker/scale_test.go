@@ -0,0 +1,35 @@
+package docker
+
+import (
+	"testing"
+
+	"github.com/shyim/tanjun/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplyScale(t *testing.T) {
+	projectConfig := &config.ProjectConfig{
+		App: config.ProjectApp{
+			Replicas: 1,
+			Workers: map[string]config.ProjectWorker{
+				"queue": {Command: "consume", Replicas: 1},
+			},
+		},
+	}
+
+	assert.NoError(t, validateScale(projectConfig, map[string]int{"web": 3, "queue": 5}))
+	assert.ErrorContains(t, validateScale(projectConfig, map[string]int{"mail": 2}), "unknown worker mail")
+	assert.ErrorContains(t, validateScale(projectConfig, map[string]int{"web": 0}), "at least one replica")
+
+	applyScale(projectConfig, map[string]int{"web": 3, "queue": 5, "removed": 2})
+
+	assert.Equal(t, 3, projectConfig.App.Replicas)
+	assert.Equal(t, 5, projectConfig.App.Workers["queue"].Replicas)
+	assert.Equal(t, "consume", projectConfig.App.Workers["queue"].Command)
+	assert.Len(t, projectConfig.App.Workers, 1)
+}
+
+func TestWorkerReplicas(t *testing.T) {
+	assert.Equal(t, 1, WorkerReplicas(config.ProjectWorker{}))
+	assert.Equal(t, 3, WorkerReplicas(config.ProjectWorker{Replicas: 3}))
+}
@@ -0,0 +1,249 @@
+package docker
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gosimple/slug"
+)
+
+// secretRevisionLimit is the amount of revisions kept per project, older revisions are dropped
+const secretRevisionLimit = 50
+
+const (
+	SecretChangeAdded   = "added"
+	SecretChangeChanged = "changed"
+	SecretChangeRemoved = "removed"
+)
+
+// SecretRevision is a snapshot of all secrets of a project after a change
+type SecretRevision struct {
+	Revision  int               `json:"revision"`
+	User      string            `json:"user"`
+	Host      string            `json:"host"`
+	CreatedAt time.Time         `json:"created_at"`
+	Secrets   map[string]string `json:"secrets"`
+}
+
+type SecretChange struct {
+	Key      string
+	Type     string
+	OldValue string
+	NewValue string
+}
+
+func secretRevisionPrefix(name string) string {
+	cfg := DeployConfiguration{Name: slug.Make(name)}
+
+	return cfg.ContainerPrefix() + "_secret_revisions_"
+}
+
+func secretRevisionKey(name string, revision int) string {
+	return fmt.Sprintf("%s%08d", secretRevisionPrefix(name), revision)
+}
+
+// listSecretRevisionNumbers returns the stored revision numbers in ascending order
+func listSecretRevisionNumbers(kv *KvClient, name string) ([]int, error) {
+	prefix := secretRevisionPrefix(name)
+
+	keys, err := kv.List(prefix)
+
+	if err != nil {
+		return nil, err
+	}
+
+	revisions := make([]int, 0, len(keys))
+
+	for _, key := range keys {
+		revision, err := strconv.Atoi(strings.TrimPrefix(key, prefix))
+
+		if err != nil {
+			continue
+		}
+
+		revisions = append(revisions, revision)
+	}
+
+	sort.Ints(revisions)
+
+	return revisions, nil
+}
+
+// ListSecretRevisions returns all stored revisions of the project, oldest revision first
+func ListSecretRevisions(kv *KvClient, name string) ([]SecretRevision, error) {
+	numbers, err := listSecretRevisionNumbers(kv, name)
+
+	if err != nil {
+		return nil, err
+	}
+
+	revisions := make([]SecretRevision, 0, len(numbers))
+
+	for _, number := range numbers {
+		revision, err := GetSecretRevision(kv, name, number)
+
+		if err != nil {
+			return nil, err
+		}
+
+		revisions = append(revisions, *revision)
+	}
+
+	return revisions, nil
+}
+
+func GetSecretRevision(kv *KvClient, name string, revision int) (*SecretRevision, error) {
+	value, err := kv.GetEncrypted(secretRevisionKey(name, revision))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if value == "" {
+		return nil, fmt.Errorf("secret revision %d not found", revision)
+	}
+
+	var stored SecretRevision
+
+	if err := json.Unmarshal([]byte(value), &stored); err != nil {
+		return nil, fmt.Errorf("could not parse secret revision %d: %w", revision, err)
+	}
+
+	return &stored, nil
+}
+
+// recordSecretRevision stores the secrets as new revision. Secrets stored before revisions were introduced are recorded first, so they can be rolled back to.
+// The revision is only created when its number is still free, so concurrent writers get consecutive revisions
+func recordSecretRevision(kv *KvClient, name string, previous, secrets map[string]string) error {
+	for attempt := 0; attempt < kvUpdateAttempts; attempt++ {
+		numbers, err := listSecretRevisionNumbers(kv, name)
+
+		if err != nil {
+			return err
+		}
+
+		if len(numbers) == 0 && len(previous) > 0 {
+			err := storeSecretRevision(kv, name, SecretRevision{Revision: 1, CreatedAt: time.Now().UTC(), Secrets: previous})
+
+			if err != nil && !errors.Is(err, ErrKVConflict) {
+				return err
+			}
+
+			continue
+		}
+
+		next := 1
+
+		if len(numbers) > 0 {
+			next = numbers[len(numbers)-1] + 1
+		}
+
+		revision := SecretRevision{
+			Revision:  next,
+			User:      currentUsername(),
+			Host:      currentHostname(),
+			CreatedAt: time.Now().UTC(),
+			Secrets:   secrets,
+		}
+
+		if err := storeSecretRevision(kv, name, revision); err != nil {
+			if errors.Is(err, ErrKVConflict) {
+				continue
+			}
+
+			return err
+		}
+
+		numbers = append(numbers, next)
+
+		for len(numbers) > secretRevisionLimit {
+			if err := kv.Delete(secretRevisionKey(name, numbers[0])); err != nil {
+				return err
+			}
+
+			numbers = numbers[1:]
+		}
+
+		return nil
+	}
+
+	return fmt.Errorf("could not store secret revision: %w", ErrKVConflict)
+}
+
+func storeSecretRevision(kv *KvClient, name string, revision SecretRevision) error {
+	encoded, err := json.Marshal(revision)
+
+	if err != nil {
+		return err
+	}
+
+	key := secretRevisionKey(name, revision.Revision)
+
+	encrypted, err := kv.encrypt(key, string(encoded))
+
+	if err != nil {
+		return err
+	}
+
+	return kv.CompareAndSwap(key, encrypted, 0)
+}
+
+// deleteSecretRevisions removes all revisions of the project
+func deleteSecretRevisions(kv *KvClient, name string) error {
+	numbers, err := listSecretRevisionNumbers(kv, name)
+
+	if err != nil {
+		return err
+	}
+
+	for _, number := range numbers {
+		if err := kv.Delete(secretRevisionKey(name, number)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RollbackProjectSecrets restores the secrets of the revision, the rollback itself is stored as new revision
+func RollbackProjectSecrets(kv *KvClient, name string, revision int) error {
+	stored, err := GetSecretRevision(kv, name, revision)
+
+	if err != nil {
+		return err
+	}
+
+	return ReplaceProjectSecrets(kv, name, stored.Secrets, nil)
+}
+
+// DiffSecrets returns the changes from old to new sorted by key
+func DiffSecrets(old, new map[string]string) []SecretChange {
+	changes := []SecretChange{}
+
+	for key, oldValue := range old {
+		newValue, ok := new[key]
+
+		if !ok {
+			changes = append(changes, SecretChange{Key: key, Type: SecretChangeRemoved, OldValue: oldValue})
+		} else if newValue != oldValue {
+			changes = append(changes, SecretChange{Key: key, Type: SecretChangeChanged, OldValue: oldValue, NewValue: newValue})
+		}
+	}
+
+	for key, newValue := range new {
+		if _, ok := old[key]; !ok {
+			changes = append(changes, SecretChange{Key: key, Type: SecretChangeAdded, NewValue: newValue})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Key < changes[j].Key
+	})
+
+	return changes
+}
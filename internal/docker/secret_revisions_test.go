@@ -0,0 +1,73 @@
+package docker
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffSecrets(t *testing.T) {
+	changes := DiffSecrets(
+		map[string]string{"A": "1", "B": "2", "C": "3"},
+		map[string]string{"A": "1", "B": "4", "D": "5"},
+	)
+
+	assert.Equal(t, []SecretChange{
+		{Key: "B", Type: SecretChangeChanged, OldValue: "2", NewValue: "4"},
+		{Key: "C", Type: SecretChangeRemoved, OldValue: "3"},
+		{Key: "D", Type: SecretChangeAdded, NewValue: "5"},
+	}, changes)
+
+	assert.Empty(t, DiffSecrets(map[string]string{"A": "1"}, map[string]string{"A": "1"}))
+}
+
+func TestSecretRevisionKey(t *testing.T) {
+	assert.Equal(t, "tanjun_my-app_secret_revisions_00000012", secretRevisionKey("My App", 12))
+}
+
+func TestProjectSecretPrefix(t *testing.T) {
+	assert.Equal(t, "tanjun_my-app_secrets", legacySecretsKey("My App"))
+	assert.Equal(t, "tanjun_my-app_secrets/", projectSecretPrefix("My App"))
+}
+
+func TestMaskSecret(t *testing.T) {
+	assert.Equal(t, "", MaskSecret(""))
+	assert.Equal(t, "********", MaskSecret("secret"))
+	assert.Equal(t, "********", MaskSecret("sk_live_1234567890"))
+}
+
+func TestRemovedSecrets(t *testing.T) {
+	current := map[string]string{"A": "1", "B": "2", "APP_SECRET": "3", "MINIO_STORAGE_ACCESS_KEY": "4"}
+
+	assert.Equal(t, []string{"APP_SECRET", "B", "MINIO_STORAGE_ACCESS_KEY"}, removedSecrets(current, map[string]string{"A": "1"}, nil))
+	assert.Equal(t, []string{"B"}, removedSecrets(current, map[string]string{"A": "1"}, []string{"APP_SECRET", "MINIO_STORAGE_ACCESS_KEY"}))
+}
+
+func TestListProjectSecretsDoesNotMigrate(t *testing.T) {
+	kv := newTestKvClient(t)
+
+	assert.NoError(t, kv.Set(legacySecretsKey("app"), `{"A":"legacy","B":"2"}`))
+	assert.NoError(t, kv.Set(projectSecretPrefix("app")+"A", "1"))
+
+	secrets, err := ListProjectSecrets(kv, "app")
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"A": "1", "B": "2"}, secrets)
+
+	legacy, err := kv.Get(legacySecretsKey("app"))
+
+	assert.NoError(t, err)
+	assert.NotEmpty(t, legacy)
+
+	assert.NoError(t, SetProjectSecrets(kv, "app", map[string]string{"C": "3"}))
+
+	legacy, err = kv.Get(legacySecretsKey("app"))
+
+	assert.NoError(t, err)
+	assert.Empty(t, legacy)
+
+	secrets, err = ListProjectSecrets(kv, "app")
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"A": "1", "B": "2", "C": "3"}, secrets)
+}
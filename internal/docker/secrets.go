@@ -3,43 +3,243 @@ package docker
 import (
 	"encoding/json"
 	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/log"
 	"github.com/gosimple/slug"
 )
 
-func ListProjectSecrets(kv *KvClient, name string) (map[string]string, error) {
+// legacySecretsKey stored all secrets of a project as one JSON object, it is migrated to one key per secret by tanjun setup
+// or the next change of the secrets
+func legacySecretsKey(name string) string {
 	cfg := DeployConfiguration{Name: slug.Make(name)}
 
-	secret, err := kv.Get(cfg.ContainerPrefix() + "_secrets")
+	return cfg.ContainerPrefix() + "_secrets"
+}
+
+// projectSecretPrefix is the prefix of the kv keys holding a single secret, so concurrent changes of different secrets do not overwrite each other
+func projectSecretPrefix(name string) string {
+	return legacySecretsKey(name) + "/"
+}
+
+// ListProjectSecrets only reads, secrets of a legacy key which was not migrated yet are returned as well
+func ListProjectSecrets(kv *KvClient, name string) (map[string]string, error) {
+	secrets, err := getLegacySecrets(kv, legacySecretsKey(name))
+
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := projectSecretPrefix(name)
+
+	keys, err := kv.List(prefix)
 
 	if err != nil {
 		return nil, err
 	}
 
-	if secret == "" {
-		return make(map[string]string), nil
+	if len(keys) == 0 {
+		return secrets, nil
 	}
 
-	var secrets map[string]string
+	stored, err := kv.MGet(keys)
 
-	if err := json.Unmarshal([]byte(secret), &secrets); err != nil {
+	if err != nil {
 		return nil, err
 	}
 
+	for key, value := range stored {
+		secret, err := kv.decrypt(key, value)
+
+		if err != nil {
+			return nil, err
+		}
+
+		secrets[strings.TrimPrefix(key, prefix)] = secret
+	}
+
 	return secrets, nil
 }
 
+func getLegacySecrets(kv *KvClient, key string) (map[string]string, error) {
+	secrets := make(map[string]string)
+
+	stored, err := kv.GetEncrypted(key)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if stored == "" {
+		return secrets, nil
+	}
+
+	if err := json.Unmarshal([]byte(stored), &secrets); err != nil {
+		return nil, fmt.Errorf("could not parse secrets of %s: %w", key, err)
+	}
+
+	return secrets, nil
+}
+
+// migrateLegacySecrets moves the secrets of the legacy key to one key per secret, secrets already stored by key win
+func migrateLegacySecrets(kv *KvClient, key string) error {
+	secrets, err := getLegacySecrets(kv, key)
+
+	if err != nil {
+		return err
+	}
+
+	if len(secrets) == 0 {
+		return nil
+	}
+
+	values := make(map[string]string, len(secrets))
+
+	for secretKey, value := range secrets {
+		values[key+"/"+secretKey] = value
+	}
+
+	existing, err := kv.List(key + "/")
+
+	if err != nil {
+		return err
+	}
+
+	for _, existingKey := range existing {
+		delete(values, existingKey)
+	}
+
+	if len(values) > 0 {
+		if err := kv.MSetEncrypted(values); err != nil {
+			return fmt.Errorf("could not migrate secrets: %s", err)
+		}
+	}
+
+	return kv.Delete(key)
+}
+
+// SetProjectSecrets stores the given secrets, other secrets of the project are kept
 func SetProjectSecrets(kv *KvClient, name string, secrets map[string]string) error {
-	cfg := DeployConfiguration{Name: slug.Make(name)}
+	return updateProjectSecrets(kv, name, secrets, nil)
+}
+
+func DeleteProjectSecrets(kv *KvClient, name string, keys []string) error {
+	return updateProjectSecrets(kv, name, nil, keys)
+}
+
+// ReplaceProjectSecrets stores the secrets and deletes all others, except the ones listed in keep
+func ReplaceProjectSecrets(kv *KvClient, name string, secrets map[string]string, keep []string) error {
+	current, err := ListProjectSecrets(kv, name)
+
+	if err != nil {
+		return err
+	}
+
+	return updateProjectSecrets(kv, name, secrets, removedSecrets(current, secrets, keep))
+}
+
+// removedSecrets returns the sorted keys of current which are neither in secrets nor in keep
+func removedSecrets(current, secrets map[string]string, keep []string) []string {
+	var removed []string
+
+	for key := range current {
+		if _, ok := secrets[key]; !ok && !slices.Contains(keep, key) {
+			removed = append(removed, key)
+		}
+	}
+
+	slices.Sort(removed)
+
+	return removed
+}
+
+// updateProjectSecrets writes only the changed secrets and records the result as new revision
+func updateProjectSecrets(kv *KvClient, name string, set map[string]string, del []string) error {
+	if err := migrateLegacySecrets(kv, legacySecretsKey(name)); err != nil {
+		return err
+	}
+
+	previous, err := ListProjectSecrets(kv, name)
+
+	if err != nil {
+		return err
+	}
+
+	prefix := projectSecretPrefix(name)
+	changed := map[string]string{}
+
+	for key, value := range set {
+		if current, ok := previous[key]; !ok || current != value {
+			changed[prefix+key] = value
+		}
+	}
+
+	var deleted []string
+
+	for _, key := range del {
+		if _, ok := previous[key]; ok {
+			deleted = append(deleted, prefix+key)
+		}
+	}
 
-	secret, err := json.Marshal(secrets)
+	if len(changed) == 0 && len(deleted) == 0 {
+		return nil
+	}
+
+	if kv.keys == nil {
+		log.Warnf("The server has no master key, secrets are stored unencrypted. Run tanjun setup to enable encryption")
+	}
+
+	if len(changed) > 0 {
+		if err := kv.MSetEncrypted(changed); err != nil {
+			return fmt.Errorf("could not set secrets: %s", err)
+		}
+	}
+
+	for _, key := range deleted {
+		if err := kv.Delete(key); err != nil {
+			return fmt.Errorf("could not delete secret: %s", err)
+		}
+	}
+
+	current, err := ListProjectSecrets(kv, name)
 
 	if err != nil {
 		return err
 	}
 
-	if err := kv.Set(cfg.ContainerPrefix()+"_secrets", string(secret)); err != nil {
-		return fmt.Errorf("could not set secrets: %s", err)
+	if err := recordSecretRevision(kv, name, previous, current); err != nil {
+		return fmt.Errorf("secrets were set, but the revision could not be recorded: %w", err)
 	}
 
 	return nil
 }
+
+// deleteAllProjectSecrets removes the secrets of the project including their revisions
+func deleteAllProjectSecrets(kv *KvClient, name string) error {
+	keys, err := kv.List(projectSecretPrefix(name))
+
+	if err != nil {
+		return err
+	}
+
+	keys = append(keys, legacySecretsKey(name))
+
+	for _, key := range keys {
+		if err := kv.Delete(key); err != nil {
+			return err
+		}
+	}
+
+	return deleteSecretRevisions(kv, name)
+}
+
+// MaskSecret hides the value for output, neither a part of the value nor its length is shown
+func MaskSecret(value string) string {
+	if value == "" {
+		return ""
+	}
+
+	return strings.Repeat("*", 8)
+}
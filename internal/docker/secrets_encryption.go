@@ -0,0 +1,670 @@
+package docker
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/errdefs"
+)
+
+// tanjunKVKeyVolumeName holds the master keys, it is separate from the kv volume so a copy of the database alone does not reveal the secrets
+const tanjunKVKeyVolumeName = "tanjun-kv-key"
+
+const masterKeysDir = "/keys"
+const masterKeysFile = "master.json"
+
+// encryptedValuePrefix marks kv values encrypted with encryptValue, values without it are plaintext from before encryption was introduced
+const encryptedValuePrefix = "enc:v1:"
+
+// masterKeys is the content of the master key file. Multiple keys only exist while a rotation is running
+type masterKeys struct {
+	Current string            `json:"current"`
+	Keys    map[string][]byte `json:"keys"`
+}
+
+// encryptedValue is an envelope: the value is encrypted with its own data key, which is encrypted with a master key
+type encryptedValue struct {
+	KeyID      string `json:"key_id"`
+	DataKey    []byte `json:"data_key"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+func newMasterKeyID() string {
+	return time.Now().UTC().Format("20060102150405")
+}
+
+func randomBytes(n int) ([]byte, error) {
+	b := make([]byte, n)
+
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+func seal(key, plaintext, additionalData []byte) ([]byte, []byte, error) {
+	aead, err := newAEAD(key)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	nonce, err := randomBytes(aead.NonceSize())
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return nonce, aead.Seal(nil, nonce, plaintext, additionalData), nil
+}
+
+func open(key, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	aead, err := newAEAD(key)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return aead.Open(nil, nonce, ciphertext, additionalData)
+}
+
+// wrapDataKey encrypts the data key with the current master key, the nonce is stored in front of the encrypted key
+func (m *masterKeys) wrapDataKey(dataKey []byte, kvKey string) (string, []byte, error) {
+	nonce, wrapped, err := seal(m.Keys[m.Current], dataKey, []byte(kvKey))
+
+	if err != nil {
+		return "", nil, err
+	}
+
+	return m.Current, append(nonce, wrapped...), nil
+}
+
+func (m *masterKeys) unwrapDataKey(keyID string, wrapped []byte, kvKey string) ([]byte, error) {
+	masterKey, ok := m.Keys[keyID]
+
+	if !ok {
+		return nil, fmt.Errorf("master key %s not found, the secrets cannot be decrypted", keyID)
+	}
+
+	if len(wrapped) < 12 {
+		return nil, fmt.Errorf("invalid data key")
+	}
+
+	return open(masterKey, wrapped[:12], wrapped[12:], []byte(kvKey))
+}
+
+// encryptValue encrypts the value for the kv key, the key is authenticated so an encrypted value cannot be moved to another project
+func (m *masterKeys) encryptValue(kvKey, value string) (string, error) {
+	dataKey, err := randomBytes(32)
+
+	if err != nil {
+		return "", err
+	}
+
+	nonce, ciphertext, err := seal(dataKey, []byte(value), []byte(kvKey))
+
+	if err != nil {
+		return "", err
+	}
+
+	keyID, wrapped, err := m.wrapDataKey(dataKey, kvKey)
+
+	if err != nil {
+		return "", err
+	}
+
+	encoded, err := json.Marshal(encryptedValue{KeyID: keyID, DataKey: wrapped, Nonce: nonce, Ciphertext: ciphertext})
+
+	if err != nil {
+		return "", err
+	}
+
+	return encryptedValuePrefix + base64.StdEncoding.EncodeToString(encoded), nil
+}
+
+func parseEncryptedValue(value string) (*encryptedValue, error) {
+	decoded, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, encryptedValuePrefix))
+
+	if err != nil {
+		return nil, err
+	}
+
+	var envelope encryptedValue
+
+	if err := json.Unmarshal(decoded, &envelope); err != nil {
+		return nil, err
+	}
+
+	return &envelope, nil
+}
+
+func (m *masterKeys) decryptValue(kvKey, value string) (string, error) {
+	envelope, err := parseEncryptedValue(value)
+
+	if err != nil {
+		return "", fmt.Errorf("could not parse encrypted value of %s: %w", kvKey, err)
+	}
+
+	dataKey, err := m.unwrapDataKey(envelope.KeyID, envelope.DataKey, kvKey)
+
+	if err != nil {
+		return "", fmt.Errorf("could not decrypt data key of %s: %w", kvKey, err)
+	}
+
+	plaintext, err := open(dataKey, envelope.Nonce, envelope.Ciphertext, []byte(kvKey))
+
+	if err != nil {
+		return "", fmt.Errorf("could not decrypt %s: %w", kvKey, err)
+	}
+
+	return string(plaintext), nil
+}
+
+// rewrapValue encrypts the data key of the value with the current master key, the value itself stays untouched
+func (m *masterKeys) rewrapValue(kvKey, value string) (string, error) {
+	envelope, err := parseEncryptedValue(value)
+
+	if err != nil {
+		return "", err
+	}
+
+	dataKey, err := m.unwrapDataKey(envelope.KeyID, envelope.DataKey, kvKey)
+
+	if err != nil {
+		return "", err
+	}
+
+	envelope.KeyID, envelope.DataKey, err = m.wrapDataKey(dataKey, kvKey)
+
+	if err != nil {
+		return "", err
+	}
+
+	encoded, err := json.Marshal(envelope)
+
+	if err != nil {
+		return "", err
+	}
+
+	return encryptedValuePrefix + base64.StdEncoding.EncodeToString(encoded), nil
+}
+
+// GetEncrypted returns the decrypted value, plaintext values stored before encryption was enabled are returned as they are
+func (c KvClient) GetEncrypted(key string) (string, error) {
+	value, err := c.Get(key)
+
+	if err != nil {
+		return "", err
+	}
+
+	return c.decrypt(key, value)
+}
+
+func (c KvClient) decrypt(key, value string) (string, error) {
+	if !strings.HasPrefix(value, encryptedValuePrefix) {
+		return value, nil
+	}
+
+	if c.keys == nil {
+		return "", fmt.Errorf("%s is encrypted, but the master key is not available, run tanjun setup", key)
+	}
+
+	return c.keys.decryptValue(key, value)
+}
+
+// SetEncrypted encrypts the value before it is stored, without master key it is stored as plaintext
+func (c KvClient) SetEncrypted(key, value string) error {
+	encrypted, err := c.encrypt(key, value)
+
+	if err != nil {
+		return err
+	}
+
+	return c.Set(key, encrypted)
+}
+
+// MSetEncrypted encrypts all values and stores them in one transaction
+func (c KvClient) MSetEncrypted(values map[string]string) error {
+	encrypted := make(map[string]string, len(values))
+
+	for key, value := range values {
+		var err error
+
+		if encrypted[key], err = c.encrypt(key, value); err != nil {
+			return err
+		}
+	}
+
+	return c.MSet(encrypted)
+}
+
+func (c KvClient) encrypt(key, value string) (string, error) {
+	if c.keys == nil {
+		return value, nil
+	}
+
+	return c.keys.encryptValue(key, value)
+}
+
+// hasMasterKeyMount checks if the kv container was created with the master key volume
+func hasMasterKeyMount(kvContainer container.Summary) bool {
+	for _, m := range kvContainer.Mounts {
+		if m.Destination == masterKeysDir {
+			return true
+		}
+	}
+
+	return false
+}
+
+func readMasterKeys(ctx context.Context, client *client.Client, containerID string) (*masterKeys, error) {
+	reader, _, err := client.CopyFromContainer(ctx, containerID, path.Join(masterKeysDir, masterKeysFile))
+
+	if err != nil {
+		if errdefs.IsNotFound(err) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	defer reader.Close()
+
+	archive := tar.NewReader(reader)
+
+	if _, err := archive.Next(); err != nil {
+		return nil, err
+	}
+
+	content, err := io.ReadAll(archive)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return parseMasterKeys(content)
+}
+
+func parseMasterKeys(content []byte) (*masterKeys, error) {
+	var keys masterKeys
+
+	if err := json.Unmarshal(content, &keys); err != nil {
+		return nil, fmt.Errorf("could not parse master key file: %w", err)
+	}
+
+	if _, ok := keys.Keys[keys.Current]; !ok {
+		return nil, fmt.Errorf("current master key %s is missing in the master key file", keys.Current)
+	}
+
+	return &keys, nil
+}
+
+func writeMasterKeys(ctx context.Context, client *client.Client, containerID string, keys *masterKeys) error {
+	encoded, err := json.Marshal(keys)
+
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+
+	archive := tar.NewWriter(&buf)
+
+	if err := archive.WriteHeader(&tar.Header{Name: masterKeysFile, Mode: 0600, Size: int64(len(encoded)), ModTime: time.Now()}); err != nil {
+		return err
+	}
+
+	if _, err := archive.Write(encoded); err != nil {
+		return err
+	}
+
+	if err := archive.Close(); err != nil {
+		return err
+	}
+
+	return client.CopyToContainer(ctx, containerID, masterKeysDir, &buf, container.CopyToContainerOptions{})
+}
+
+// ensureMasterKey creates the master key on the first setup, encrypts the plaintext values stored before and migrates
+// the legacy secrets
+func ensureMasterKey(ctx context.Context, client *client.Client) error {
+	kv, err := CreateKVConnection(ctx, client)
+
+	if err != nil {
+		return err
+	}
+
+	defer kv.Close()
+
+	if kv.keys == nil {
+		// Values encrypted without a master key file mean the key volume was lost, only the exported key can decrypt them again
+		encrypted, err := staleEncryptedKeys(kv, "")
+
+		if err != nil {
+			return err
+		}
+
+		if len(encrypted) > 0 {
+			log.Warnf("The master key is missing, but %d values are encrypted. Restore the exported key with tanjun secret import-key, otherwise these secrets are lost", len(encrypted))
+		}
+
+		masterKey, err := randomBytes(32)
+
+		if err != nil {
+			return err
+		}
+
+		keyID := newMasterKeyID()
+		keys := &masterKeys{Current: keyID, Keys: map[string][]byte{keyID: masterKey}}
+
+		if err := writeMasterKeys(ctx, client, kv.containerID, keys); err != nil {
+			return fmt.Errorf("could not write master key: %w", err)
+		}
+
+		kv.keys = keys
+
+		log.Warnf("Created a new master key in the %s volume. Store a copy of it with tanjun secret export-key, the secrets cannot be decrypted without it", tanjunKVKeyVolumeName)
+	}
+
+	if err := encryptPlaintextSecrets(kv); err != nil {
+		return err
+	}
+
+	return migrateAllLegacySecrets(kv)
+}
+
+// migrateAllLegacySecrets moves the secrets of all projects stored as one JSON object to one key per secret
+func migrateAllLegacySecrets(kv *KvClient) error {
+	keys, err := kv.List("tanjun_")
+
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		if !strings.HasSuffix(key, "_secrets") {
+			continue
+		}
+
+		if err := migrateLegacySecrets(kv, key); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// encryptPlaintextSecrets migrates the project secrets and their revisions stored before encryption was introduced
+func encryptPlaintextSecrets(kv *KvClient) error {
+	keys, err := kv.List("tanjun_")
+
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		if !strings.HasSuffix(key, "_secrets") && !strings.Contains(key, "_secrets/") && !strings.Contains(key, "_secret_revisions_") {
+			continue
+		}
+
+		value, err := kv.Get(key)
+
+		if err != nil {
+			return err
+		}
+
+		if value == "" || strings.HasPrefix(value, encryptedValuePrefix) {
+			continue
+		}
+
+		if err := kv.SetEncrypted(key, value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ExportMasterKey returns the content of the master key file, it is the only way to decrypt the secrets when the key volume is lost
+func ExportMasterKey(ctx context.Context, client *client.Client) (string, error) {
+	kv, err := CreateKVConnection(ctx, client)
+
+	if err != nil {
+		return "", err
+	}
+
+	defer kv.Close()
+
+	if kv.keys == nil {
+		return "", fmt.Errorf("no master key found, run tanjun setup first")
+	}
+
+	encoded, err := json.Marshal(kv.keys)
+
+	return string(encoded), err
+}
+
+// ImportMasterKey adds the keys of an exported master key file to the server. The current key of the server is kept,
+// so values encrypted since the key volume was lost stay readable as well
+func ImportMasterKey(ctx context.Context, client *client.Client, exported []byte) (int, error) {
+	imported, err := parseMasterKeys(exported)
+
+	if err != nil {
+		return 0, err
+	}
+
+	kv, err := CreateKVConnection(ctx, client)
+
+	if err != nil {
+		return 0, err
+	}
+
+	defer kv.Close()
+
+	keys := mergeMasterKeys(kv.keys, imported)
+
+	if err := writeMasterKeys(ctx, client, kv.containerID, keys); err != nil {
+		return 0, fmt.Errorf("could not write master key: %w", err)
+	}
+
+	kv.keys = keys
+
+	stale, err := staleEncryptedKeys(kv, "")
+
+	if err != nil {
+		return 0, err
+	}
+
+	var unreadable int
+
+	for _, key := range stale {
+		if _, err := kv.GetEncrypted(key); err != nil {
+			unreadable++
+		}
+	}
+
+	return unreadable, nil
+}
+
+// mergeMasterKeys adds the imported keys, the current key of existing stays the current one
+func mergeMasterKeys(existing, imported *masterKeys) *masterKeys {
+	if existing == nil {
+		return imported
+	}
+
+	merged := &masterKeys{Current: existing.Current, Keys: map[string][]byte{}}
+
+	for id, key := range imported.Keys {
+		merged.Keys[id] = key
+	}
+
+	for id, key := range existing.Keys {
+		merged.Keys[id] = key
+	}
+
+	return merged
+}
+
+// RotateMasterKey replaces the master key. Only the data keys are encrypted again, the values keep their data key.
+// The new key is written next to the old one first, so an interrupted rotation can be run again. The old key is only removed
+// when no value uses it anymore
+func RotateMasterKey(ctx context.Context, client *client.Client) (int, error) {
+	kv, err := CreateKVConnection(ctx, client)
+
+	if err != nil {
+		return 0, err
+	}
+
+	defer kv.Close()
+
+	if kv.keys == nil {
+		return 0, fmt.Errorf("no master key found, run tanjun setup first")
+	}
+
+	newKey, err := randomBytes(32)
+
+	if err != nil {
+		return 0, err
+	}
+
+	newKeyID := newMasterKeyID()
+
+	if _, ok := kv.keys.Keys[newKeyID]; ok {
+		return 0, fmt.Errorf("master key %s already exists, try again in a second", newKeyID)
+	}
+
+	kv.keys.Keys[newKeyID] = newKey
+	kv.keys.Current = newKeyID
+
+	if err := writeMasterKeys(ctx, client, kv.containerID, kv.keys); err != nil {
+		return 0, fmt.Errorf("could not write new master key: %w", err)
+	}
+
+	keys, err := kv.List("")
+
+	if err != nil {
+		return 0, err
+	}
+
+	rotated := 0
+
+	for _, key := range keys {
+		changed, err := rewrapKey(kv, key)
+
+		if err != nil {
+			return rotated, err
+		}
+
+		if changed {
+			rotated++
+		}
+	}
+
+	// A deployment running in the meantime could still have written values with the old key, they would be lost with it
+	stale, err := staleEncryptedKeys(kv, newKeyID)
+
+	if err != nil {
+		return rotated, err
+	}
+
+	if len(stale) > 0 {
+		return rotated, fmt.Errorf("%d values were written with the old master key during the rotation (%s), run the rotation again", len(stale), strings.Join(stale, ", "))
+	}
+
+	kv.keys.Keys = map[string][]byte{newKeyID: newKey}
+
+	if err := writeMasterKeys(ctx, client, kv.containerID, kv.keys); err != nil {
+		return rotated, fmt.Errorf("could not remove old master key: %w", err)
+	}
+
+	return rotated, nil
+}
+
+// rewrapKey encrypts the data key of the value with the current master key, the value is only replaced when it was not changed in the meantime
+func rewrapKey(kv *KvClient, key string) (bool, error) {
+	for attempt := 0; attempt < kvUpdateAttempts; attempt++ {
+		value, revision, err := kv.GetRevision(key)
+
+		if err != nil {
+			return false, err
+		}
+
+		if !strings.HasPrefix(value, encryptedValuePrefix) {
+			return false, nil
+		}
+
+		rewrapped, err := kv.keys.rewrapValue(key, value)
+
+		if err != nil {
+			return false, err
+		}
+
+		if err := kv.CompareAndSwap(key, rewrapped, revision); !errors.Is(err, ErrKVConflict) {
+			return err == nil, err
+		}
+	}
+
+	return false, fmt.Errorf("could not rewrap %s: %w", key, ErrKVConflict)
+}
+
+// staleEncryptedKeys returns the keys whose value is encrypted with another master key than the given one, with an empty id all encrypted keys
+func staleEncryptedKeys(kv *KvClient, keyID string) ([]string, error) {
+	keys, err := kv.List("")
+
+	if err != nil {
+		return nil, err
+	}
+
+	var stale []string
+
+	for _, key := range keys {
+		value, err := kv.Get(key)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if !strings.HasPrefix(value, encryptedValuePrefix) {
+			continue
+		}
+
+		envelope, err := parseEncryptedValue(value)
+
+		if err != nil {
+			return nil, fmt.Errorf("could not parse encrypted value of %s: %w", key, err)
+		}
+
+		if envelope.KeyID != keyID {
+			stale = append(stale, key)
+		}
+	}
+
+	return stale, nil
+}
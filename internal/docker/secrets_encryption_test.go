@@ -0,0 +1,111 @@
+package docker
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testMasterKeys(t *testing.T, id string) *masterKeys {
+	key, err := randomBytes(32)
+	assert.NoError(t, err)
+
+	return &masterKeys{Current: id, Keys: map[string][]byte{id: key}}
+}
+
+func TestEncryptValue(t *testing.T) {
+	keys := testMasterKeys(t, "1")
+
+	encrypted, err := keys.encryptValue("tanjun_app_secrets", `{"FOO":"bar"}`)
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(encrypted, encryptedValuePrefix))
+	assert.NotContains(t, encrypted, "bar")
+
+	decrypted, err := keys.decryptValue("tanjun_app_secrets", encrypted)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"FOO":"bar"}`, decrypted)
+
+	other, err := keys.encryptValue("tanjun_app_secrets", `{"FOO":"bar"}`)
+	assert.NoError(t, err)
+	assert.NotEqual(t, encrypted, other)
+}
+
+func TestDecryptValueOfOtherKey(t *testing.T) {
+	keys := testMasterKeys(t, "1")
+
+	encrypted, err := keys.encryptValue("tanjun_app_secrets", "value")
+	assert.NoError(t, err)
+
+	_, err = keys.decryptValue("tanjun_other_secrets", encrypted)
+	assert.Error(t, err)
+
+	_, err = testMasterKeys(t, "1").decryptValue("tanjun_app_secrets", encrypted)
+	assert.Error(t, err)
+}
+
+func TestRewrapValue(t *testing.T) {
+	keys := testMasterKeys(t, "1")
+
+	encrypted, err := keys.encryptValue("tanjun_app_secrets", "value")
+	assert.NoError(t, err)
+
+	newKeys := testMasterKeys(t, "2")
+	keys.Keys["2"] = newKeys.Keys["2"]
+	keys.Current = "2"
+
+	rewrapped, err := keys.rewrapValue("tanjun_app_secrets", encrypted)
+	assert.NoError(t, err)
+
+	decrypted, err := newKeys.decryptValue("tanjun_app_secrets", rewrapped)
+	assert.NoError(t, err)
+	assert.Equal(t, "value", decrypted)
+
+	_, err = newKeys.decryptValue("tanjun_app_secrets", encrypted)
+	assert.ErrorContains(t, err, "master key 1 not found")
+}
+
+func TestDecryptPlaintextValue(t *testing.T) {
+	kv := KvClient{}
+
+	value, err := kv.decrypt("tanjun_app_secrets", `{"FOO":"bar"}`)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"FOO":"bar"}`, value)
+
+	encrypted, err := testMasterKeys(t, "1").encryptValue("tanjun_app_secrets", "value")
+	assert.NoError(t, err)
+
+	_, err = kv.decrypt("tanjun_app_secrets", encrypted)
+	assert.ErrorContains(t, err, "run tanjun setup")
+}
+
+func TestMergeMasterKeys(t *testing.T) {
+	existing := testMasterKeys(t, "2")
+	imported := testMasterKeys(t, "1")
+
+	merged := mergeMasterKeys(existing, imported)
+
+	assert.Equal(t, "2", merged.Current)
+	assert.Len(t, merged.Keys, 2)
+
+	encrypted, err := imported.encryptValue("tanjun_app_secrets", "value")
+	assert.NoError(t, err)
+
+	decrypted, err := merged.decryptValue("tanjun_app_secrets", encrypted)
+	assert.NoError(t, err)
+	assert.Equal(t, "value", decrypted)
+
+	assert.Equal(t, imported, mergeMasterKeys(nil, imported))
+}
+
+func TestParseMasterKeys(t *testing.T) {
+	keys, err := parseMasterKeys([]byte(`{"current":"1","keys":{"1":"AAAA"}}`))
+	assert.NoError(t, err)
+	assert.Equal(t, "1", keys.Current)
+
+	_, err = parseMasterKeys([]byte(`{"current":"2","keys":{"1":"AAAA"}}`))
+	assert.ErrorContains(t, err, "current master key 2 is missing")
+
+	_, err = parseMasterKeys([]byte(`not json`))
+	assert.ErrorContains(t, err, "could not parse master key file")
+}
@@ -14,13 +14,13 @@ import (
 	"github.com/docker/go-connections/nat"
 )
 
-const kamalImage = "ghcr.io/shyim/tanjun/kamal-proxy:v0.8.7"
+const kamalImage = "ghcr.io/shyim/tanjun/kamal-proxy:v0.9.0"
 const kamalContainerName = "tanjun-proxy"
 const kamalVolumeName = "tanjun-kamal-certs"
 const kamalNetworkName = "tanjun-public"
 const tanjunKVContainerName = "tanjun-kv"
 const tanjunKVVolumeName = "tanjun-kv"
-const tanjunKVImage = "ghcr.io/shyim/tanjun/kv-store:v1"
+const tanjunKVImage = "ghcr.io/shyim/tanjun/kv-store:v3"
 
 func ConfigureServer(ctx context.Context, client *client.Client) error {
 	var sideGroup errgroup.Group
@@ -33,6 +33,10 @@ func ConfigureServer(ctx context.Context, client *client.Client) error {
 		return createVolume(ctx, client, tanjunKVVolumeName)
 	})
 
+	sideGroup.Go(func() error {
+		return createVolume(ctx, client, tanjunKVKeyVolumeName)
+	})
+
 	sideGroup.Go(func() error {
 		return createPublicNetwork(ctx, client)
 	})
@@ -55,7 +59,11 @@ func ConfigureServer(ctx context.Context, client *client.Client) error {
 		return createSysctlContainer(ctx, client)
 	})
 
-	return containerGroup.Wait()
+	if err := containerGroup.Wait(); err != nil {
+		return err
+	}
+
+	return ensureMasterKey(ctx, client)
 }
 
 func createKeyValueContainer(ctx context.Context, c *client.Client) error {
@@ -73,7 +81,7 @@ func createKeyValueContainer(ctx context.Context, c *client.Client) error {
 	}
 
 	if len(containers) == 1 {
-		if containers[0].Image == tanjunKVImage {
+		if containers[0].Image == tanjunKVImage && hasMasterKeyMount(containers[0]) {
 			return nil
 		}
 
@@ -95,7 +103,10 @@ func createKeyValueContainer(ctx context.Context, c *client.Client) error {
 	}
 
 	hostCfg := &container.HostConfig{
-		Mounts: []mount.Mount{{Type: "volume", Source: tanjunKVVolumeName, Target: "/data"}},
+		Mounts: []mount.Mount{
+			{Type: "volume", Source: tanjunKVVolumeName, Target: "/data"},
+			{Type: "volume", Source: tanjunKVKeyVolumeName, Target: masterKeysDir},
+		},
 		RestartPolicy: container.RestartPolicy{
 			Name: container.RestartPolicyUnlessStopped,
 		},
@@ -3,7 +3,13 @@ package docker
 import (
 	"context"
 	"fmt"
-	"sync"
+	"io"
+	"maps"
+	"net"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/invopop/jsonschema"
@@ -12,15 +18,17 @@ import (
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/mount"
 	"github.com/docker/docker/api/types/network"
 	"github.com/docker/docker/api/types/volume"
 	"github.com/docker/docker/client"
+	"github.com/docker/go-connections/nat"
 	"github.com/shyim/tanjun/internal/config"
 	"golang.org/x/sync/errgroup"
 )
 
 type AppService interface {
-	Deploy(ctx context.Context, client *client.Client, serviceName string, deployCfg DeployConfiguration, existingContainer *container.InspectResponse) error
+	Plan(ctx context.Context, serviceName string, deployCfg DeployConfiguration, existingContainer *container.InspectResponse) (*ServicePlan, error)
 	AttachInfo(serviceName string, serviceConfig config.ProjectService) interface{}
 	Validate(serviceName string, serviceConfig config.ProjectService) error
 	SupportedTypes() []string
@@ -29,6 +37,184 @@ type AppService interface {
 
 var allServices []AppService
 
+const (
+	ServiceActionKeep     = "keep"
+	ServiceActionCreate   = "create"
+	ServiceActionRecreate = "recreate"
+)
+
+// ServicePlan is the decision what happens with a service container on deploy. Planning never changes anything, apply does
+type ServicePlan struct {
+	Name   string
+	Image  string
+	Action string
+	Reason string
+
+	containerName     string
+	containerCfg      *container.Config
+	hostCfg           *container.HostConfig
+	networkCfg        *network.NetworkingConfig
+	existingContainer *container.InspectResponse
+	upgrade           *ServiceUpgrade
+	// setupCommands are executed in the running container on every deployment, they have to be idempotent
+	setupCommands [][]string
+	// route publishes the service through kamal-proxy
+	route *serviceRoute
+}
+
+func newServicePlan(name, containerName string, containerCfg *container.Config, hostCfg *container.HostConfig, networkCfg *network.NetworkingConfig, existingContainer *container.InspectResponse) *ServicePlan {
+	plan := &ServicePlan{
+		Name:              name,
+		Image:             containerCfg.Image,
+		Action:            ServiceActionKeep,
+		containerName:     containerName,
+		containerCfg:      containerCfg,
+		hostCfg:           hostCfg,
+		networkCfg:        networkCfg,
+		existingContainer: existingContainer,
+	}
+
+	if existingContainer == nil {
+		plan.Action = ServiceActionCreate
+		plan.Reason = "not deployed yet"
+	}
+
+	return plan
+}
+
+// recreate marks an existing container to be replaced
+func (p *ServicePlan) recreate(reason string) {
+	if p.existingContainer == nil {
+		return
+	}
+
+	p.Action = ServiceActionRecreate
+	p.Reason = reason
+}
+
+// Volumes returns the names of the docker volumes mounted into the service container
+func (p *ServicePlan) Volumes() []string {
+	var volumes []string
+
+	for _, m := range p.hostCfg.Mounts {
+		if m.Type == mount.TypeVolume {
+			volumes = append(volumes, m.Source)
+		}
+	}
+
+	return volumes
+}
+
+func (p *ServicePlan) apply(ctx context.Context, client *client.Client) error {
+	if err := p.applyContainer(ctx, client); err != nil {
+		return err
+	}
+
+	for _, cmd := range p.setupCommands {
+		if err := execServiceCommand(ctx, client, p.containerName, cmd, nil, io.Discard); err != nil {
+			return fmt.Errorf("failed to set up service %s: %w", p.Name, err)
+		}
+	}
+
+	return nil
+}
+
+func (p *ServicePlan) applyContainer(ctx context.Context, client *client.Client) error {
+	switch p.Action {
+	case ServiceActionKeep:
+		return nil
+	case ServiceActionRecreate:
+		if p.upgrade != nil {
+			return p.applyUpgrade(ctx, client)
+		}
+
+		if err := stopAndRemoveContainer(ctx, client, p.existingContainer.ID); err != nil {
+			return fmt.Errorf("failed to stop and remove service %s (id: %s): %w", p.Name, p.existingContainer.ID, err)
+		}
+	}
+
+	return startService(ctx, client, p.Name, p.containerName, p.containerCfg, p.hostCfg, p.networkCfg)
+}
+
+// envWithPrefix returns the sorted environment variables starting with the prefix, used to detect changed settings passed as environment
+func envWithPrefix(env []string, prefix string) []string {
+	var filtered []string
+
+	for _, value := range env {
+		if strings.HasPrefix(value, prefix) {
+			filtered = append(filtered, value)
+		}
+	}
+
+	slices.Sort(filtered)
+
+	return filtered
+}
+
+// serviceSecretName returns the name of a stored secret holding a generated credential of a service like MINIO_STORAGE_ACCESS_KEY
+func serviceSecretName(serviceType, serviceName, suffix string) string {
+	return strings.ToUpper(serviceType + "_" + strings.ReplaceAll(serviceName, "-", "_") + "_" + suffix)
+}
+
+// serviceSecret returns a credential of a service from the stored secrets, it is generated and stored on the first deployment
+func serviceSecret(deployCfg DeployConfiguration, key string, length int) (string, error) {
+	if value, ok := deployCfg.storedSecrets[key]; ok {
+		return value, nil
+	}
+
+	value := randomString(length)
+	deployCfg.storedSecrets[key] = value
+
+	if deployCfg.dryRun {
+		return value, nil
+	}
+
+	if err := SetProjectSecrets(deployCfg.storage, deployCfg.Name, map[string]string{key: value}); err != nil {
+		return "", err
+	}
+
+	return value, nil
+}
+
+// SecretGeneratingService is implemented by services whose credentials are generated with serviceSecret on the first deployment
+type SecretGeneratingService interface {
+	// GeneratedSecrets returns the names of the stored secrets holding the credentials
+	GeneratedSecrets(serviceName string, serviceConfig config.ProjectService) []string
+}
+
+// GeneratedProjectSecrets returns the names of the stored secrets created by tanjun, like the initial secrets and service credentials.
+// They are not part of a dotenv file usually, but the data of the services depends on them
+func GeneratedProjectSecrets(cfg *config.ProjectConfig) []string {
+	var names []string
+
+	for key := range cfg.App.InitialSecrets {
+		names = append(names, key)
+	}
+
+	for serviceName, serviceConfig := range cfg.Services {
+		if svc, err := newService(serviceConfig.Type, serviceConfig); err == nil {
+			if generating, ok := svc.(SecretGeneratingService); ok {
+				names = append(names, generating.GeneratedSecrets(serviceName, serviceConfig)...)
+			}
+		}
+
+		if serviceConfig.Expose != nil && serviceConfig.Expose.BasicAuth != nil {
+			names = append(names, serviceSecretName("service", serviceName, "basic_auth_password"))
+		}
+	}
+
+	slices.Sort(names)
+
+	return names
+}
+
+// addServiceInfo adds a value unknown to AttachInfo, like a generated credential, to the info of the service usable in expressions
+func addServiceInfo(deployCfg DeployConfiguration, serviceName, key, value string) {
+	if info, ok := deployCfg.serviceConfig[serviceName].(map[string]interface{}); ok {
+		info[key] = value
+	}
+}
+
 func GetAllServices() []AppService {
 	return allServices
 }
@@ -74,10 +260,45 @@ func validateServices(deployCfg DeployConfiguration) error {
 }
 
 func startServices(ctx context.Context, client *client.Client, deployCfg DeployConfiguration) error {
-	if err := validateServices(deployCfg); err != nil {
+	plans, err := planServices(ctx, client, deployCfg)
+
+	if err != nil {
+		return err
+	}
+
+	for _, plan := range plans {
+		if plan.upgrade != nil && !deployCfg.ProjectConfig.AllowServiceUpgrade {
+			return fmt.Errorf("service %s would be upgraded from version %s to %s (%s), deploy with --allow-service-upgrade to run it", plan.Name, plan.upgrade.From, plan.upgrade.To, plan.upgrade.Strategy)
+		}
+	}
+
+	var wg errgroup.Group
+
+	for _, plan := range plans {
+		wg.Go(func() error {
+			return plan.apply(ctx, client)
+		})
+	}
+
+	if err := wg.Wait(); err != nil {
 		return err
 	}
 
+	for _, plan := range plans {
+		if err := plan.applyRoute(ctx, client, deployCfg); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// planServices determines for every configured service if the container has to be created, recreated or can be kept
+func planServices(ctx context.Context, client *client.Client, deployCfg DeployConfiguration) ([]*ServicePlan, error) {
+	if err := validateServices(deployCfg); err != nil {
+		return nil, err
+	}
+
 	options := container.ListOptions{
 		Filters: filters.NewArgs(),
 		All:     true,
@@ -89,64 +310,67 @@ func startServices(ctx context.Context, client *client.Client, deployCfg DeployC
 	containers, err := client.ContainerList(ctx, options)
 
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	var wg errgroup.Group
-	var configLock sync.Mutex
+	services := make(map[string]AppService)
 
 	for serviceName, serviceConfig := range deployCfg.ProjectConfig.Services {
-		wg.Go(func(serviceName string, serviceConfig config.ProjectService) func() error {
-			return func() error {
-				svc, err := newService(deployCfg.ProjectConfig.Services[serviceName].Type, serviceConfig)
+		svc, err := newService(serviceConfig.Type, serviceConfig)
+
+		if err != nil {
+			return nil, err
+		}
 
-				if err != nil {
-					return err
-				}
+		services[serviceName] = svc
+		deployCfg.serviceConfig[serviceName] = svc.AttachInfo(serviceName, serviceConfig)
+	}
 
-				configLock.Lock()
-				deployCfg.serviceConfig[serviceName] = svc.AttachInfo(serviceName, serviceConfig)
-				configLock.Unlock()
+	plans := make([]*ServicePlan, 0, len(services))
 
-				var containerId string
+	for _, serviceName := range slices.Sorted(maps.Keys(services)) {
+		var existingContainer *container.InspectResponse
 
-				for _, c := range containers {
-					if c.Labels["tanjun.service"] == serviceName {
-						containerId = c.ID
-						break
-					}
-				}
+		for _, c := range containers {
+			if c.Labels["tanjun.service"] != serviceName {
+				continue
+			}
 
-				var existingContainer *container.InspectResponse
+			inspect, err := client.ContainerInspect(ctx, c.ID)
 
-				if containerId != "" {
-					c, err := client.ContainerInspect(ctx, containerId)
-					if err != nil {
-						return err
-					}
+			if err != nil {
+				return nil, err
+			}
 
-					existingContainer = &c
-				}
+			existingContainer = &inspect
+			break
+		}
 
-				if err := svc.Deploy(ctx, client, serviceName, deployCfg, existingContainer); err != nil {
-					return err
-				}
+		plan, err := services[serviceName].Plan(ctx, serviceName, deployCfg, existingContainer)
 
-				return nil
-			}
-		}(serviceName, serviceConfig))
+		if err != nil {
+			return nil, err
+		}
+
+		planPublishedPort(plan)
+
+		if err := planServiceExpose(deployCfg, serviceName, plan); err != nil {
+			return nil, err
+		}
+
+		plans = append(plans, plan)
 	}
 
-	return wg.Wait()
+	return plans, nil
 }
 
-func getDefaultServiceContainers(ctx context.Context, cfg DeployConfiguration, name string) (string, *container.Config, *network.NetworkingConfig, *container.HostConfig) {
+func getDefaultServiceContainers(ctx context.Context, cfg DeployConfiguration, name string) (string, *container.Config, *network.NetworkingConfig, *container.HostConfig, error) {
 	containerName := fmt.Sprintf("%s_%s", cfg.ContainerPrefix(), name)
 
 	envValues, err := getEnvironmentVariables(ctx, cfg, cfg.ProjectConfig.Services[name].Environment, cfg.ProjectConfig.Services[name].Secrets, make(map[string]config.ProjectInitialSecrets))
 
 	if err != nil {
-		return "", nil, nil, nil
+		return "", nil, nil, nil, fmt.Errorf("could not resolve the environment of service %s: %w", name, err)
 	}
 
 	envValuesList := []string{}
@@ -167,6 +391,8 @@ func getDefaultServiceContainers(ctx context.Context, cfg DeployConfiguration, n
 		},
 	}
 
+	cfg.addEnvironmentLabel(containerCfg.Labels)
+
 	networkCfg := &network.NetworkingConfig{
 		EndpointsConfig: map[string]*network.EndpointSettings{
 			cfg.Name: {
@@ -181,7 +407,64 @@ func getDefaultServiceContainers(ctx context.Context, cfg DeployConfiguration, n
 		},
 	}
 
-	return containerName, containerCfg, networkCfg, hostCfg
+	if len(cfg.ProjectConfig.Servers) > 0 {
+		publishServicePort(cfg, name, containerCfg, hostCfg)
+	}
+
+	return containerName, containerCfg, networkCfg, hostCfg, nil
+}
+
+// servicePort returns the port the service listens on, empty when the service has no port in its attach info
+func servicePort(name string, serviceConfig config.ProjectService) string {
+	svc, err := newService(serviceConfig.Type, serviceConfig)
+
+	if err != nil {
+		return ""
+	}
+
+	attachInfo, ok := svc.AttachInfo(name, serviceConfig).(map[string]interface{})
+
+	if !ok {
+		return ""
+	}
+
+	port, _ := attachInfo["port"].(string)
+
+	return port
+}
+
+// publishServicePort makes the service reachable for app containers running on the other servers.
+// It is only published on the private address, the config validation refuses multiple servers without one
+func publishServicePort(cfg DeployConfiguration, name string, containerCfg *container.Config, hostCfg *container.HostConfig) {
+	serviceConfig := cfg.ProjectConfig.Services[name]
+	hostIP := cfg.ProjectConfig.Server.PrivateAddress
+	port := servicePort(name, serviceConfig)
+
+	if port == "" || serviceConfig.HostPort == 0 || net.ParseIP(hostIP) == nil {
+		return
+	}
+
+	containerPort := nat.Port(port + "/tcp")
+
+	containerCfg.ExposedPorts = nat.PortSet{containerPort: struct{}{}}
+	hostCfg.PortBindings = nat.PortMap{containerPort: []nat.PortBinding{{HostIP: hostIP, HostPort: strconv.Itoa(serviceConfig.HostPort)}}}
+}
+
+// planPublishedPort recreates the service when the published port changed
+func planPublishedPort(plan *ServicePlan) {
+	if plan.Action != ServiceActionKeep || plan.existingContainer == nil || plan.existingContainer.HostConfig == nil {
+		return
+	}
+
+	existing := plan.existingContainer.HostConfig.PortBindings
+
+	if len(existing) == 0 && len(plan.hostCfg.PortBindings) == 0 {
+		return
+	}
+
+	if !reflect.DeepEqual(existing, plan.hostCfg.PortBindings) {
+		plan.recreate("published port changed")
+	}
 }
 
 func startService(ctx context.Context, client *client.Client, name, containerName string, containerCfg *container.Config, hostCfg *container.HostConfig, networkCfg *network.NetworkingConfig) error {
@@ -281,7 +564,7 @@ type ProjectServiceInfo struct {
 func ProjectListServices(ctx context.Context, client *client.Client, cfg *config.ProjectConfig) (ProjectServiceList, error) {
 	opts := container.ListOptions{Filters: filters.NewArgs(), All: true}
 
-	opts.Filters.Add("label", fmt.Sprintf("tanjun.project=%s", cfg.Name))
+	opts.Filters.Add("label", fmt.Sprintf("tanjun.project=%s", cfg.Identifier()))
 	opts.Filters.Add("label", "tanjun.service")
 
 	containers, err := client.ContainerList(ctx, opts)
@@ -320,7 +603,7 @@ func ProjectListServices(ctx context.Context, client *client.Client, cfg *config
 func ProjectDeleteService(ctx context.Context, client *client.Client, cfg *config.ProjectConfig, serviceName string) error {
 	opts := container.ListOptions{Filters: filters.NewArgs(), All: true}
 
-	opts.Filters.Add("label", fmt.Sprintf("tanjun.project=%s", cfg.Name))
+	opts.Filters.Add("label", fmt.Sprintf("tanjun.project=%s", cfg.Identifier()))
 	opts.Filters.Add("label", fmt.Sprintf("tanjun.service=%s", serviceName))
 
 	containers, err := client.ContainerList(ctx, opts)
@@ -329,6 +612,10 @@ func ProjectDeleteService(ctx context.Context, client *client.Client, cfg *confi
 		return err
 	}
 
+	if err := removeServiceRoutes(ctx, client, containers); err != nil {
+		return err
+	}
+
 	for _, c := range containers {
 		if err := client.ContainerKill(ctx, c.ID, "SIGKILL"); err != nil {
 			return err
@@ -340,7 +627,7 @@ func ProjectDeleteService(ctx context.Context, client *client.Client, cfg *confi
 	}
 
 	volumeOpts := volume.ListOptions{Filters: filters.NewArgs()}
-	volumeOpts.Filters.Add("label", fmt.Sprintf("tanjun.project=%s", cfg.Name))
+	volumeOpts.Filters.Add("label", fmt.Sprintf("tanjun.project=%s", cfg.Identifier()))
 	volumeOpts.Filters.Add("label", fmt.Sprintf("tanjun.service=%s", serviceName))
 
 	volumes, err := client.VolumeList(ctx, volumeOpts)
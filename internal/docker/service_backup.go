@@ -0,0 +1,193 @@
+package docker
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/shyim/tanjun/internal/config"
+)
+
+// BackupableService is implemented by services whose data can be dumped and restored.
+// Both commands are executed in the running service container
+type BackupableService interface {
+	// BackupCommand writes an uncompressed dump of the service data to stdout
+	BackupCommand(serviceName string, serviceConfig config.ProjectService) []string
+	// RestoreCommand reads a dump created by BackupCommand from stdin
+	RestoreCommand(serviceName string, serviceConfig config.ProjectService) []string
+}
+
+func getBackupableService(projectConfig *config.ProjectConfig, serviceName string) (BackupableService, config.ProjectService, error) {
+	serviceConfig, ok := projectConfig.Services[serviceName]
+
+	if !ok {
+		return nil, serviceConfig, fmt.Errorf("service %s is not defined in the config", serviceName)
+	}
+
+	svc, err := newService(serviceConfig.Type, serviceConfig)
+
+	if err != nil {
+		return nil, serviceConfig, err
+	}
+
+	backupable, ok := svc.(BackupableService)
+
+	if !ok {
+		return nil, serviceConfig, fmt.Errorf("service %s of type %s does not support backups", serviceName, serviceConfig.Type)
+	}
+
+	return backupable, serviceConfig, nil
+}
+
+func findServiceContainer(ctx context.Context, client *client.Client, projectName, serviceName string) (string, error) {
+	opts := container.ListOptions{Filters: filters.NewArgs()}
+
+	opts.Filters.Add("label", fmt.Sprintf("tanjun.project=%s", projectName))
+	opts.Filters.Add("label", fmt.Sprintf("tanjun.service=%s", serviceName))
+
+	containers, err := client.ContainerList(ctx, opts)
+
+	if err != nil {
+		return "", err
+	}
+
+	if len(containers) == 0 {
+		return "", fmt.Errorf("service %s is not running", serviceName)
+	}
+
+	return containers[0].ID, nil
+}
+
+// BackupService writes a gzip compressed dump of the service to the writer
+func BackupService(ctx context.Context, client *client.Client, projectConfig *config.ProjectConfig, serviceName string, w io.Writer) error {
+	svc, serviceConfig, err := getBackupableService(projectConfig, serviceName)
+
+	if err != nil {
+		return err
+	}
+
+	containerID, err := findServiceContainer(ctx, client, projectConfig.Identifier(), serviceName)
+
+	if err != nil {
+		return err
+	}
+
+	compressed := gzip.NewWriter(w)
+
+	if err := execServiceCommand(ctx, client, containerID, svc.BackupCommand(serviceName, serviceConfig), nil, compressed); err != nil {
+		return fmt.Errorf("backup of service %s failed: %w", serviceName, err)
+	}
+
+	return compressed.Close()
+}
+
+// RestoreService replaces the data of the service with the dump read from the reader, gzip compressed dumps are decompressed
+func RestoreService(ctx context.Context, client *client.Client, projectConfig *config.ProjectConfig, serviceName string, r io.Reader) error {
+	svc, serviceConfig, err := getBackupableService(projectConfig, serviceName)
+
+	if err != nil {
+		return err
+	}
+
+	containerID, err := findServiceContainer(ctx, client, projectConfig.Identifier(), serviceName)
+
+	if err != nil {
+		return err
+	}
+
+	dump, err := maybeDecompress(r)
+
+	if err != nil {
+		return err
+	}
+
+	if err := execServiceCommand(ctx, client, containerID, svc.RestoreCommand(serviceName, serviceConfig), dump, io.Discard); err != nil {
+		return fmt.Errorf("restore of service %s failed: %w", serviceName, err)
+	}
+
+	return nil
+}
+
+func maybeDecompress(r io.Reader) (io.Reader, error) {
+	buffered := bufio.NewReader(r)
+
+	magic, err := buffered.Peek(2)
+
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	if len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		return gzip.NewReader(buffered)
+	}
+
+	return buffered, nil
+}
+
+// execServiceCommand runs the command in the container, stdin is streamed into the command and its stdout into the writer
+func execServiceCommand(ctx context.Context, client *client.Client, containerID string, cmd []string, stdin io.Reader, stdout io.Writer) error {
+	exec, err := client.ContainerExecCreate(ctx, containerID, container.ExecOptions{
+		Cmd:          cmd,
+		AttachStdin:  stdin != nil,
+		AttachStdout: true,
+		AttachStderr: true,
+	})
+
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.ContainerExecAttach(ctx, exec.ID, container.ExecAttachOptions{})
+
+	if err != nil {
+		return err
+	}
+
+	defer resp.Close()
+
+	copyErr := make(chan error, 1)
+
+	if stdin != nil {
+		go func() {
+			_, err := io.Copy(resp.Conn, stdin)
+
+			if closeErr := resp.CloseWrite(); err == nil {
+				err = closeErr
+			}
+
+			copyErr <- err
+		}()
+	} else {
+		copyErr <- nil
+	}
+
+	var stderr bytes.Buffer
+
+	if _, err := stdcopy.StdCopy(stdout, &stderr, resp.Reader); err != nil {
+		return err
+	}
+
+	if err := <-copyErr; err != nil {
+		return err
+	}
+
+	inspect, err := client.ContainerExecInspect(ctx, exec.ID)
+
+	if err != nil {
+		return err
+	}
+
+	if inspect.ExitCode != 0 {
+		return fmt.Errorf("command exited with status code %d: %s", inspect.ExitCode, strings.TrimSpace(stderr.String()))
+	}
+
+	return nil
+}
@@ -0,0 +1,279 @@
+package docker
+
+import (
+	"archive/tar"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"math/rand/v2"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/client"
+	"github.com/shyim/tanjun/internal/config"
+)
+
+// backupSchedulerLabel marks the scheduler container dumping the services, it runs on the server of the services
+const backupSchedulerLabel = "tanjun.backup"
+
+// ServiceBackup is a dump stored in the backups volume on the server
+type ServiceBackup struct {
+	Service   string    `json:"service"`
+	Name      string    `json:"name"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type backupSchedulerJob struct {
+	Name     string             `json:"name"`
+	Schedule string             `json:"schedule"`
+	Backup   backupSchedulerRun `json:"backup"`
+}
+
+type backupSchedulerRun struct {
+	Service   string   `json:"service"`
+	Container string   `json:"container"`
+	Command   []string `json:"command"`
+	Keep      int      `json:"keep"`
+	MaxAge    int      `json:"max_age"`
+}
+
+func getBackupSchedulerJobs(deployCfg DeployConfiguration) ([]backupSchedulerJob, error) {
+	var jobs []backupSchedulerJob
+
+	for _, serviceName := range slices.Sorted(maps.Keys(deployCfg.ProjectConfig.Services)) {
+		serviceConfig := deployCfg.ProjectConfig.Services[serviceName]
+
+		if serviceConfig.Backup == nil {
+			continue
+		}
+
+		svc, _, err := getBackupableService(deployCfg.ProjectConfig, serviceName)
+
+		if err != nil {
+			return nil, err
+		}
+
+		jobs = append(jobs, backupSchedulerJob{
+			Name:     "backup-" + serviceName,
+			Schedule: serviceConfig.Backup.Schedule,
+			Backup: backupSchedulerRun{
+				Service:   serviceName,
+				Container: fmt.Sprintf("%s_%s", deployCfg.ContainerPrefix(), serviceName),
+				Command:   svc.BackupCommand(serviceName, serviceConfig),
+				Keep:      serviceConfig.Backup.Keep,
+				MaxAge:    serviceConfig.Backup.MaxAge,
+			},
+		})
+	}
+
+	return jobs, nil
+}
+
+func getBackupSchedulerContainers(ctx context.Context, client *client.Client, projectName string) ([]container.Summary, error) {
+	options := container.ListOptions{
+		Filters: filters.NewArgs(),
+		All:     true,
+	}
+
+	options.Filters.Add("label", fmt.Sprintf("tanjun.project=%s", projectName))
+	options.Filters.Add("label", backupSchedulerLabel+"=scheduler")
+
+	return client.ContainerList(ctx, options)
+}
+
+// startBackupScheduler replaces the scheduler running the backups of the services when its configuration changed, it is
+// removed when no service has a backup schedule
+func startBackupScheduler(ctx context.Context, client *client.Client, deployCfg DeployConfiguration) error {
+	jobs, err := getBackupSchedulerJobs(deployCfg)
+
+	if err != nil {
+		return err
+	}
+
+	existing, err := getBackupSchedulerContainers(ctx, client, deployCfg.Name)
+
+	if err != nil {
+		return err
+	}
+
+	if len(jobs) == 0 {
+		return removeContainers(ctx, client, existing)
+	}
+
+	schedulerConfig, err := json.Marshal(map[string]interface{}{"jobs": jobs})
+
+	if err != nil {
+		return err
+	}
+
+	if err := PullImageIfNotThere(ctx, client, schedulerImage); err != nil {
+		return err
+	}
+
+	cfg := &container.Config{
+		Image: schedulerImage,
+		Labels: map[string]string{
+			"com.docker.compose.project": deployCfg.ContainerPrefix(),
+			"com.docker.compose.service": "backup",
+			"tanjun":                     "true",
+			"tanjun.project":             deployCfg.Name,
+			backupSchedulerLabel:         "scheduler",
+		},
+		Env: []string{"SCHEDULER_CONFIG=" + string(schedulerConfig)},
+	}
+
+	deployCfg.addEnvironmentLabel(cfg.Labels)
+
+	hostCfg := &container.HostConfig{
+		RestartPolicy: container.RestartPolicy{
+			Name: container.RestartPolicyUnlessStopped,
+		},
+		Mounts: []mount.Mount{
+			{
+				Type:   mount.TypeBind,
+				Source: "/var/run/docker.sock",
+				Target: "/var/run/docker.sock",
+			},
+			{
+				Type:   mount.TypeVolume,
+				Source: deployCfg.ContainerPrefix() + "_backups",
+				Target: "/backups",
+				VolumeOptions: &mount.VolumeOptions{
+					Labels: map[string]string{
+						"tanjun":         "true",
+						"tanjun.project": deployCfg.Name,
+					},
+				},
+			},
+			{
+				Type:   mount.TypeVolume,
+				Source: deployCfg.ContainerPrefix() + "_backup_scheduler_data",
+				Target: "/data",
+				VolumeOptions: &mount.VolumeOptions{
+					Labels: map[string]string{
+						"tanjun":         "true",
+						"tanjun.project": deployCfg.Name,
+					},
+				},
+			},
+		},
+	}
+
+	hash, err := customServiceHash(cfg, hostCfg)
+
+	if err != nil {
+		return err
+	}
+
+	cfg.Labels[customServiceHashLabel] = hash
+
+	// Recreating the scheduler would interrupt a running backup, keep it when nothing changed
+	if len(existing) == 1 && existing[0].State == container.StateRunning && existing[0].Labels[customServiceHashLabel] == hash {
+		return nil
+	}
+
+	if err := removeContainers(ctx, client, existing); err != nil {
+		return err
+	}
+
+	c, err := client.ContainerCreate(ctx, cfg, hostCfg, nil, nil, fmt.Sprintf("%s-backup-%d", deployCfg.ContainerPrefix(), rand.IntN(1000000)))
+
+	if err != nil {
+		return err
+	}
+
+	return client.ContainerStart(ctx, c.ID, container.StartOptions{})
+}
+
+func findBackupScheduler(ctx context.Context, client *client.Client, projectConfig *config.ProjectConfig) (string, error) {
+	containers, err := getBackupSchedulerContainers(ctx, client, projectConfig.Identifier())
+
+	if err != nil {
+		return "", err
+	}
+
+	for _, c := range containers {
+		if c.State == container.StateRunning {
+			return c.ID, nil
+		}
+	}
+
+	return "", fmt.Errorf("no backup scheduler found for project %s, configure services.<name>.backup and deploy", projectConfig.Identifier())
+}
+
+// ListServiceBackups returns the stored backups of the service, newest first
+func ListServiceBackups(ctx context.Context, client *client.Client, projectConfig *config.ProjectConfig, serviceName string) ([]ServiceBackup, error) {
+	containerID, err := findBackupScheduler(ctx, client, projectConfig)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var output strings.Builder
+
+	if err := execServiceCommand(ctx, client, containerID, []string{"/scheduler", "backups", serviceName}, nil, &output); err != nil {
+		return nil, fmt.Errorf("could not list backups: %w", err)
+	}
+
+	var backups []ServiceBackup
+
+	if err := json.Unmarshal([]byte(output.String()), &backups); err != nil {
+		return nil, fmt.Errorf("could not parse backups: %w", err)
+	}
+
+	return backups, nil
+}
+
+// DownloadServiceBackup writes the gzip compressed backup to the writer
+func DownloadServiceBackup(ctx context.Context, client *client.Client, projectConfig *config.ProjectConfig, serviceName, name string, w io.Writer) error {
+	if path.Base(name) != name || path.Base(serviceName) != serviceName {
+		return fmt.Errorf("invalid backup %s/%s", serviceName, name)
+	}
+
+	containerID, err := findBackupScheduler(ctx, client, projectConfig)
+
+	if err != nil {
+		return err
+	}
+
+	reader, _, err := client.CopyFromContainer(ctx, containerID, path.Join("/backups", serviceName, name))
+
+	if err != nil {
+		return fmt.Errorf("could not download backup %s of service %s: %w", name, serviceName, err)
+	}
+
+	defer reader.Close()
+
+	archive := tar.NewReader(reader)
+
+	if _, err := archive.Next(); err != nil {
+		return err
+	}
+
+	_, err = io.Copy(w, archive)
+
+	return err
+}
+
+// RestoreServiceBackup restores a backup stored on the server into the service
+func RestoreServiceBackup(ctx context.Context, client *client.Client, projectConfig *config.ProjectConfig, serviceName, name string) error {
+	pr, pw := io.Pipe()
+
+	go func() {
+		pw.CloseWithError(DownloadServiceBackup(ctx, client, projectConfig, serviceName, name, pw))
+	}()
+
+	err := RestoreService(ctx, client, projectConfig, serviceName, pr)
+
+	_ = pr.Close()
+
+	return err
+}
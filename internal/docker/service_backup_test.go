@@ -0,0 +1,86 @@
+package docker
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"testing"
+
+	"github.com/shyim/tanjun/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetBackupableService(t *testing.T) {
+	projectConfig := &config.ProjectConfig{
+		Services: map[string]config.ProjectService{
+			"database": {Type: "postgres:16"},
+			"cache":    {Type: "valkey:7.2"},
+		},
+	}
+
+	svc, _, err := getBackupableService(projectConfig, "database")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "pg_dump", svc.BackupCommand("database", projectConfig.Services["database"])[0])
+
+	_, _, err = getBackupableService(projectConfig, "cache")
+
+	assert.ErrorContains(t, err, "does not support backups")
+
+	_, _, err = getBackupableService(projectConfig, "missing")
+
+	assert.ErrorContains(t, err, "is not defined")
+}
+
+func TestMaybeDecompress(t *testing.T) {
+	var compressed bytes.Buffer
+
+	w := gzip.NewWriter(&compressed)
+	_, _ = w.Write([]byte("SELECT 1;"))
+	assert.NoError(t, w.Close())
+
+	r, err := maybeDecompress(&compressed)
+
+	assert.NoError(t, err)
+
+	content, err := io.ReadAll(r)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT 1;", string(content))
+
+	r, err = maybeDecompress(bytes.NewBufferString("SELECT 2;"))
+
+	assert.NoError(t, err)
+
+	content, err = io.ReadAll(r)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT 2;", string(content))
+}
+
+func TestGetBackupSchedulerJobs(t *testing.T) {
+	projectConfig := &config.ProjectConfig{
+		Name: "test-project",
+		Services: map[string]config.ProjectService{
+			"database": {Type: "mariadb:11.4", Backup: &config.ProjectServiceBackup{Schedule: "@daily", Keep: 3, MaxAge: 3600}},
+			"other":    {Type: "mysql:8.0"},
+		},
+	}
+
+	jobs, err := getBackupSchedulerJobs(newDeployConfiguration(projectConfig, ""))
+
+	assert.NoError(t, err)
+	assert.Len(t, jobs, 1)
+	assert.Equal(t, "backup-database", jobs[0].Name)
+	assert.Equal(t, "@daily", jobs[0].Schedule)
+	assert.Equal(t, "tanjun_test-project_database", jobs[0].Backup.Container)
+	assert.Equal(t, "mariadb-dump", jobs[0].Backup.Command[0])
+	assert.Equal(t, 3, jobs[0].Backup.Keep)
+	assert.Equal(t, 3600, jobs[0].Backup.MaxAge)
+
+	projectConfig.Services["cache"] = config.ProjectService{Type: "valkey:7.2", Backup: &config.ProjectServiceBackup{Schedule: "@daily"}}
+
+	_, err = getBackupSchedulerJobs(newDeployConfiguration(projectConfig, ""))
+
+	assert.ErrorContains(t, err, "does not support backups")
+}
@@ -5,7 +5,6 @@ import (
 	"fmt"
 
 	"github.com/docker/docker/api/types/container"
-	"github.com/docker/docker/client"
 	"github.com/invopop/jsonschema"
 	"github.com/shyim/tanjun/internal/config"
 )
@@ -13,16 +12,16 @@ import (
 type BlackfireService struct {
 }
 
-func (t BlackfireService) Deploy(ctx context.Context, client *client.Client, serviceName string, deployCfg DeployConfiguration, existingContainer *container.InspectResponse) error {
-	containerName, containerCfg, networkConfig, hostCfg := getDefaultServiceContainers(ctx, deployCfg, serviceName)
+func (t BlackfireService) Plan(ctx context.Context, serviceName string, deployCfg DeployConfiguration, existingContainer *container.InspectResponse) (*ServicePlan, error) {
+	containerName, containerCfg, networkConfig, hostCfg, err := getDefaultServiceContainers(ctx, deployCfg, serviceName)
 
-	containerCfg.Image = "blackfire/blackfire:2"
-
-	if existingContainer != nil {
-		return nil
+	if err != nil {
+		return nil, err
 	}
 
-	return startService(ctx, client, serviceName, containerName, containerCfg, hostCfg, networkConfig)
+	containerCfg.Image = "blackfire/blackfire:2"
+
+	return newServicePlan(serviceName, containerName, containerCfg, hostCfg, networkConfig, existingContainer), nil
 }
 
 func (t BlackfireService) AttachInfo(serviceName string, serviceConfig config.ProjectService) interface{} {
@@ -0,0 +1,175 @@
+package docker
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/go-connections/nat"
+	"github.com/invopop/jsonschema"
+	"github.com/shyim/tanjun/internal/config"
+)
+
+// customServiceHashLabel holds the hash of the configuration the container was created with
+const customServiceHashLabel = "tanjun.config-hash"
+
+var validServiceVolumeName = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+type CustomService struct {
+}
+
+func (c CustomService) Plan(ctx context.Context, serviceName string, deployCfg DeployConfiguration, existingContainer *container.InspectResponse) (*ServicePlan, error) {
+	serviceConfig := deployCfg.ProjectConfig.Services[serviceName]
+	custom := serviceConfig.Custom
+
+	containerName, containerCfg, networkConfig, hostCfg, err := getDefaultServiceContainers(ctx, deployCfg, serviceName)
+
+	if err != nil {
+		return nil, err
+	}
+
+	containerCfg.Image = custom.Image
+	containerCfg.Cmd = custom.Command
+
+	// The environment is built from a map, sort it to get a stable hash
+	slices.Sort(containerCfg.Env)
+
+	if len(custom.Ports) > 0 && containerCfg.ExposedPorts == nil {
+		containerCfg.ExposedPorts = nat.PortSet{}
+	}
+
+	for _, port := range custom.Ports {
+		containerCfg.ExposedPorts[nat.Port(fmt.Sprintf("%d/tcp", port))] = struct{}{}
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(custom.Volumes)) {
+		hostCfg.Mounts = append(hostCfg.Mounts, mount.Mount{
+			Type:   mount.TypeVolume,
+			Source: fmt.Sprintf("%s_%s_%s", deployCfg.ContainerPrefix(), serviceName, name),
+			Target: custom.Volumes[name],
+			VolumeOptions: &mount.VolumeOptions{
+				Labels: map[string]string{
+					"tanjun":         "true",
+					"tanjun.project": deployCfg.Name,
+					"tanjun.service": serviceName,
+				},
+			},
+		})
+	}
+
+	if custom.Healthcheck != nil {
+		containerCfg.Healthcheck = &container.HealthConfig{
+			Test:     []string{"CMD-SHELL", custom.Healthcheck.Command},
+			Interval: time.Duration(custom.Healthcheck.Interval) * time.Second,
+			Retries:  custom.Healthcheck.Retries,
+		}
+	}
+
+	hash, err := customServiceHash(containerCfg, hostCfg)
+
+	if err != nil {
+		return nil, err
+	}
+
+	containerCfg.Labels[customServiceHashLabel] = hash
+
+	plan := newServicePlan(serviceName, containerName, containerCfg, hostCfg, networkConfig, existingContainer)
+
+	if existingContainer != nil && existingContainer.Config.Labels[customServiceHashLabel] != hash {
+		plan.recreate("configuration changed")
+	}
+
+	return plan, nil
+}
+
+// customServiceHash covers everything the user can configure, a change recreates the container
+func customServiceHash(containerCfg *container.Config, hostCfg *container.HostConfig) (string, error) {
+	data, err := json.Marshal(map[string]interface{}{
+		"image":        containerCfg.Image,
+		"cmd":          containerCfg.Cmd,
+		"env":          containerCfg.Env,
+		"exposedPorts": containerCfg.ExposedPorts,
+		"healthcheck":  containerCfg.Healthcheck,
+		"mounts":       hostCfg.Mounts,
+		"portBindings": hostCfg.PortBindings,
+	})
+
+	if err != nil {
+		return "", err
+	}
+
+	hash := sha256.Sum256(data)
+
+	return hex.EncodeToString(hash[:]), nil
+}
+
+func (c CustomService) AttachInfo(serviceName string, serviceConfig config.ProjectService) interface{} {
+	info := map[string]interface{}{
+		"host": serviceName,
+	}
+
+	if len(serviceConfig.Custom.Ports) > 0 {
+		info["port"] = strconv.Itoa(serviceConfig.Custom.Ports[0])
+	}
+
+	return info
+}
+
+func (c CustomService) Validate(serviceName string, serviceConfig config.ProjectService) error {
+	custom := serviceConfig.Custom
+
+	if custom.Image == "" {
+		return fmt.Errorf("services.%s.image is required for custom services", serviceName)
+	}
+
+	if len(serviceConfig.Settings) > 0 {
+		return fmt.Errorf("services.%s: custom services have no settings, use env instead", serviceName)
+	}
+
+	for _, port := range custom.Ports {
+		if port < 1 || port > 65535 {
+			return fmt.Errorf("services.%s.ports: %d is not a valid port", serviceName, port)
+		}
+	}
+
+	for name, target := range custom.Volumes {
+		if !validServiceVolumeName.MatchString(name) {
+			return fmt.Errorf("services.%s.volumes: %s is not a valid volume name", serviceName, name)
+		}
+
+		if !path.IsAbs(target) {
+			return fmt.Errorf("services.%s.volumes.%s: the path %s has to be absolute", serviceName, name, target)
+		}
+	}
+
+	if custom.Healthcheck != nil && custom.Healthcheck.Command == "" {
+		return fmt.Errorf("services.%s.healthcheck.command is required", serviceName)
+	}
+
+	return nil
+}
+
+func (c CustomService) SupportedTypes() []string {
+	return []string{"custom"}
+}
+
+func (c CustomService) ConfigSchema(serviceType string) *jsonschema.Schema {
+	return &jsonschema.Schema{
+		Type:                 "object",
+		AdditionalProperties: jsonschema.FalseSchema,
+	}
+}
+
+func init() {
+	allServices = append(allServices, CustomService{})
+}
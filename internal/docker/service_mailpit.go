@@ -0,0 +1,141 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/invopop/jsonschema"
+	"github.com/shyim/tanjun/internal/config"
+	orderedmap "github.com/wk8/go-ordered-map/v2"
+)
+
+// mailpitUIUser is the basic auth user of the web UI, the password is generated and stored as secret
+const mailpitUIUser = "admin"
+
+var supportedMailpitConfiguration = []string{
+	"ui_host",
+	"max_messages",
+}
+
+type MailpitService struct {
+}
+
+func (m MailpitService) Plan(ctx context.Context, serviceName string, deployCfg DeployConfiguration, existingContainer *container.InspectResponse) (*ServicePlan, error) {
+	serviceConfig := deployCfg.ProjectConfig.Services[serviceName]
+
+	containerName, containerCfg, networkConfig, hostCfg, err := getDefaultServiceContainers(ctx, deployCfg, serviceName)
+
+	if err != nil {
+		return nil, err
+	}
+
+	containerCfg.Image = "axllent/mailpit:v1.21"
+	containerCfg.Env = append(containerCfg.Env,
+		"MP_DATABASE=/data/mailpit.db",
+		"MP_SMTP_AUTH_ACCEPT_ANY=1",
+		"MP_SMTP_AUTH_ALLOW_INSECURE=1",
+	)
+
+	if maxMessages := serviceConfig.Settings["max_messages"]; maxMessages != "" {
+		containerCfg.Env = append(containerCfg.Env, "MP_MAX_MESSAGES="+maxMessages)
+	}
+
+	hostCfg.Mounts = []mount.Mount{
+		{
+			Type:   mount.TypeVolume,
+			Source: fmt.Sprintf("%s_%s_data", deployCfg.ContainerPrefix(), serviceName),
+			Target: "/data",
+			VolumeOptions: &mount.VolumeOptions{
+				Labels: map[string]string{
+					"tanjun":         "true",
+					"tanjun.project": deployCfg.Name,
+					"tanjun.service": serviceName,
+				},
+			},
+		},
+	}
+
+	containerCfg.Healthcheck = &container.HealthConfig{
+		Test: []string{"CMD", "/mailpit", "readyz"},
+	}
+
+	plan := newServicePlan(serviceName, containerName, containerCfg, hostCfg, networkConfig, existingContainer)
+
+	if uiHost := serviceConfig.Settings["ui_host"]; uiHost != "" {
+		password, err := serviceSecret(deployCfg, serviceSecretName("mailpit", serviceName, "ui_password"), 24)
+
+		if err != nil {
+			return nil, err
+		}
+
+		containerCfg.Env = append(containerCfg.Env, fmt.Sprintf("MP_UI_AUTH=%s:%s", mailpitUIUser, password))
+
+		plan.publish(deployCfg, serviceRoute{Host: uiHost, Port: "8025", HealthCheckPath: "/livez"})
+	}
+
+	if existingContainer != nil {
+		if existingContainer.Config.Image != containerCfg.Image {
+			plan.recreate("image changed")
+		} else if slices.Compare(envWithPrefix(existingContainer.Config.Env, "MP_"), envWithPrefix(containerCfg.Env, "MP_")) != 0 {
+			plan.recreate("settings changed")
+		}
+	}
+
+	return plan, nil
+}
+
+func (m MailpitService) GeneratedSecrets(serviceName string, serviceConfig config.ProjectService) []string {
+	if serviceConfig.Settings["ui_host"] == "" {
+		return nil
+	}
+
+	return []string{serviceSecretName("mailpit", serviceName, "ui_password")}
+}
+
+func (m MailpitService) AttachInfo(serviceName string, serviceConfig config.ProjectService) interface{} {
+	return map[string]interface{}{
+		"host": serviceName,
+		"port": "1025",
+		"dsn":  fmt.Sprintf("smtp://%s:1025", serviceName),
+	}
+}
+
+func (m MailpitService) Validate(serviceName string, serviceConfig config.ProjectService) error {
+	for key := range serviceConfig.Settings {
+		if !slices.Contains(supportedMailpitConfiguration, key) {
+			return fmt.Errorf("unsupported mailpit configuration key %s", key)
+		}
+	}
+
+	return nil
+}
+
+func (m MailpitService) SupportedTypes() []string {
+	return []string{"mailpit"}
+}
+
+func (m MailpitService) ConfigSchema(serviceType string) *jsonschema.Schema {
+	properties := orderedmap.New[string, *jsonschema.Schema]()
+
+	properties.Set("ui_host", &jsonschema.Schema{
+		Type:        "string",
+		Description: "Publish the web UI through the proxy on this host, like mail.example.com. It is protected with basic auth, the user is admin and the password is stored as secret MAILPIT_<SERVICE>_UI_PASSWORD.",
+	})
+
+	properties.Set("max_messages", &jsonschema.Schema{
+		Type:        "string",
+		Description: "Maximum number of stored messages, older ones are deleted. 500 by default.",
+	})
+
+	return &jsonschema.Schema{
+		Type:       "object",
+		Properties: properties,
+	}
+}
+
+func init() {
+	allServices = append(allServices, MailpitService{})
+}
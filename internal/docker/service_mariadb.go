@@ -7,7 +7,6 @@ import (
 
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/mount"
-	"github.com/docker/docker/client"
 	"github.com/invopop/jsonschema"
 	"github.com/shyim/tanjun/internal/config"
 	orderedmap "github.com/wk8/go-ordered-map/v2"
@@ -31,12 +30,16 @@ var supportedMariaDBConfiguration = []string{
 type MariaDBService struct {
 }
 
-func (m MariaDBService) Deploy(ctx context.Context, client *client.Client, serviceName string, deployCfg DeployConfiguration, existingContainer *container.InspectResponse) error {
+func (m MariaDBService) Plan(ctx context.Context, serviceName string, deployCfg DeployConfiguration, existingContainer *container.InspectResponse) (*ServicePlan, error) {
 	serviceConfig := deployCfg.ProjectConfig.Services[serviceName]
 
-	containerName, containerCfg, networkConfig, hostCfg := getDefaultServiceContainers(ctx, deployCfg, serviceName)
+	containerName, containerCfg, networkConfig, hostCfg, err := getDefaultServiceContainers(ctx, deployCfg, serviceName)
 
-	containerCfg.Env = append(containerCfg.Env, "MARIADB_ALLOW_EMPTY_ROOT_PASSWORD=yes", "MARIADB_DATABASE=database")
+	if err != nil {
+		return nil, err
+	}
+
+	containerCfg.Env = append(containerCfg.Env, "MARIADB_ALLOW_EMPTY_ROOT_PASSWORD=yes", "MARIADB_DATABASE=database", "MARIADB_AUTO_UPGRADE=1")
 
 	hostCfg.Mounts = []mount.Mount{
 		{
@@ -61,17 +64,17 @@ func (m MariaDBService) Deploy(ctx context.Context, client *client.Client, servi
 		containerCfg.Cmd = append(containerCfg.Cmd, fmt.Sprintf("--%s=%s", key, value))
 	}
 
-	if existingContainer != nil {
-		if slices.Compare(existingContainer.Config.Cmd, containerCfg.Cmd) == 0 {
-			return nil
-		}
+	plan := newServicePlan(serviceName, containerName, containerCfg, hostCfg, networkConfig, existingContainer)
 
-		if err := stopAndRemoveContainer(ctx, client, existingContainer.ID); err != nil {
-			return fmt.Errorf("failed to stop and remove service %s (id: %s): %w", serviceName, existingContainer.ID, err)
-		}
+	if existingContainer != nil && slices.Compare(existingContainer.Config.Cmd, containerCfg.Cmd) != 0 {
+		plan.recreate("settings changed")
 	}
 
-	return startService(ctx, client, serviceName, containerName, containerCfg, hostCfg, networkConfig)
+	if err := plan.planUpgrade(m, serviceConfig); err != nil {
+		return nil, err
+	}
+
+	return plan, nil
 }
 
 func (m MariaDBService) AttachInfo(serviceName string, serviceCfg config.ProjectService) interface{} {
@@ -96,6 +99,27 @@ func (m MariaDBService) Validate(serviceName string, serviceCfg config.ProjectSe
 	return nil
 }
 
+func (m MariaDBService) BackupCommand(serviceName string, serviceCfg config.ProjectService) []string {
+	return []string{"mariadb-dump", "-uroot", "--single-transaction", "--routines", "--triggers", "--events", "database"}
+}
+
+func (m MariaDBService) RestoreCommand(serviceName string, serviceCfg config.ProjectService) []string {
+	return []string{"mariadb", "-uroot", "database"}
+}
+
+func (m MariaDBService) DataVersion(image string, env []string) string {
+	return imageDataVersion(image, env, "MARIADB_VERSION", 2)
+}
+
+// UpgradeStrategy upgrades in-place, the container runs mariadb-upgrade on start because of MARIADB_AUTO_UPGRADE
+func (m MariaDBService) UpgradeStrategy(from, to string) (string, error) {
+	if compareVersions(from, to) > 0 {
+		return "", fmt.Errorf("mariadb does not support downgrades")
+	}
+
+	return ServiceUpgradeInPlace, nil
+}
+
 func (m MariaDBService) SupportedTypes() []string {
 	return []string{"mariadb:10.6", "mariadb:10.11", "mariadb:11.4"}
 }
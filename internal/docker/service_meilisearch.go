@@ -0,0 +1,149 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/invopop/jsonschema"
+	"github.com/shyim/tanjun/internal/config"
+	orderedmap "github.com/wk8/go-ordered-map/v2"
+)
+
+var supportedMeilisearchConfiguration = []string{
+	"max_indexing_memory",
+	"max_indexing_threads",
+	"http_payload_size_limit",
+}
+
+type MeilisearchService struct {
+}
+
+func (m MeilisearchService) Plan(ctx context.Context, serviceName string, deployCfg DeployConfiguration, existingContainer *container.InspectResponse) (*ServicePlan, error) {
+	serviceConfig := deployCfg.ProjectConfig.Services[serviceName]
+
+	masterKey, err := serviceSecret(deployCfg, serviceSecretName("meilisearch", serviceName, "master_key"), 32)
+
+	if err != nil {
+		return nil, err
+	}
+
+	addServiceInfo(deployCfg, serviceName, "api_key", masterKey)
+
+	containerName, containerCfg, networkConfig, hostCfg, err := getDefaultServiceContainers(ctx, deployCfg, serviceName)
+
+	if err != nil {
+		return nil, err
+	}
+
+	containerCfg.Image = "getmeili/" + strings.Replace(serviceConfig.Type, ":", ":v", 1)
+	containerCfg.Env = append(containerCfg.Env,
+		"MEILI_ENV=production",
+		"MEILI_NO_ANALYTICS=true",
+		"MEILI_MASTER_KEY="+masterKey,
+	)
+
+	for _, key := range slices.Sorted(maps.Keys(serviceConfig.Settings)) {
+		containerCfg.Env = append(containerCfg.Env, fmt.Sprintf("MEILI_%s=%s", strings.ToUpper(key), serviceConfig.Settings[key]))
+	}
+
+	hostCfg.Mounts = []mount.Mount{
+		{
+			Type:   mount.TypeVolume,
+			Source: fmt.Sprintf("%s_%s_data", deployCfg.ContainerPrefix(), serviceName),
+			Target: "/meili_data",
+			VolumeOptions: &mount.VolumeOptions{
+				Labels: map[string]string{
+					"tanjun":         "true",
+					"tanjun.project": deployCfg.Name,
+					"tanjun.service": serviceName,
+				},
+			},
+		},
+	}
+
+	containerCfg.Healthcheck = &container.HealthConfig{
+		Test: []string{"CMD", "curl", "-sf", "http://localhost:7700/health"},
+	}
+
+	plan := newServicePlan(serviceName, containerName, containerCfg, hostCfg, networkConfig, existingContainer)
+
+	if existingContainer != nil && slices.Compare(envWithPrefix(existingContainer.Config.Env, "MEILI_"), envWithPrefix(containerCfg.Env, "MEILI_")) != 0 {
+		plan.recreate("settings changed")
+	}
+
+	if err := plan.planUpgrade(m, serviceConfig); err != nil {
+		return nil, err
+	}
+
+	return plan, nil
+}
+
+// DataVersion is the minor version, meilisearch cannot open the database of another minor version
+func (m MeilisearchService) DataVersion(image string, env []string) string {
+	_, tag, _ := strings.Cut(image, ":")
+
+	return parseMajorVersion(strings.TrimPrefix(tag, "v"), 2)
+}
+
+func (m MeilisearchService) UpgradeStrategy(from, to string) (string, error) {
+	return "", fmt.Errorf("meilisearch %s cannot open the data of %s, add a new service with the version and index the documents again", to, from)
+}
+
+func (m MeilisearchService) GeneratedSecrets(serviceName string, serviceConfig config.ProjectService) []string {
+	return []string{serviceSecretName("meilisearch", serviceName, "master_key")}
+}
+
+func (m MeilisearchService) AttachInfo(serviceName string, serviceConfig config.ProjectService) interface{} {
+	return map[string]interface{}{
+		"host": serviceName,
+		"port": "7700",
+		"url":  fmt.Sprintf("http://%s:7700", serviceName),
+	}
+}
+
+func (m MeilisearchService) Validate(serviceName string, serviceConfig config.ProjectService) error {
+	for key := range serviceConfig.Settings {
+		if !slices.Contains(supportedMeilisearchConfiguration, key) {
+			return fmt.Errorf("unsupported meilisearch configuration key %s", key)
+		}
+	}
+
+	return nil
+}
+
+func (m MeilisearchService) SupportedTypes() []string {
+	return []string{"meilisearch:1.11", "meilisearch:1.12"}
+}
+
+func (m MeilisearchService) ConfigSchema(serviceType string) *jsonschema.Schema {
+	properties := orderedmap.New[string, *jsonschema.Schema]()
+
+	properties.Set("max_indexing_memory", &jsonschema.Schema{
+		Type:        "string",
+		Description: "Maximum amount of memory used for indexing, like 2Gb.",
+	})
+
+	properties.Set("max_indexing_threads", &jsonschema.Schema{
+		Type:        "string",
+		Description: "Maximum number of threads used for indexing.",
+	})
+
+	properties.Set("http_payload_size_limit", &jsonschema.Schema{
+		Type:        "string",
+		Description: "Maximum accepted size of a request body, like 100Mb.",
+	})
+
+	return &jsonschema.Schema{
+		Type:       "object",
+		Properties: properties,
+	}
+}
+
+func init() {
+	allServices = append(allServices, MeilisearchService{})
+}
@@ -0,0 +1,222 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/invopop/jsonschema"
+	"github.com/shyim/tanjun/internal/config"
+	orderedmap "github.com/wk8/go-ordered-map/v2"
+)
+
+var supportedMinioConfiguration = []string{
+	"buckets",
+	"public_buckets",
+	"region",
+}
+
+// minioBucketName follows the S3 bucket naming rules
+var minioBucketName = regexp.MustCompile(`^[a-z0-9][a-z0-9.-]{1,61}[a-z0-9]$`)
+
+type MinioService struct {
+}
+
+func (m MinioService) Plan(ctx context.Context, serviceName string, deployCfg DeployConfiguration, existingContainer *container.InspectResponse) (*ServicePlan, error) {
+	serviceConfig := deployCfg.ProjectConfig.Services[serviceName]
+
+	accessKey, secretKey, err := minioCredentials(deployCfg, serviceName)
+
+	if err != nil {
+		return nil, err
+	}
+
+	addServiceInfo(deployCfg, serviceName, "access_key", accessKey)
+	addServiceInfo(deployCfg, serviceName, "secret_key", secretKey)
+
+	containerName, containerCfg, networkConfig, hostCfg, err := getDefaultServiceContainers(ctx, deployCfg, serviceName)
+
+	if err != nil {
+		return nil, err
+	}
+
+	containerCfg.Image = "minio/minio:latest"
+	containerCfg.Cmd = []string{"server", "/data", "--console-address", ":9001"}
+	containerCfg.Env = append(containerCfg.Env,
+		"MINIO_ROOT_USER="+accessKey,
+		"MINIO_ROOT_PASSWORD="+secretKey,
+		"MINIO_REGION="+minioRegion(serviceConfig),
+		// mc inside the container uses this alias to create the buckets
+		fmt.Sprintf("MC_HOST_local=http://%s:%s@localhost:9000", accessKey, secretKey),
+	)
+
+	hostCfg.Mounts = []mount.Mount{
+		{
+			Type:   mount.TypeVolume,
+			Source: fmt.Sprintf("%s_%s_data", deployCfg.ContainerPrefix(), serviceName),
+			Target: "/data",
+			VolumeOptions: &mount.VolumeOptions{
+				Labels: map[string]string{
+					"tanjun":         "true",
+					"tanjun.project": deployCfg.Name,
+					"tanjun.service": serviceName,
+				},
+			},
+		},
+	}
+
+	containerCfg.Healthcheck = &container.HealthConfig{
+		Test: []string{"CMD", "mc", "ready", "local"},
+	}
+
+	plan := newServicePlan(serviceName, containerName, containerCfg, hostCfg, networkConfig, existingContainer)
+
+	// The minio image defines MINIO_ variables on its own, so every variable set here has to be unchanged in the existing container
+	if existingContainer != nil {
+		for _, value := range append(envWithPrefix(containerCfg.Env, "MINIO_"), envWithPrefix(containerCfg.Env, "MC_HOST_")...) {
+			if !slices.Contains(existingContainer.Config.Env, value) {
+				plan.recreate("settings changed")
+				break
+			}
+		}
+	}
+
+	publicBuckets := splitMinioBuckets(serviceConfig.Settings["public_buckets"])
+
+	for _, bucket := range minioBuckets(serviceConfig) {
+		plan.setupCommands = append(plan.setupCommands, []string{"mc", "mb", "--ignore-existing", "local/" + bucket})
+
+		policy := "private"
+
+		if slices.Contains(publicBuckets, bucket) {
+			policy = "download"
+		}
+
+		plan.setupCommands = append(plan.setupCommands, []string{"mc", "anonymous", "set", policy, "local/" + bucket})
+	}
+
+	return plan, nil
+}
+
+func (m MinioService) GeneratedSecrets(serviceName string, serviceConfig config.ProjectService) []string {
+	return []string{serviceSecretName("minio", serviceName, "access_key"), serviceSecretName("minio", serviceName, "secret_key")}
+}
+
+func minioCredentials(deployCfg DeployConfiguration, serviceName string) (string, string, error) {
+	accessKey, err := serviceSecret(deployCfg, serviceSecretName("minio", serviceName, "access_key"), 20)
+
+	if err != nil {
+		return "", "", err
+	}
+
+	secretKey, err := serviceSecret(deployCfg, serviceSecretName("minio", serviceName, "secret_key"), 40)
+
+	if err != nil {
+		return "", "", err
+	}
+
+	return accessKey, secretKey, nil
+}
+
+func minioRegion(serviceConfig config.ProjectService) string {
+	if region := serviceConfig.Settings["region"]; region != "" {
+		return region
+	}
+
+	return "us-east-1"
+}
+
+func splitMinioBuckets(value string) []string {
+	var buckets []string
+
+	for _, bucket := range strings.Split(value, ",") {
+		if bucket = strings.TrimSpace(bucket); bucket != "" {
+			buckets = append(buckets, bucket)
+		}
+	}
+
+	return buckets
+}
+
+// minioBuckets returns all buckets to create, public buckets do not have to be listed in buckets again
+func minioBuckets(serviceConfig config.ProjectService) []string {
+	buckets := splitMinioBuckets(serviceConfig.Settings["buckets"])
+
+	for _, bucket := range splitMinioBuckets(serviceConfig.Settings["public_buckets"]) {
+		if !slices.Contains(buckets, bucket) {
+			buckets = append(buckets, bucket)
+		}
+	}
+
+	return buckets
+}
+
+func (m MinioService) AttachInfo(serviceName string, serviceConfig config.ProjectService) interface{} {
+	endpoint := fmt.Sprintf("http://%s:9000", serviceName)
+
+	buckets := make(map[string]interface{})
+
+	for _, bucket := range minioBuckets(serviceConfig) {
+		buckets[bucket] = endpoint + "/" + bucket
+	}
+
+	return map[string]interface{}{
+		"host":     serviceName,
+		"port":     "9000",
+		"endpoint": endpoint,
+		"region":   minioRegion(serviceConfig),
+		"buckets":  buckets,
+	}
+}
+
+func (m MinioService) Validate(serviceName string, serviceConfig config.ProjectService) error {
+	for key := range serviceConfig.Settings {
+		if !slices.Contains(supportedMinioConfiguration, key) {
+			return fmt.Errorf("unsupported minio configuration key %s", key)
+		}
+	}
+
+	for _, bucket := range minioBuckets(serviceConfig) {
+		if !minioBucketName.MatchString(bucket) {
+			return fmt.Errorf("invalid bucket name %s of service %s", bucket, serviceName)
+		}
+	}
+
+	return nil
+}
+
+func (m MinioService) SupportedTypes() []string {
+	return []string{"minio"}
+}
+
+func (m MinioService) ConfigSchema(serviceType string) *jsonschema.Schema {
+	properties := orderedmap.New[string, *jsonschema.Schema]()
+
+	properties.Set("buckets", &jsonschema.Schema{
+		Type:        "string",
+		Description: "Comma separated list of buckets created on deploy.",
+	})
+
+	properties.Set("public_buckets", &jsonschema.Schema{
+		Type:        "string",
+		Description: "Comma separated list of buckets which can be read without credentials.",
+	})
+
+	properties.Set("region", &jsonschema.Schema{
+		Type:        "string",
+		Description: "The region reported by the server, us-east-1 by default.",
+	})
+
+	return &jsonschema.Schema{
+		Type:       "object",
+		Properties: properties,
+	}
+}
+
+func init() {
+	allServices = append(allServices, MinioService{})
+}
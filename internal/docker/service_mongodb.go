@@ -0,0 +1,163 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/invopop/jsonschema"
+	"github.com/shyim/tanjun/internal/config"
+	orderedmap "github.com/wk8/go-ordered-map/v2"
+)
+
+// mongoDBReplicaSet is the name of the single node replica set started with the replica_set setting
+const mongoDBReplicaSet = "rs0"
+
+var supportedMongoDBConfiguration = []string{
+	"wiredTigerCacheSizeGB",
+	"replica_set",
+}
+
+type MongoDBService struct {
+}
+
+func (m MongoDBService) Plan(ctx context.Context, serviceName string, deployCfg DeployConfiguration, existingContainer *container.InspectResponse) (*ServicePlan, error) {
+	serviceConfig := deployCfg.ProjectConfig.Services[serviceName]
+
+	containerName, containerCfg, networkConfig, hostCfg, err := getDefaultServiceContainers(ctx, deployCfg, serviceName)
+
+	if err != nil {
+		return nil, err
+	}
+
+	containerCfg.Image = strings.Replace(serviceConfig.Type, "mongodb:", "mongo:", 1)
+	containerCfg.Env = append(containerCfg.Env, "MONGO_INITDB_DATABASE=database")
+	containerCfg.Cmd = []string{"mongod"}
+
+	hostCfg.Mounts = []mount.Mount{
+		{
+			Type:   mount.TypeVolume,
+			Source: fmt.Sprintf("%s_%s_data", deployCfg.ContainerPrefix(), serviceName),
+			Target: "/data/db",
+			VolumeOptions: &mount.VolumeOptions{
+				Labels: map[string]string{
+					"tanjun":         "true",
+					"tanjun.project": deployCfg.Name,
+					"tanjun.service": serviceName,
+				},
+			},
+		},
+	}
+
+	containerCfg.Healthcheck = &container.HealthConfig{
+		Test: []string{"CMD", "mongosh", "--quiet", "--eval", "db.adminCommand('ping').ok"},
+	}
+
+	for _, key := range slices.Sorted(maps.Keys(serviceConfig.Settings)) {
+		if key == "replica_set" {
+			continue
+		}
+
+		containerCfg.Cmd = append(containerCfg.Cmd, fmt.Sprintf("--%s=%s", key, serviceConfig.Settings[key]))
+	}
+
+	if mongoDBReplicaSetEnabled(serviceConfig) {
+		containerCfg.Cmd = append(containerCfg.Cmd, "--replSet="+mongoDBReplicaSet)
+
+		// The healthcheck initiates the replica set on the first run. mongosh exits with 0 whatever the result is, so it quits with 1 until the node became primary
+		containerCfg.Healthcheck.Test = []string{
+			"CMD", "mongosh", "--quiet", "--eval",
+			fmt.Sprintf("try { rs.status().ok } catch (e) { rs.initiate({_id: '%s', members: [{_id: 0, host: '%s:27017'}]}).ok }; if (!db.hello().isWritablePrimary) quit(1)", mongoDBReplicaSet, serviceName),
+		}
+	}
+
+	plan := newServicePlan(serviceName, containerName, containerCfg, hostCfg, networkConfig, existingContainer)
+
+	if existingContainer != nil && slices.Compare(existingContainer.Config.Cmd, containerCfg.Cmd) != 0 {
+		plan.recreate("settings changed")
+	}
+
+	if err := plan.planUpgrade(m, serviceConfig); err != nil {
+		return nil, err
+	}
+
+	return plan, nil
+}
+
+func mongoDBReplicaSetEnabled(serviceConfig config.ProjectService) bool {
+	return serviceConfig.Settings["replica_set"] == "true"
+}
+
+func (m MongoDBService) AttachInfo(serviceName string, serviceConfig config.ProjectService) interface{} {
+	url := fmt.Sprintf("mongodb://%s:27017/database", serviceName)
+
+	if mongoDBReplicaSetEnabled(serviceConfig) {
+		url += "?replicaSet=" + mongoDBReplicaSet
+	}
+
+	return map[string]interface{}{
+		"host":     serviceName,
+		"port":     "27017",
+		"database": "database",
+		"url":      url,
+	}
+}
+
+func (m MongoDBService) Validate(serviceName string, serviceConfig config.ProjectService) error {
+	for key, value := range serviceConfig.Settings {
+		if !slices.Contains(supportedMongoDBConfiguration, key) {
+			return fmt.Errorf("unsupported mongodb configuration key %s", key)
+		}
+
+		if key == "replica_set" && value != "true" && value != "false" {
+			return fmt.Errorf("mongodb setting replica_set must be true or false, got %s", value)
+		}
+	}
+
+	return nil
+}
+
+func (m MongoDBService) DataVersion(image string, env []string) string {
+	return imageDataVersion(image, env, "MONGO_MAJOR", 1)
+}
+
+// UpgradeStrategy allows only the upgrade to the next major version, mongod refuses to start on data of older versions
+func (m MongoDBService) UpgradeStrategy(from, to string) (string, error) {
+	if from == "6" && to == "7" {
+		return ServiceUpgradeInPlace, nil
+	}
+
+	return "", fmt.Errorf("mongodb only supports the upgrade from 6 to 7")
+}
+
+func (m MongoDBService) SupportedTypes() []string {
+	return []string{"mongodb:6", "mongodb:7"}
+}
+
+func (m MongoDBService) ConfigSchema(serviceType string) *jsonschema.Schema {
+	properties := orderedmap.New[string, *jsonschema.Schema]()
+
+	properties.Set("wiredTigerCacheSizeGB", &jsonschema.Schema{
+		Type:        "string",
+		Description: "Maximum size of the internal cache of WiredTiger in GB.",
+	})
+
+	properties.Set("replica_set", &jsonschema.Schema{
+		Type:        "string",
+		Enum:        []interface{}{"true", "false"},
+		Description: "Run as single node replica set, required for transactions and change streams.",
+	})
+
+	return &jsonschema.Schema{
+		Type:       "object",
+		Properties: properties,
+	}
+}
+
+func init() {
+	allServices = append(allServices, MongoDBService{})
+}
@@ -7,7 +7,6 @@ import (
 
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/mount"
-	"github.com/docker/docker/client"
 	"github.com/invopop/jsonschema"
 	"github.com/shyim/tanjun/internal/config"
 	orderedmap "github.com/wk8/go-ordered-map/v2"
@@ -31,10 +30,14 @@ var supportedMySQLConfiguration = []string{
 type MySQLService struct {
 }
 
-func (m MySQLService) Deploy(ctx context.Context, client *client.Client, serviceName string, deployCfg DeployConfiguration, existingContainer *container.InspectResponse) error {
+func (m MySQLService) Plan(ctx context.Context, serviceName string, deployCfg DeployConfiguration, existingContainer *container.InspectResponse) (*ServicePlan, error) {
 	serviceConfig := deployCfg.ProjectConfig.Services[serviceName]
 
-	containerName, containerCfg, networkConfig, hostCfg := getDefaultServiceContainers(ctx, deployCfg, serviceName)
+	containerName, containerCfg, networkConfig, hostCfg, err := getDefaultServiceContainers(ctx, deployCfg, serviceName)
+
+	if err != nil {
+		return nil, err
+	}
 
 	containerCfg.Env = append(containerCfg.Env, "MYSQL_ALLOW_EMPTY_PASSWORD=yes", "MYSQL_DATABASE=database")
 
@@ -61,6 +64,8 @@ func (m MySQLService) Deploy(ctx context.Context, client *client.Client, service
 		containerCfg.Cmd = append(containerCfg.Cmd, fmt.Sprintf("--%s=%s", key, value))
 	}
 
+	plan := newServicePlan(serviceName, containerName, containerCfg, hostCfg, networkConfig, existingContainer)
+
 	if existingContainer != nil {
 		existingContainerCmd := existingContainer.Config.Cmd
 
@@ -68,16 +73,16 @@ func (m MySQLService) Deploy(ctx context.Context, client *client.Client, service
 			existingContainerCmd = existingContainerCmd[1:]
 		}
 
-		if slices.Compare(existingContainerCmd, containerCfg.Cmd) == 0 {
-			return nil
+		if slices.Compare(existingContainerCmd, containerCfg.Cmd) != 0 {
+			plan.recreate("settings changed")
 		}
+	}
 
-		if err := stopAndRemoveContainer(ctx, client, existingContainer.ID); err != nil {
-			return fmt.Errorf("failed to stop and remove service %s (id: %s): %w", serviceName, existingContainer.ID, err)
-		}
+	if err := plan.planUpgrade(m, serviceConfig); err != nil {
+		return nil, err
 	}
 
-	return startService(ctx, client, serviceName, containerName, containerCfg, hostCfg, networkConfig)
+	return plan, nil
 }
 
 func (m MySQLService) AttachInfo(serviceName string, serviceCfg config.ProjectService) interface{} {
@@ -102,6 +107,26 @@ func (m MySQLService) Validate(serviceName string, serviceCfg config.ProjectServ
 	return nil
 }
 
+func (m MySQLService) BackupCommand(serviceName string, serviceCfg config.ProjectService) []string {
+	return []string{"mysqldump", "-uroot", "--single-transaction", "--routines", "--triggers", "--events", "database"}
+}
+
+func (m MySQLService) RestoreCommand(serviceName string, serviceCfg config.ProjectService) []string {
+	return []string{"mysql", "-uroot", "database"}
+}
+
+func (m MySQLService) DataVersion(image string, env []string) string {
+	return imageDataVersion(image, env, "MYSQL_MAJOR", 2)
+}
+
+func (m MySQLService) UpgradeStrategy(from, to string) (string, error) {
+	if from == "8.0" && to == "8.4" {
+		return ServiceUpgradeInPlace, nil
+	}
+
+	return "", fmt.Errorf("mysql only supports the upgrade from 8.0 to 8.4")
+}
+
 func (m MySQLService) SupportedTypes() []string {
 	return []string{"mysql:8.0", "mysql:8.4"}
 }
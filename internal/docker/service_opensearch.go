@@ -6,7 +6,6 @@ import (
 
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/mount"
-	"github.com/docker/docker/client"
 	"github.com/invopop/jsonschema"
 	"github.com/shyim/tanjun/internal/config"
 	orderedmap "github.com/wk8/go-ordered-map/v2"
@@ -15,10 +14,14 @@ import (
 type OpenSearchService struct {
 }
 
-func (v OpenSearchService) Deploy(ctx context.Context, client *client.Client, serviceName string, deployCfg DeployConfiguration, existingContainer *container.InspectResponse) error {
+func (v OpenSearchService) Plan(ctx context.Context, serviceName string, deployCfg DeployConfiguration, existingContainer *container.InspectResponse) (*ServicePlan, error) {
 	serviceConfig := deployCfg.ProjectConfig.Services[serviceName]
 
-	containerName, containerCfg, networkConfig, hostCfg := getDefaultServiceContainers(ctx, deployCfg, serviceName)
+	containerName, containerCfg, networkConfig, hostCfg, err := getDefaultServiceContainers(ctx, deployCfg, serviceName)
+
+	if err != nil {
+		return nil, err
+	}
 
 	containerCfg.Image = fmt.Sprintf("opensearchproject/%s", serviceConfig.Type)
 	containerCfg.Env = append(
@@ -47,17 +50,13 @@ func (v OpenSearchService) Deploy(ctx context.Context, client *client.Client, se
 		Test: []string{"CMD", "curl", "-f", "localhost:9200"},
 	}
 
-	if existingContainer != nil {
-		if existingContainer.Config.Image == containerCfg.Image {
-			return nil
-		}
+	plan := newServicePlan(serviceName, containerName, containerCfg, hostCfg, networkConfig, existingContainer)
 
-		if err := stopAndRemoveContainer(ctx, client, existingContainer.ID); err != nil {
-			return fmt.Errorf("failed to stop and remove service %s (id: %s): %w", serviceName, existingContainer.ID, err)
-		}
+	if existingContainer != nil && existingContainer.Config.Image != containerCfg.Image {
+		plan.recreate("image changed")
 	}
 
-	return startService(ctx, client, serviceName, containerName, containerCfg, hostCfg, networkConfig)
+	return plan, nil
 }
 
 func (v OpenSearchService) AttachInfo(serviceName string, serviceConfig config.ProjectService) interface{} {
@@ -5,9 +5,9 @@ import (
 	"fmt"
 	"slices"
 
+	"github.com/charmbracelet/log"
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/mount"
-	"github.com/docker/docker/client"
 	"github.com/invopop/jsonschema"
 	"github.com/shyim/tanjun/internal/config"
 	orderedmap "github.com/wk8/go-ordered-map/v2"
@@ -31,15 +31,33 @@ var supportedPostgresConfiguration = []string{
 	"max_parallel_workers",
 }
 
+// legacyPostgresImage was used for every postgres type before the image followed the configured version
+const legacyPostgresImage = "postgres:alpine"
+
 type PostgresService struct {
 }
 
-func (p PostgresService) Deploy(ctx context.Context, client *client.Client, serviceName string, deployCfg DeployConfiguration, existingContainer *container.InspectResponse) error {
+func (p PostgresService) Plan(ctx context.Context, serviceName string, deployCfg DeployConfiguration, existingContainer *container.InspectResponse) (*ServicePlan, error) {
 	serviceConfig := deployCfg.ProjectConfig.Services[serviceName]
 
-	containerName, containerCfg, networkConfig, hostCfg := getDefaultServiceContainers(ctx, deployCfg, serviceName)
+	containerName, containerCfg, networkConfig, hostCfg, err := getDefaultServiceContainers(ctx, deployCfg, serviceName)
+
+	if err != nil {
+		return nil, err
+	}
+
+	containerCfg.Image = serviceConfig.Type + "-alpine"
+
+	// The data of the legacy image can have any version, it is only replaced when the version matches or the upgrade was allowed
+	if existingContainer != nil && existingContainer.Config != nil && existingContainer.Config.Image == legacyPostgresImage && !deployCfg.ProjectConfig.AllowServiceUpgrade {
+		legacyVersion := p.DataVersion(existingContainer.Config.Image, existingContainer.Config.Env)
+
+		if legacyVersion != p.DataVersion(containerCfg.Image, nil) {
+			log.Warnf("Service %s runs the legacy image %s with PostgreSQL %s and is kept. Set the type to postgres:%s to use the versioned image, or deploy with --allow-service-upgrade to migrate the data to %s", serviceName, legacyPostgresImage, legacyVersion, legacyVersion, serviceConfig.Type)
 
-	containerCfg.Image = "postgres:alpine"
+			containerCfg.Image = legacyPostgresImage
+		}
+	}
 	containerCfg.Env = append(containerCfg.Env, "POSTGRES_DB=database", "POSTGRES_USER=user", "POSTGRES_PASSWORD=password")
 	containerCfg.Cmd = []string{"postgres"}
 
@@ -59,24 +77,24 @@ func (p PostgresService) Deploy(ctx context.Context, client *client.Client, serv
 	}
 
 	containerCfg.Healthcheck = &container.HealthConfig{
-		Test: []string{"CMD-SHELL", "pg_isready -U user"},
+		Test: []string{"CMD-SHELL", "pg_isready -U user -h 127.0.0.1"},
 	}
 
 	for key, value := range serviceConfig.Settings {
 		containerCfg.Cmd = append(containerCfg.Cmd, fmt.Sprintf("--%s=%s", key, value))
 	}
 
-	if existingContainer != nil {
-		if slices.Compare(existingContainer.Config.Cmd, containerCfg.Cmd) == 0 {
-			return nil
-		}
+	plan := newServicePlan(serviceName, containerName, containerCfg, hostCfg, networkConfig, existingContainer)
 
-		if err := stopAndRemoveContainer(ctx, client, existingContainer.ID); err != nil {
-			return fmt.Errorf("failed to stop and remove service %s (id: %s): %w", serviceName, existingContainer.ID, err)
-		}
+	if existingContainer != nil && slices.Compare(existingContainer.Config.Cmd, containerCfg.Cmd) != 0 {
+		plan.recreate("settings changed")
+	}
+
+	if err := plan.planUpgrade(p, serviceConfig); err != nil {
+		return nil, err
 	}
 
-	return startService(ctx, client, serviceName, containerName, containerCfg, hostCfg, networkConfig)
+	return plan, nil
 }
 
 func (p PostgresService) AttachInfo(serviceName string, serviceCfg config.ProjectService) interface{} {
@@ -101,6 +119,26 @@ func (p PostgresService) Validate(serviceName string, serviceCfg config.ProjectS
 	return nil
 }
 
+func (p PostgresService) BackupCommand(serviceName string, serviceCfg config.ProjectService) []string {
+	return []string{"pg_dump", "-U", "user", "--no-owner", "--clean", "--if-exists", "database"}
+}
+
+func (p PostgresService) RestoreCommand(serviceName string, serviceCfg config.ProjectService) []string {
+	return []string{"psql", "-U", "user", "-v", "ON_ERROR_STOP=1", "-q", "database"}
+}
+
+func (p PostgresService) DataVersion(image string, env []string) string {
+	return imageDataVersion(image, env, "PG_MAJOR", 1)
+}
+
+func (p PostgresService) UpgradeStrategy(from, to string) (string, error) {
+	if compareVersions(from, to) > 0 {
+		return "", fmt.Errorf("postgres does not support downgrades")
+	}
+
+	return ServiceUpgradeDumpRestore, nil
+}
+
 func (p PostgresService) SupportedTypes() []string {
 	return []string{"postgres:17", "postgres:16", "postgres:15", "postgres:14"}
 }
@@ -6,7 +6,6 @@ import (
 
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/mount"
-	"github.com/docker/docker/client"
 	"github.com/invopop/jsonschema"
 	"github.com/shyim/tanjun/internal/config"
 	orderedmap "github.com/wk8/go-ordered-map/v2"
@@ -15,8 +14,12 @@ import (
 type RabbitmqService struct {
 }
 
-func (v RabbitmqService) Deploy(ctx context.Context, client *client.Client, serviceName string, deployCfg DeployConfiguration, existingContainer *container.InspectResponse) error {
-	containerName, containerCfg, networkConfig, hostCfg := getDefaultServiceContainers(ctx, deployCfg, serviceName)
+func (v RabbitmqService) Plan(ctx context.Context, serviceName string, deployCfg DeployConfiguration, existingContainer *container.InspectResponse) (*ServicePlan, error) {
+	containerName, containerCfg, networkConfig, hostCfg, err := getDefaultServiceContainers(ctx, deployCfg, serviceName)
+
+	if err != nil {
+		return nil, err
+	}
 
 	containerCfg.Image = "rabbitmq:4-management-alpine"
 
@@ -35,17 +38,13 @@ func (v RabbitmqService) Deploy(ctx context.Context, client *client.Client, serv
 		},
 	}
 
-	if existingContainer != nil {
-		if existingContainer.Config.Image == containerCfg.Image {
-			return nil
-		}
+	plan := newServicePlan(serviceName, containerName, containerCfg, hostCfg, networkConfig, existingContainer)
 
-		if err := stopAndRemoveContainer(ctx, client, existingContainer.ID); err != nil {
-			return fmt.Errorf("failed to stop and remove service %s (id: %s): %w", serviceName, existingContainer.ID, err)
-		}
+	if existingContainer != nil && existingContainer.Config.Image != containerCfg.Image {
+		plan.recreate("image changed")
 	}
 
-	return startService(ctx, client, serviceName, containerName, containerCfg, hostCfg, networkConfig)
+	return plan, nil
 }
 
 func (v RabbitmqService) AttachInfo(serviceName string, serviceConfig config.ProjectService) interface{} {
@@ -0,0 +1,118 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/client"
+)
+
+// serviceRelayImage forwards the port of a service on the other servers to the port published on the private address of the primary server
+const serviceRelayImage = "alpine/socat:1.8.0.1"
+
+const serviceRelayLabel = "tanjun.service.relay"
+const serviceRelayTargetLabel = "tanjun.service.relay.target"
+
+// ensureServiceRelays starts a relay for every service in the project network, app containers reach it with the service name like on the primary server
+func ensureServiceRelays(ctx context.Context, client *client.Client, deployCfg DeployConfiguration) error {
+	opts := container.ListOptions{Filters: filters.NewArgs(), All: true}
+	opts.Filters.Add("label", fmt.Sprintf("tanjun.project=%s", deployCfg.Name))
+	opts.Filters.Add("label", serviceRelayLabel)
+
+	existing, err := client.ContainerList(ctx, opts)
+
+	if err != nil {
+		return err
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(deployCfg.ProjectConfig.Services)) {
+		serviceConfig := deployCfg.ProjectConfig.Services[name]
+		port := servicePort(name, serviceConfig)
+
+		if port == "" {
+			continue
+		}
+
+		target := fmt.Sprintf("%s:%d", deployCfg.serviceHostIP, serviceConfig.HostPort)
+		upToDate := false
+
+		for _, c := range existing {
+			if c.Labels[serviceRelayLabel] != name {
+				continue
+			}
+
+			if c.Labels[serviceRelayTargetLabel] == port+"->"+target && c.State == container.StateRunning {
+				upToDate = true
+				continue
+			}
+
+			if err := client.ContainerRemove(ctx, c.ID, container.RemoveOptions{Force: true}); err != nil {
+				return err
+			}
+		}
+
+		if upToDate {
+			continue
+		}
+
+		if err := startServiceRelay(ctx, client, deployCfg, name, port, target); err != nil {
+			return err
+		}
+	}
+
+	for _, c := range existing {
+		if _, ok := deployCfg.ProjectConfig.Services[c.Labels[serviceRelayLabel]]; ok {
+			continue
+		}
+
+		if err := client.ContainerRemove(ctx, c.ID, container.RemoveOptions{Force: true}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func startServiceRelay(ctx context.Context, client *client.Client, deployCfg DeployConfiguration, name, port, target string) error {
+	if err := PullImageIfNotThere(ctx, client, serviceRelayImage); err != nil {
+		return err
+	}
+
+	cfg := &container.Config{
+		Image: serviceRelayImage,
+		Cmd:   []string{fmt.Sprintf("TCP-LISTEN:%s,fork,reuseaddr", port), "TCP:" + target},
+		Labels: map[string]string{
+			"tanjun":                "true",
+			"tanjun.project":        deployCfg.Name,
+			serviceRelayLabel:       name,
+			serviceRelayTargetLabel: port + "->" + target,
+		},
+	}
+
+	hostCfg := &container.HostConfig{
+		RestartPolicy: container.RestartPolicy{
+			Name: container.RestartPolicyUnlessStopped,
+		},
+	}
+
+	networkCfg := &network.NetworkingConfig{
+		EndpointsConfig: map[string]*network.EndpointSettings{
+			deployCfg.Name: {
+				Aliases: []string{name},
+			},
+		},
+	}
+
+	created, err := client.ContainerCreate(ctx, cfg, hostCfg, networkCfg, nil, fmt.Sprintf("%s_%s_relay", deployCfg.ContainerPrefix(), name))
+
+	if err != nil {
+		return err
+	}
+
+	return client.ContainerStart(ctx, created.ID, container.StartOptions{})
+}
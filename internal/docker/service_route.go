@@ -0,0 +1,274 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/client"
+	"github.com/pterm/pterm"
+)
+
+// serviceRouteLabel holds the host a service container is reachable at through kamal-proxy
+const serviceRouteLabel = "tanjun.service.host"
+
+// serviceAuthProxyLabel marks the container checking the basic auth in front of an exposed service, the value is the service name
+const serviceAuthProxyLabel = "tanjun.service.auth"
+
+const authProxyImage = "ghcr.io/shyim/tanjun/auth-proxy:v1"
+
+// authProxyHealthPath is answered by the auth proxy without credentials
+const authProxyHealthPath = "/.tanjun/up"
+
+// serviceRoute publishes a port of a service container through kamal-proxy
+type serviceRoute struct {
+	Host            string
+	Port            string
+	HealthCheckPath string
+	// Username and Password enable basic auth, checked by an auth proxy container in front of the service
+	Username string
+	Password string
+}
+
+// serviceRouteName is the name of the kamal-proxy service, the app itself uses the project name.
+// Project names cannot contain underscores, so it cannot collide with the route of another project
+func serviceRouteName(projectName, serviceName string) string {
+	return fmt.Sprintf("%s_service_%s", projectName, serviceName)
+}
+
+// publish routes the host to the given port of the service. Without basic auth the service container is connected to the proxy network,
+// otherwise only the auth proxy in front of it is, so the service cannot be reached without credentials.
+// Previews share the config of the project, so their services are not published to not take over the host
+func (p *ServicePlan) publish(deployCfg DeployConfiguration, route serviceRoute) {
+	if deployCfg.ProjectConfig.PreviewName != "" {
+		return
+	}
+
+	p.route = &route
+
+	p.containerCfg.Labels[serviceRouteLabel] = route.Host
+
+	public := route.Username == ""
+
+	if public {
+		p.networkCfg.EndpointsConfig[kamalNetworkName] = &network.EndpointSettings{}
+	}
+
+	if p.existingContainer == nil {
+		return
+	}
+
+	if p.existingContainer.Config.Labels[serviceRouteLabel] != route.Host {
+		p.recreate("proxy host changed")
+	} else if connectedToNetwork(p.existingContainer, kamalNetworkName) != public {
+		p.recreate("basic auth changed")
+	}
+}
+
+func connectedToNetwork(c *container.InspectResponse, networkName string) bool {
+	if c.NetworkSettings == nil {
+		return false
+	}
+
+	_, ok := c.NetworkSettings.Networks[networkName]
+
+	return ok
+}
+
+// unpublished reports if the existing container was reachable through kamal-proxy, but should not be anymore
+func (p *ServicePlan) unpublished() bool {
+	return p.route == nil && p.existingContainer != nil && p.existingContainer.Config.Labels[serviceRouteLabel] != ""
+}
+
+// applyRoute points the route to the current container, the ip changes when the container was recreated
+func (p *ServicePlan) applyRoute(ctx context.Context, client *client.Client, deployCfg DeployConfiguration) error {
+	if p.unpublished() {
+		return removeServiceRoute(ctx, client, deployCfg.Name, p.Name)
+	}
+
+	if p.route == nil {
+		return nil
+	}
+
+	previousAuthProxies, err := getServiceAuthProxies(ctx, client, deployCfg.Name, p.Name)
+
+	if err != nil {
+		return err
+	}
+
+	targetContainer, targetPort, healthCheckPath := p.containerName, p.route.Port, p.route.HealthCheckPath
+
+	if p.route.Username != "" {
+		targetContainer, err = p.startAuthProxy(ctx, client, deployCfg)
+
+		if err != nil {
+			return err
+		}
+
+		targetPort, healthCheckPath = "80", authProxyHealthPath
+	}
+
+	inspect, err := client.ContainerInspect(ctx, targetContainer)
+
+	if err != nil {
+		return err
+	}
+
+	publicNetwork, ok := inspect.NetworkSettings.Networks[kamalNetworkName]
+
+	if !ok {
+		return fmt.Errorf("service %s is not connected to the %s network", p.Name, kamalNetworkName)
+	}
+
+	kamalCmd := []string{
+		"kamal-proxy",
+		"deploy",
+		serviceRouteName(deployCfg.Name, p.Name),
+		"--host", p.route.Host,
+		"--target", fmt.Sprintf("%s:%s", publicNetwork.IPAddress, targetPort),
+		"--health-check-path", healthCheckPath,
+		"--forward-headers",
+	}
+
+	if deployCfg.ProjectConfig.Proxy.SSL {
+		kamalCmd = append(kamalCmd, "--tls")
+	}
+
+	if err := configureKamalService(ctx, client, kamalCmd); err != nil {
+		return fmt.Errorf("could not publish service %s at %s: %w", p.Name, p.route.Host, err)
+	}
+
+	pterm.Info.Printfln("Service %s is reachable at %s", p.Name, p.route.Host)
+
+	return removeContainers(ctx, client, previousAuthProxies)
+}
+
+// startAuthProxy starts a new auth proxy in front of the service, kamal-proxy is switched to it before the previous one is removed
+func (p *ServicePlan) startAuthProxy(ctx context.Context, client *client.Client, deployCfg DeployConfiguration) (string, error) {
+	if err := PullImageIfNotThere(ctx, client, authProxyImage); err != nil {
+		return "", err
+	}
+
+	containerCfg := &container.Config{
+		Image: authProxyImage,
+		Env: []string{
+			fmt.Sprintf("AUTH_UPSTREAM=%s:%s", p.Name, p.route.Port),
+			"AUTH_USERNAME=" + p.route.Username,
+			"AUTH_PASSWORD=" + p.route.Password,
+		},
+		Labels: map[string]string{
+			"com.docker.compose.project": deployCfg.ContainerPrefix(),
+			"com.docker.compose.service": p.Name + "-auth",
+			"tanjun":                     "true",
+			"tanjun.project":             deployCfg.Name,
+			serviceAuthProxyLabel:        p.Name,
+		},
+	}
+
+	deployCfg.addEnvironmentLabel(containerCfg.Labels)
+
+	hostCfg := &container.HostConfig{
+		RestartPolicy: container.RestartPolicy{
+			Name: container.RestartPolicyUnlessStopped,
+		},
+	}
+
+	networkCfg := &network.NetworkingConfig{
+		EndpointsConfig: map[string]*network.EndpointSettings{
+			deployCfg.Name:   {},
+			kamalNetworkName: {},
+		},
+	}
+
+	c, err := client.ContainerCreate(ctx, containerCfg, hostCfg, networkCfg, nil, fmt.Sprintf("%s_%s_auth_%d", deployCfg.ContainerPrefix(), p.Name, rand.IntN(1000000)))
+
+	if err != nil {
+		return "", err
+	}
+
+	if err := client.ContainerStart(ctx, c.ID, container.StartOptions{}); err != nil {
+		return "", err
+	}
+
+	return c.ID, nil
+}
+
+func getServiceAuthProxies(ctx context.Context, client *client.Client, projectName, serviceName string) ([]container.Summary, error) {
+	opts := container.ListOptions{Filters: filters.NewArgs(), All: true}
+
+	opts.Filters.Add("label", fmt.Sprintf("tanjun.project=%s", projectName))
+	opts.Filters.Add("label", fmt.Sprintf("%s=%s", serviceAuthProxyLabel, serviceName))
+
+	return client.ContainerList(ctx, opts)
+}
+
+// removeServiceRoute removes the kamal-proxy route of the service and its auth proxy
+func removeServiceRoute(ctx context.Context, client *client.Client, projectName, serviceName string) error {
+	if err := configureKamalService(ctx, client, []string{"kamal-proxy", "remove", serviceRouteName(projectName, serviceName)}); err != nil {
+		if !strings.Contains(err.Error(), "service not found") {
+			return err
+		}
+	}
+
+	authProxies, err := getServiceAuthProxies(ctx, client, projectName, serviceName)
+
+	if err != nil {
+		return err
+	}
+
+	return removeContainers(ctx, client, authProxies)
+}
+
+// removeServiceRoutes removes the kamal-proxy routes of the given containers
+func removeServiceRoutes(ctx context.Context, client *client.Client, containers []container.Summary) error {
+	for _, c := range containers {
+		if c.Labels[serviceRouteLabel] == "" {
+			continue
+		}
+
+		if err := removeServiceRoute(ctx, client, c.Labels["tanjun.project"], c.Labels["tanjun.service"]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// planServiceExpose publishes the service configured with services.<name>.expose
+func planServiceExpose(deployCfg DeployConfiguration, serviceName string, plan *ServicePlan) error {
+	expose := deployCfg.ProjectConfig.Services[serviceName].Expose
+
+	if expose == nil {
+		if plan.unpublished() {
+			plan.recreate("proxy host removed")
+		}
+
+		return nil
+	}
+
+	route := serviceRoute{
+		Host:            expose.Host,
+		Port:            strconv.Itoa(expose.Port),
+		HealthCheckPath: expose.HealthCheckPath,
+	}
+
+	if expose.BasicAuth != nil {
+		password, err := serviceSecret(deployCfg, serviceSecretName("service", serviceName, "basic_auth_password"), 24)
+
+		if err != nil {
+			return err
+		}
+
+		route.Username = expose.BasicAuth.Username
+		route.Password = password
+	}
+
+	plan.publish(deployCfg, route)
+
+	return nil
+}
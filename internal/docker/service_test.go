@@ -0,0 +1,551 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/volume"
+	"github.com/docker/go-connections/nat"
+	"github.com/shyim/tanjun/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestServiceConfig returns the project with the given services and its deploy configuration
+func newTestServiceConfig(services map[string]config.ProjectService, storedSecrets map[string]string) (*config.ProjectConfig, DeployConfiguration) {
+	projectConfig := &config.ProjectConfig{Name: "test-project", Services: services}
+
+	if storedSecrets == nil {
+		storedSecrets = make(map[string]string)
+	}
+
+	deployCfg := newDeployConfiguration(projectConfig, "test:latest")
+	deployCfg.storedSecrets = storedSecrets
+
+	return projectConfig, deployCfg
+}
+
+func TestServicePlan(t *testing.T) {
+	_, deployCfg := newTestServiceConfig(map[string]config.ProjectService{
+		"database": {Type: "mysql:8.0", Settings: map[string]string{"max_connections": "100"}},
+	}, nil)
+
+	cases := []struct {
+		name     string
+		existing *container.Config
+		action   string
+		reason   string
+	}{
+		{name: "new", action: ServiceActionCreate, reason: "not deployed yet"},
+		{name: "unchanged", existing: &container.Config{Image: "mysql:8.0", Cmd: []string{"mysqld", "--max_connections=100"}}, action: ServiceActionKeep},
+		{name: "settings changed", existing: &container.Config{Image: "mysql:8.0", Cmd: []string{"mysqld", "--max_connections=50"}}, action: ServiceActionRecreate, reason: "settings changed"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var existing *container.InspectResponse
+
+			if c.existing != nil {
+				existing = &container.InspectResponse{ContainerJSONBase: &container.ContainerJSONBase{ID: "existing"}, Config: c.existing}
+			}
+
+			plan, err := MySQLService{}.Plan(context.Background(), "database", deployCfg, existing)
+
+			assert.NoError(t, err)
+			assert.Equal(t, c.action, plan.Action)
+			assert.Equal(t, c.reason, plan.Reason)
+			assert.Equal(t, []string{"tanjun_test-project_database_data"}, plan.Volumes())
+		})
+	}
+}
+
+func TestServicePlanEnvironmentError(t *testing.T) {
+	_, deployCfg := newTestServiceConfig(map[string]config.ProjectService{
+		"database": {
+			Type:        "mongo:7",
+			Environment: map[string]config.ProjectEnvironment{"BROKEN": {Expression: "unknown_function("}},
+		},
+	}, nil)
+
+	_, err := MongoDBService{}.Plan(context.Background(), "database", deployCfg, nil)
+
+	assert.ErrorContains(t, err, "could not resolve the environment of service database")
+}
+
+func TestServicePublishedPort(t *testing.T) {
+	projectConfig, deployCfg := newTestServiceConfig(map[string]config.ProjectService{
+		"database": {Type: "mysql:8.0", HostPort: 13306},
+	}, nil)
+
+	projectConfig.Server = config.ProjectServer{Address: "example.com", PrivateAddress: "10.0.0.1"}
+	projectConfig.Servers = []config.ProjectServer{{Address: "10.0.0.2"}}
+
+	plan, err := MySQLService{}.Plan(context.Background(), "database", deployCfg, nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, nat.PortMap{"3306/tcp": []nat.PortBinding{{HostIP: "10.0.0.1", HostPort: "13306"}}}, plan.hostCfg.PortBindings)
+
+	existing := &container.InspectResponse{
+		ContainerJSONBase: &container.ContainerJSONBase{ID: "existing", HostConfig: &container.HostConfig{PortBindings: nat.PortMap{"3306/tcp": []nat.PortBinding{{HostPort: "3306"}}}}},
+		Config:            &container.Config{Image: "mysql:8.0", Cmd: []string{"mysqld"}},
+	}
+
+	plan, err = MySQLService{}.Plan(context.Background(), "database", deployCfg, existing)
+	assert.NoError(t, err)
+
+	planPublishedPort(plan)
+
+	assert.Equal(t, ServiceActionRecreate, plan.Action)
+	assert.Equal(t, "published port changed", plan.Reason)
+
+	projectConfig.Server.PrivateAddress = ""
+
+	plan, err = MySQLService{}.Plan(context.Background(), "database", deployCfg, nil)
+
+	assert.NoError(t, err)
+	assert.Empty(t, plan.hostCfg.PortBindings)
+}
+
+func TestServiceDataVersion(t *testing.T) {
+	cases := []struct {
+		service UpgradableService
+		image   string
+		env     []string
+		version string
+	}{
+		{PostgresService{}, "postgres:16-alpine", nil, "16"},
+		{PostgresService{}, "postgres:alpine", []string{"PATH=/usr/bin", "PG_MAJOR=17"}, "17"},
+		{PostgresService{}, "postgres:latest", nil, ""},
+		{MySQLService{}, "mysql:8.0", nil, "8.0"},
+		{MySQLService{}, "mysql:8", []string{"MYSQL_MAJOR=8.4", "MYSQL_VERSION=8.4.2-1.el9"}, "8.4"},
+		{MariaDBService{}, "mariadb:11", []string{"MARIADB_VERSION=1:11.4.2+maria~ubu2404"}, "11.4"},
+		{MariaDBService{}, "mariadb:10.11", nil, "10.11"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.image, func(t *testing.T) {
+			assert.Equal(t, c.version, c.service.DataVersion(c.image, c.env))
+		})
+	}
+}
+
+func TestServiceUpgradeStrategy(t *testing.T) {
+	cases := []struct {
+		service  UpgradableService
+		from     string
+		to       string
+		strategy string
+	}{
+		{PostgresService{}, "15", "16", ServiceUpgradeDumpRestore},
+		{PostgresService{}, "17", "16", ""},
+		{MySQLService{}, "8.0", "8.4", ServiceUpgradeInPlace},
+		{MySQLService{}, "8.4", "8.0", ""},
+		{MariaDBService{}, "10.6", "10.11", ServiceUpgradeInPlace},
+		{MariaDBService{}, "11.4", "10.11", ""},
+	}
+
+	for _, c := range cases {
+		t.Run(fmt.Sprintf("%T %s to %s", c.service, c.from, c.to), func(t *testing.T) {
+			strategy, err := c.service.UpgradeStrategy(c.from, c.to)
+
+			// a downgrade has no strategy
+			if c.strategy == "" {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, c.strategy, strategy)
+		})
+	}
+}
+
+func TestServicePlanUpgrade(t *testing.T) {
+	cases := []struct {
+		name         string
+		service      AppService
+		serviceType  string
+		existing     *container.Config
+		allowUpgrade bool
+		err          bool
+		action       string
+		reason       string
+		image        string
+		upgrade      bool
+	}{
+		{
+			name:        "mysql in-place upgrade",
+			service:     MySQLService{},
+			serviceType: "mysql:8.4",
+			existing:    &container.Config{Image: "mysql:8.0", Env: []string{"MYSQL_MAJOR=8.0"}, Cmd: []string{"mysqld"}},
+			action:      ServiceActionRecreate,
+			reason:      "upgrade from version 8.0 to 8.4 (in-place)",
+			image:       "mysql:8.4",
+			upgrade:     true,
+		},
+		{
+			name:        "mysql downgrade",
+			service:     MySQLService{},
+			serviceType: "mysql:8.0",
+			existing:    &container.Config{Image: "mysql:8.4", Env: []string{"MYSQL_MAJOR=8.4"}, Cmd: []string{"mysqld"}},
+			err:         true,
+		},
+		{
+			// Deployments before the image followed the version run the legacy image with any version
+			name:        "legacy postgres image of another version is kept",
+			service:     PostgresService{},
+			serviceType: "postgres:17",
+			existing:    &container.Config{Image: "postgres:alpine", Env: []string{"PG_MAJOR=18"}, Cmd: []string{"postgres"}},
+			action:      ServiceActionKeep,
+			image:       "postgres:alpine",
+		},
+		{
+			name:        "legacy postgres image of the same version",
+			service:     PostgresService{},
+			serviceType: "postgres:17",
+			existing:    &container.Config{Image: "postgres:alpine", Env: []string{"PG_MAJOR=17"}, Cmd: []string{"postgres"}},
+			action:      ServiceActionRecreate,
+			reason:      "image changed from postgres:alpine",
+			image:       "postgres:17-alpine",
+		},
+		{
+			name:         "legacy postgres image upgrade",
+			service:      PostgresService{},
+			serviceType:  "postgres:17",
+			existing:     &container.Config{Image: "postgres:alpine", Env: []string{"PG_MAJOR=16"}, Cmd: []string{"postgres"}},
+			allowUpgrade: true,
+			action:       ServiceActionRecreate,
+			reason:       "upgrade from version 16 to 17 (dump and restore)",
+			image:        "postgres:17-alpine",
+			upgrade:      true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			projectConfig, deployCfg := newTestServiceConfig(map[string]config.ProjectService{
+				"database": {Type: c.serviceType},
+			}, nil)
+
+			projectConfig.AllowServiceUpgrade = c.allowUpgrade
+
+			existing := &container.InspectResponse{ContainerJSONBase: &container.ContainerJSONBase{ID: "existing"}, Config: c.existing}
+
+			plan, err := c.service.Plan(context.Background(), "database", deployCfg, existing)
+
+			if c.err {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, c.action, plan.Action)
+			assert.Equal(t, c.reason, plan.Reason)
+			assert.Equal(t, c.image, plan.Image)
+			assert.Equal(t, c.upgrade, plan.Upgrade() != nil)
+		})
+	}
+}
+
+func TestLatestServiceSnapshots(t *testing.T) {
+	latest := latestServiceSnapshots([]*volume.Volume{
+		{Name: "tanjun_app_db_data_snapshot_20250101000000"},
+		{Name: "tanjun_app_db_data_snapshot_20250301000000"},
+		{Name: "tanjun_app_db_config_snapshot_20250301000000"},
+	})
+
+	assert.Len(t, latest, 2)
+	assert.Contains(t, latest, "tanjun_app_db_data_snapshot_20250301000000")
+	assert.Contains(t, latest, "tanjun_app_db_config_snapshot_20250301000000")
+}
+
+func TestMongoDBServicePlan(t *testing.T) {
+	projectConfig, deployCfg := newTestServiceConfig(map[string]config.ProjectService{
+		"mongo": {
+			Type: "mongodb:7",
+			Settings: map[string]string{
+				"wiredTigerCacheSizeGB": "0.5",
+				"replica_set":           "true",
+			},
+		},
+	}, nil)
+
+	plan, err := MongoDBService{}.Plan(context.Background(), "mongo", deployCfg, nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "mongo:7", plan.Image)
+	assert.Equal(t, []string{"mongod", "--wiredTigerCacheSizeGB=0.5", "--replSet=rs0"}, []string(plan.containerCfg.Cmd))
+	assert.Equal(t, []string{"tanjun_test-project_mongo_data"}, plan.Volumes())
+	assert.Contains(t, plan.containerCfg.Healthcheck.Test[len(plan.containerCfg.Healthcheck.Test)-1], "if (!db.hello().isWritablePrimary) quit(1)")
+
+	info := MongoDBService{}.AttachInfo("mongo", projectConfig.Services["mongo"]).(map[string]interface{})
+	assert.Equal(t, "mongodb://mongo:27017/database?replicaSet=rs0", info["url"])
+
+	assert.Error(t, MongoDBService{}.Validate("mongo", config.ProjectService{Settings: map[string]string{"replica_set": "yes"}}))
+	assert.Error(t, MongoDBService{}.Validate("mongo", config.ProjectService{Settings: map[string]string{"bind_ip": "0.0.0.0"}}))
+}
+
+func TestMinioServicePlan(t *testing.T) {
+	projectConfig, deployCfg := newTestServiceConfig(map[string]config.ProjectService{
+		"storage": {
+			Type: "minio",
+			Settings: map[string]string{
+				"buckets":        "uploads, media",
+				"public_buckets": "assets",
+			},
+		},
+	}, map[string]string{"MINIO_STORAGE_ACCESS_KEY": "access"})
+
+	deployCfg.dryRun = true
+	deployCfg.serviceConfig["storage"] = MinioService{}.AttachInfo("storage", projectConfig.Services["storage"])
+
+	plan, err := MinioService{}.Plan(context.Background(), "storage", deployCfg, nil)
+
+	assert.NoError(t, err)
+	assert.Len(t, deployCfg.storedSecrets["MINIO_STORAGE_SECRET_KEY"], 40)
+	assert.Contains(t, plan.containerCfg.Env, "MINIO_ROOT_USER=access")
+	assert.Equal(t, [][]string{
+		{"mc", "mb", "--ignore-existing", "local/uploads"},
+		{"mc", "anonymous", "set", "private", "local/uploads"},
+		{"mc", "mb", "--ignore-existing", "local/media"},
+		{"mc", "anonymous", "set", "private", "local/media"},
+		{"mc", "mb", "--ignore-existing", "local/assets"},
+		{"mc", "anonymous", "set", "download", "local/assets"},
+	}, plan.setupCommands)
+
+	info := deployCfg.serviceConfig["storage"].(map[string]interface{})
+	assert.Equal(t, "http://storage:9000", info["endpoint"])
+	assert.Equal(t, "access", info["access_key"])
+	assert.Equal(t, "us-east-1", info["region"])
+	assert.Equal(t, "http://storage:9000/assets", info["buckets"].(map[string]interface{})["assets"])
+
+	existing := &container.InspectResponse{
+		ContainerJSONBase: &container.ContainerJSONBase{ID: "existing"},
+		Config:            &container.Config{Image: "minio/minio:latest", Env: append([]string{"MINIO_ROOT_USER_FILE=access_key"}, plan.containerCfg.Env...)},
+	}
+
+	plan, err = MinioService{}.Plan(context.Background(), "storage", deployCfg, existing)
+
+	assert.NoError(t, err)
+	assert.Equal(t, ServiceActionKeep, plan.Action)
+
+	deployCfg.storedSecrets["MINIO_STORAGE_SECRET_KEY"] = "rotated"
+
+	plan, err = MinioService{}.Plan(context.Background(), "storage", deployCfg, existing)
+
+	assert.NoError(t, err)
+	assert.Equal(t, ServiceActionRecreate, plan.Action)
+	assert.Equal(t, "settings changed", plan.Reason)
+
+	assert.Error(t, MinioService{}.Validate("storage", config.ProjectService{Settings: map[string]string{"buckets": "Invalid_Bucket"}}))
+}
+
+func TestSearchServicePlan(t *testing.T) {
+	projectConfig, deployCfg := newTestServiceConfig(map[string]config.ProjectService{
+		"search":    {Type: "meilisearch:1.12", Settings: map[string]string{"max_indexing_memory": "1Gb"}},
+		"typesense": {Type: "typesense:28.0", Settings: map[string]string{"enable-cors": "true"}},
+	}, map[string]string{"MEILISEARCH_SEARCH_MASTER_KEY": "master"})
+
+	deployCfg.dryRun = true
+	deployCfg.serviceConfig["search"] = MeilisearchService{}.AttachInfo("search", projectConfig.Services["search"])
+	deployCfg.serviceConfig["typesense"] = TypesenseService{}.AttachInfo("typesense", projectConfig.Services["typesense"])
+
+	plan, err := MeilisearchService{}.Plan(context.Background(), "search", deployCfg, nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "getmeili/meilisearch:v1.12", plan.Image)
+	assert.Contains(t, plan.containerCfg.Env, "MEILI_MASTER_KEY=master")
+	assert.Contains(t, plan.containerCfg.Env, "MEILI_MAX_INDEXING_MEMORY=1Gb")
+	assert.Equal(t, "master", deployCfg.serviceConfig["search"].(map[string]interface{})["api_key"])
+
+	existing := &container.InspectResponse{
+		ContainerJSONBase: &container.ContainerJSONBase{ID: "existing"},
+		Config:            &container.Config{Image: "getmeili/meilisearch:v1.12", Env: []string{"PATH=/bin", "MEILI_ENV=production", "MEILI_NO_ANALYTICS=true", "MEILI_MASTER_KEY=master"}},
+	}
+
+	plan, err = MeilisearchService{}.Plan(context.Background(), "search", deployCfg, existing)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "settings changed", plan.Reason)
+
+	// The database of another minor version cannot be opened, so the image is not replaced
+	existing.Config.Image = "getmeili/meilisearch:v1.11"
+
+	_, err = MeilisearchService{}.Plan(context.Background(), "search", deployCfg, existing)
+
+	assert.ErrorContains(t, err, "cannot be changed from version 1.11 to 1.12")
+
+	plan, err = TypesenseService{}.Plan(context.Background(), "typesense", deployCfg, nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "typesense/typesense:28.0", plan.Image)
+	assert.Equal(t, []string{"--data-dir=/data", "--enable-cors=true"}, []string(plan.containerCfg.Cmd))
+	assert.Len(t, deployCfg.storedSecrets["TYPESENSE_TYPESENSE_API_KEY"], 32)
+	assert.Equal(t, deployCfg.storedSecrets["TYPESENSE_TYPESENSE_API_KEY"], deployCfg.serviceConfig["typesense"].(map[string]interface{})["api_key"])
+}
+
+func TestMailpitServicePlan(t *testing.T) {
+	projectConfig, deployCfg := newTestServiceConfig(map[string]config.ProjectService{
+		"mail": {Type: "mailpit", Settings: map[string]string{"ui_host": "mail.example.com"}},
+	}, map[string]string{"MAILPIT_MAIL_UI_PASSWORD": "secret"})
+
+	plan, err := MailpitService{}.Plan(context.Background(), "mail", deployCfg, nil)
+
+	assert.NoError(t, err)
+	assert.Contains(t, plan.containerCfg.Env, "MP_UI_AUTH=admin:secret")
+	assert.Equal(t, &serviceRoute{Host: "mail.example.com", Port: "8025", HealthCheckPath: "/livez"}, plan.route)
+	assert.Equal(t, "mail.example.com", plan.containerCfg.Labels[serviceRouteLabel])
+	assert.Contains(t, plan.networkCfg.EndpointsConfig, kamalNetworkName)
+
+	existing := &container.InspectResponse{
+		ContainerJSONBase: &container.ContainerJSONBase{ID: "existing"},
+		Config:            &container.Config{Image: plan.Image, Env: plan.containerCfg.Env, Labels: map[string]string{}},
+	}
+
+	plan, err = MailpitService{}.Plan(context.Background(), "mail", deployCfg, existing)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "proxy host changed", plan.Reason)
+
+	previewConfig, err := projectConfig.ForPreview("pr-1")
+	assert.NoError(t, err)
+
+	previewCfg := newDeployConfiguration(previewConfig, "test:latest")
+	previewCfg.storedSecrets = deployCfg.storedSecrets
+
+	plan, err = MailpitService{}.Plan(context.Background(), "mail", previewCfg, nil)
+
+	assert.NoError(t, err)
+	assert.Nil(t, plan.route)
+
+	info := MailpitService{}.AttachInfo("mail", projectConfig.Services["mail"]).(map[string]interface{})
+	assert.Equal(t, "smtp://mail:1025", info["dsn"])
+}
+
+func TestPlanServiceExpose(t *testing.T) {
+	projectConfig, deployCfg := newTestServiceConfig(map[string]config.ProjectService{
+		"queue": {
+			Type: "rabbitmq:4",
+			Expose: &config.ProjectServiceExpose{
+				Host:            "queue.example.com",
+				Port:            15672,
+				HealthCheckPath: "/",
+				BasicAuth:       &config.ProjectServiceBasicAuth{Username: "admin"},
+			},
+		},
+	}, map[string]string{"SERVICE_QUEUE_BASIC_AUTH_PASSWORD": "secret"})
+
+	plan, err := RabbitmqService{}.Plan(context.Background(), "queue", deployCfg, nil)
+
+	assert.NoError(t, err)
+	assert.NoError(t, planServiceExpose(deployCfg, "queue", plan))
+	assert.Equal(t, &serviceRoute{Host: "queue.example.com", Port: "15672", HealthCheckPath: "/", Username: "admin", Password: "secret"}, plan.route)
+	// Only the auth proxy is reachable by kamal-proxy
+	assert.NotContains(t, plan.networkCfg.EndpointsConfig, kamalNetworkName)
+
+	existing := &container.InspectResponse{
+		ContainerJSONBase: &container.ContainerJSONBase{ID: "existing"},
+		Config:            &container.Config{Image: plan.Image, Labels: map[string]string{serviceRouteLabel: "queue.example.com"}},
+		NetworkSettings:   &container.NetworkSettings{Networks: map[string]*network.EndpointSettings{kamalNetworkName: {}}},
+	}
+
+	plan, err = RabbitmqService{}.Plan(context.Background(), "queue", deployCfg, existing)
+
+	assert.NoError(t, err)
+	assert.NoError(t, planServiceExpose(deployCfg, "queue", plan))
+	assert.Equal(t, "basic auth changed", plan.Reason)
+
+	projectConfig.Services["queue"] = config.ProjectService{Type: "rabbitmq:4"}
+
+	plan, err = RabbitmqService{}.Plan(context.Background(), "queue", deployCfg, existing)
+
+	assert.NoError(t, err)
+	assert.NoError(t, planServiceExpose(deployCfg, "queue", plan))
+	assert.Nil(t, plan.route)
+	assert.True(t, plan.unpublished())
+	assert.Equal(t, "proxy host removed", plan.Reason)
+}
+
+func TestCustomServicePlan(t *testing.T) {
+	projectConfig, deployCfg := newTestServiceConfig(map[string]config.ProjectService{
+		"pdf": {
+			Type: "custom",
+			Environment: map[string]config.ProjectEnvironment{
+				"B": {Value: "2"},
+				"A": {Expression: "'1'"},
+			},
+			Custom: config.ProjectCustomService{
+				Image:       "gotenberg/gotenberg:8",
+				Command:     []string{"gotenberg", "--api-port=3000"},
+				Ports:       []int{3000},
+				Volumes:     map[string]string{"cache": "/tmp/cache"},
+				Healthcheck: &config.ProjectCustomHealthcheck{Command: "curl -f localhost:3000/health", Interval: 5},
+			},
+		},
+	}, nil)
+
+	assert.NoError(t, CustomService{}.Validate("pdf", projectConfig.Services["pdf"]))
+
+	plan, err := CustomService{}.Plan(context.Background(), "pdf", deployCfg, nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "gotenberg/gotenberg:8", plan.Image)
+	assert.Equal(t, []string{"A=1", "B=2"}, plan.containerCfg.Env)
+	assert.Equal(t, []string{"tanjun_test-project_pdf_cache"}, plan.Volumes())
+	assert.Equal(t, "3000", CustomService{}.AttachInfo("pdf", projectConfig.Services["pdf"]).(map[string]interface{})["port"])
+
+	existing := &container.InspectResponse{
+		ContainerJSONBase: &container.ContainerJSONBase{ID: "existing"},
+		Config:            &container.Config{Image: plan.Image, Labels: plan.containerCfg.Labels},
+	}
+
+	plan, err = CustomService{}.Plan(context.Background(), "pdf", deployCfg, existing)
+
+	assert.NoError(t, err)
+	assert.Equal(t, ServiceActionKeep, plan.Action)
+
+	service := projectConfig.Services["pdf"]
+	service.Custom.Command = []string{"gotenberg", "--api-port=3001"}
+	projectConfig.Services["pdf"] = service
+
+	plan, err = CustomService{}.Plan(context.Background(), "pdf", deployCfg, existing)
+
+	assert.NoError(t, err)
+	assert.Equal(t, ServiceActionRecreate, plan.Action)
+	assert.Equal(t, "configuration changed", plan.Reason)
+
+	assert.Error(t, CustomService{}.Validate("pdf", config.ProjectService{Type: "custom"}))
+	assert.Error(t, CustomService{}.Validate("pdf", config.ProjectService{Type: "custom", Custom: config.ProjectCustomService{Image: "foo", Volumes: map[string]string{"data": "relative"}}}))
+}
+
+func TestGeneratedProjectSecrets(t *testing.T) {
+	projectConfig := &config.ProjectConfig{
+		App: config.ProjectApp{
+			InitialSecrets: map[string]config.ProjectInitialSecrets{"APP_SECRET": {Expression: "randomString(32)"}},
+		},
+		Services: map[string]config.ProjectService{
+			"storage":   {Type: "minio"},
+			"search":    {Type: "meilisearch:1.12"},
+			"typesense": {Type: "typesense:28.0"},
+			"mail":      {Type: "mailpit", Settings: map[string]string{"ui_host": "mail.example.com"}},
+			"outbox":    {Type: "mailpit"},
+			"database":  {Type: "mysql:8.0"},
+			"queue": {
+				Type:   "rabbitmq:4",
+				Expose: &config.ProjectServiceExpose{Host: "queue.example.com", Port: 15672, BasicAuth: &config.ProjectServiceBasicAuth{Username: "admin"}},
+			},
+		},
+	}
+
+	assert.Equal(t, []string{
+		"APP_SECRET",
+		"MAILPIT_MAIL_UI_PASSWORD",
+		"MEILISEARCH_SEARCH_MASTER_KEY",
+		"MINIO_STORAGE_ACCESS_KEY",
+		"MINIO_STORAGE_SECRET_KEY",
+		"SERVICE_QUEUE_BASIC_AUTH_PASSWORD",
+		"TYPESENSE_TYPESENSE_API_KEY",
+	}, GeneratedProjectSecrets(projectConfig))
+}
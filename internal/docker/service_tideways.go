@@ -5,7 +5,6 @@ import (
 	"fmt"
 
 	"github.com/docker/docker/api/types/container"
-	"github.com/docker/docker/client"
 	"github.com/invopop/jsonschema"
 	"github.com/shyim/tanjun/internal/config"
 )
@@ -13,16 +12,16 @@ import (
 type TidewaysService struct {
 }
 
-func (t TidewaysService) Deploy(ctx context.Context, client *client.Client, serviceName string, deployCfg DeployConfiguration, existingContainer *container.InspectResponse) error {
-	containerName, containerCfg, networkConfig, hostCfg := getDefaultServiceContainers(ctx, deployCfg, serviceName)
+func (t TidewaysService) Plan(ctx context.Context, serviceName string, deployCfg DeployConfiguration, existingContainer *container.InspectResponse) (*ServicePlan, error) {
+	containerName, containerCfg, networkConfig, hostCfg, err := getDefaultServiceContainers(ctx, deployCfg, serviceName)
 
-	containerCfg.Image = "ghcr.io/tideways/daemon"
-
-	if existingContainer != nil {
-		return nil
+	if err != nil {
+		return nil, err
 	}
 
-	return startService(ctx, client, serviceName, containerName, containerCfg, hostCfg, networkConfig)
+	containerCfg.Image = "ghcr.io/tideways/daemon"
+
+	return newServicePlan(serviceName, containerName, containerCfg, hostCfg, networkConfig, existingContainer), nil
 }
 
 func (t TidewaysService) AttachInfo(serviceName string, serviceConfig config.ProjectService) interface{} {
@@ -0,0 +1,136 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/invopop/jsonschema"
+	"github.com/shyim/tanjun/internal/config"
+	orderedmap "github.com/wk8/go-ordered-map/v2"
+)
+
+var supportedTypesenseConfiguration = []string{
+	"enable-cors",
+	"thread-pool-size",
+	"cache-num-entries",
+}
+
+type TypesenseService struct {
+}
+
+func (t TypesenseService) Plan(ctx context.Context, serviceName string, deployCfg DeployConfiguration, existingContainer *container.InspectResponse) (*ServicePlan, error) {
+	serviceConfig := deployCfg.ProjectConfig.Services[serviceName]
+
+	apiKey, err := serviceSecret(deployCfg, serviceSecretName("typesense", serviceName, "api_key"), 32)
+
+	if err != nil {
+		return nil, err
+	}
+
+	addServiceInfo(deployCfg, serviceName, "api_key", apiKey)
+
+	containerName, containerCfg, networkConfig, hostCfg, err := getDefaultServiceContainers(ctx, deployCfg, serviceName)
+
+	if err != nil {
+		return nil, err
+	}
+
+	containerCfg.Image = "typesense/" + serviceConfig.Type
+	containerCfg.Env = append(containerCfg.Env, "TYPESENSE_API_KEY="+apiKey)
+	containerCfg.Cmd = []string{"--data-dir=/data"}
+
+	for _, key := range slices.Sorted(maps.Keys(serviceConfig.Settings)) {
+		containerCfg.Cmd = append(containerCfg.Cmd, fmt.Sprintf("--%s=%s", key, serviceConfig.Settings[key]))
+	}
+
+	hostCfg.Mounts = []mount.Mount{
+		{
+			Type:   mount.TypeVolume,
+			Source: fmt.Sprintf("%s_%s_data", deployCfg.ContainerPrefix(), serviceName),
+			Target: "/data",
+			VolumeOptions: &mount.VolumeOptions{
+				Labels: map[string]string{
+					"tanjun":         "true",
+					"tanjun.project": deployCfg.Name,
+					"tanjun.service": serviceName,
+				},
+			},
+		},
+	}
+
+	// The image ships without curl, bash can talk HTTP on its own
+	containerCfg.Healthcheck = &container.HealthConfig{
+		Test: []string{"CMD", "bash", "-c", `exec 3<>/dev/tcp/localhost/8108 && printf 'GET /health HTTP/1.0\r\n\r\n' >&3 && grep -q '"ok":true' <&3`},
+	}
+
+	plan := newServicePlan(serviceName, containerName, containerCfg, hostCfg, networkConfig, existingContainer)
+
+	if existingContainer != nil {
+		if existingContainer.Config.Image != containerCfg.Image {
+			plan.recreate("image changed")
+		} else if slices.Compare(existingContainer.Config.Cmd, containerCfg.Cmd) != 0 {
+			plan.recreate("settings changed")
+		}
+	}
+
+	return plan, nil
+}
+
+func (t TypesenseService) GeneratedSecrets(serviceName string, serviceConfig config.ProjectService) []string {
+	return []string{serviceSecretName("typesense", serviceName, "api_key")}
+}
+
+func (t TypesenseService) AttachInfo(serviceName string, serviceConfig config.ProjectService) interface{} {
+	return map[string]interface{}{
+		"host": serviceName,
+		"port": "8108",
+		"url":  fmt.Sprintf("http://%s:8108", serviceName),
+	}
+}
+
+func (t TypesenseService) Validate(serviceName string, serviceConfig config.ProjectService) error {
+	for key := range serviceConfig.Settings {
+		if !slices.Contains(supportedTypesenseConfiguration, key) {
+			return fmt.Errorf("unsupported typesense configuration key %s", key)
+		}
+	}
+
+	return nil
+}
+
+func (t TypesenseService) SupportedTypes() []string {
+	return []string{"typesense:27.1", "typesense:28.0"}
+}
+
+func (t TypesenseService) ConfigSchema(serviceType string) *jsonschema.Schema {
+	properties := orderedmap.New[string, *jsonschema.Schema]()
+
+	properties.Set("enable-cors", &jsonschema.Schema{
+		Type:        "string",
+		Enum:        []interface{}{"true", "false"},
+		Description: "Allow requests from browsers of other origins.",
+	})
+
+	properties.Set("thread-pool-size", &jsonschema.Schema{
+		Type:        "string",
+		Description: "Number of threads used for handling concurrent requests.",
+	})
+
+	properties.Set("cache-num-entries", &jsonschema.Schema{
+		Type:        "string",
+		Description: "Number of entries of the search cache.",
+	})
+
+	return &jsonschema.Schema{
+		Type:       "object",
+		Properties: properties,
+	}
+}
+
+func init() {
+	allServices = append(allServices, TypesenseService{})
+}
@@ -0,0 +1,397 @@
+package docker
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/volume"
+	"github.com/docker/docker/client"
+	"github.com/pterm/pterm"
+	"github.com/shyim/tanjun/internal/config"
+)
+
+const (
+	// ServiceUpgradeInPlace starts the new version on the existing data, it migrates the data on start
+	ServiceUpgradeInPlace = "in-place"
+	// ServiceUpgradeDumpRestore dumps the data with the old version and restores it into an empty volume of the new version
+	ServiceUpgradeDumpRestore = "dump and restore"
+)
+
+// serviceSnapshotLabel is set on the copies of the service volumes taken before an upgrade, the value is the previous image
+const serviceSnapshotLabel = "tanjun.snapshot"
+
+// serviceSnapshotMarker separates the volume name from the time of the snapshot
+const serviceSnapshotMarker = "_snapshot_"
+
+// UpgradableService is implemented by services whose data format depends on the version, changing the image of an
+// existing container to another data version needs deploy --allow-service-upgrade
+type UpgradableService interface {
+	// DataVersion returns the version of the data format used by the image, env contains the environment of an existing container
+	DataVersion(image string, env []string) string
+	// UpgradeStrategy returns how data of the from version is migrated to the to version, or an error when there is no way
+	UpgradeStrategy(from, to string) (string, error)
+}
+
+// ServiceUpgrade is planned when an existing service container is replaced with another data version
+type ServiceUpgrade struct {
+	From     string
+	To       string
+	Strategy string
+
+	service       AppService
+	serviceConfig config.ProjectService
+}
+
+// planUpgrade checks the image change of an existing service container and marks the plan as upgrade, when the data version changes
+func (p *ServicePlan) planUpgrade(svc AppService, serviceConfig config.ProjectService) error {
+	if p.existingContainer == nil || p.existingContainer.Config == nil || p.existingContainer.Config.Image == p.Image {
+		return nil
+	}
+
+	upgradable, ok := svc.(UpgradableService)
+
+	if !ok {
+		p.recreate(fmt.Sprintf("image changed from %s", p.existingContainer.Config.Image))
+		return nil
+	}
+
+	from := upgradable.DataVersion(p.existingContainer.Config.Image, p.existingContainer.Config.Env)
+	to := upgradable.DataVersion(p.Image, nil)
+
+	if from == to || from == "" || to == "" {
+		p.recreate(fmt.Sprintf("image changed from %s", p.existingContainer.Config.Image))
+		return nil
+	}
+
+	strategy, err := upgradable.UpgradeStrategy(from, to)
+
+	if err != nil {
+		return fmt.Errorf("service %s cannot be changed from version %s to %s: %w", p.Name, from, to, err)
+	}
+
+	p.upgrade = &ServiceUpgrade{From: from, To: to, Strategy: strategy, service: svc, serviceConfig: serviceConfig}
+	p.recreate(fmt.Sprintf("upgrade from version %s to %s (%s)", from, to, strategy))
+
+	return nil
+}
+
+// Upgrade returns the planned upgrade of the data version or nil
+func (p *ServicePlan) Upgrade() *ServiceUpgrade {
+	return p.upgrade
+}
+
+// applyUpgrade replaces the service container with the new version. The volumes are copied before, so the upgrade can be rolled back
+func (p *ServicePlan) applyUpgrade(ctx context.Context, client *client.Client) error {
+	var dump *os.File
+
+	if p.upgrade.Strategy == ServiceUpgradeDumpRestore {
+		var err error
+
+		dump, err = p.dumpBeforeUpgrade(ctx, client)
+
+		if err != nil {
+			return err
+		}
+
+		defer func() {
+			_ = dump.Close()
+			_ = os.Remove(dump.Name())
+		}()
+	}
+
+	if err := stopAndRemoveContainer(ctx, client, p.existingContainer.ID); err != nil {
+		return fmt.Errorf("failed to stop and remove service %s (id: %s): %w", p.Name, p.existingContainer.ID, err)
+	}
+
+	snapshots, err := p.snapshotVolumes(ctx, client)
+
+	if err != nil {
+		return err
+	}
+
+	pterm.Info.Printfln("Snapshot of service %s before the upgrade: %s, roll back with tanjun service upgrade-rollback %s", p.Name, strings.Join(snapshots, ", "), p.Name)
+
+	if dump != nil {
+		for _, v := range p.Volumes() {
+			if err := client.VolumeRemove(ctx, v, true); err != nil {
+				return fmt.Errorf("could not empty volume %s: %w", v, err)
+			}
+		}
+	}
+
+	if err := startService(ctx, client, p.Name, p.containerName, p.containerCfg, p.hostCfg, p.networkCfg); err != nil {
+		return err
+	}
+
+	// The upgrade runs before the app is switched, the previous app version may not work with the new data version
+	pterm.Warning.Printfln("Service %s was upgraded from version %s to %s. tanjun deploy --rollback does not downgrade the data, only tanjun service upgrade-rollback %s restores the snapshot", p.Name, p.upgrade.From, p.upgrade.To, p.Name)
+
+	if dump == nil {
+		return nil
+	}
+
+	return p.restoreAfterUpgrade(ctx, client, dump)
+}
+
+func (p *ServicePlan) dumpBeforeUpgrade(ctx context.Context, client *client.Client) (*os.File, error) {
+	backupable, ok := p.upgrade.service.(BackupableService)
+
+	if !ok {
+		return nil, fmt.Errorf("service %s cannot be dumped", p.Name)
+	}
+
+	spinnerInfo, err := pterm.DefaultSpinner.Start(fmt.Sprintf("Dumping service %s before the upgrade", p.Name))
+
+	if err != nil {
+		return nil, err
+	}
+
+	dump, err := os.CreateTemp("", "tanjun-upgrade-*.sql.gz")
+
+	if err != nil {
+		spinnerInfo.Fail(err)
+		return nil, err
+	}
+
+	compressed := gzip.NewWriter(dump)
+
+	err = execServiceCommand(ctx, client, p.existingContainer.ID, backupable.BackupCommand(p.Name, p.upgrade.serviceConfig), nil, compressed)
+
+	if err == nil {
+		err = compressed.Close()
+	}
+
+	if err != nil {
+		_ = dump.Close()
+		_ = os.Remove(dump.Name())
+		spinnerInfo.Fail(err)
+		return nil, fmt.Errorf("could not dump service %s before the upgrade: %w", p.Name, err)
+	}
+
+	spinnerInfo.Success(fmt.Sprintf("Dumped service %s", p.Name))
+
+	return dump, nil
+}
+
+func (p *ServicePlan) restoreAfterUpgrade(ctx context.Context, client *client.Client, dump *os.File) error {
+	backupable := p.upgrade.service.(BackupableService)
+
+	spinnerInfo, err := pterm.DefaultSpinner.Start(fmt.Sprintf("Restoring service %s into version %s", p.Name, p.upgrade.To))
+
+	if err != nil {
+		return err
+	}
+
+	if _, err := dump.Seek(0, io.SeekStart); err != nil {
+		spinnerInfo.Fail(err)
+		return err
+	}
+
+	decompressed, err := gzip.NewReader(dump)
+
+	if err != nil {
+		spinnerInfo.Fail(err)
+		return err
+	}
+
+	if err := execServiceCommand(ctx, client, p.containerName, backupable.RestoreCommand(p.Name, p.upgrade.serviceConfig), decompressed, io.Discard); err != nil {
+		spinnerInfo.Fail(err)
+		return fmt.Errorf("could not restore service %s after the upgrade, roll back with tanjun service upgrade-rollback %s: %w", p.Name, p.Name, err)
+	}
+
+	spinnerInfo.Success(fmt.Sprintf("Restored service %s into version %s", p.Name, p.upgrade.To))
+
+	return nil
+}
+
+// snapshotVolumes copies the volumes of the stopped service container
+func (p *ServicePlan) snapshotVolumes(ctx context.Context, client *client.Client) ([]string, error) {
+	if err := PullImageIfNotThere(ctx, client, "alpine:latest"); err != nil {
+		return nil, err
+	}
+
+	suffix := serviceSnapshotMarker + time.Now().UTC().Format("20060102150405")
+
+	var snapshots []string
+
+	for _, v := range p.Volumes() {
+		snapshot := v + suffix
+
+		_, err := client.VolumeCreate(ctx, volume.CreateOptions{
+			Name: snapshot,
+			Labels: map[string]string{
+				"tanjun":             "true",
+				"tanjun.project":     p.existingContainer.Config.Labels["tanjun.project"],
+				"tanjun.service":     p.Name,
+				serviceSnapshotLabel: p.existingContainer.Config.Image,
+			},
+		})
+
+		if err != nil {
+			return nil, err
+		}
+
+		if err := copyVolume(ctx, client, v, snapshot); err != nil {
+			return nil, fmt.Errorf("could not snapshot volume %s: %w", v, err)
+		}
+
+		snapshots = append(snapshots, snapshot)
+	}
+
+	return snapshots, nil
+}
+
+// RollbackServiceUpgrade removes the service container and copies the latest snapshot back into its volumes.
+// It returns the image the snapshot was taken from, the service type has to be set back to it before the next deployment
+func RollbackServiceUpgrade(ctx context.Context, client *client.Client, projectName, serviceName string) (string, error) {
+	volumeOpts := volume.ListOptions{Filters: filters.NewArgs()}
+	volumeOpts.Filters.Add("label", fmt.Sprintf("tanjun.project=%s", projectName))
+	volumeOpts.Filters.Add("label", fmt.Sprintf("tanjun.service=%s", serviceName))
+	volumeOpts.Filters.Add("label", serviceSnapshotLabel)
+
+	volumes, err := client.VolumeList(ctx, volumeOpts)
+
+	if err != nil {
+		return "", err
+	}
+
+	latest := latestServiceSnapshots(volumes.Volumes)
+
+	if len(latest) == 0 {
+		return "", fmt.Errorf("no snapshot of service %s found", serviceName)
+	}
+
+	containerOpts := container.ListOptions{Filters: filters.NewArgs(), All: true}
+	containerOpts.Filters.Add("label", fmt.Sprintf("tanjun.project=%s", projectName))
+	containerOpts.Filters.Add("label", fmt.Sprintf("tanjun.service=%s", serviceName))
+
+	containers, err := client.ContainerList(ctx, containerOpts)
+
+	if err != nil {
+		return "", err
+	}
+
+	if err := removeContainers(ctx, client, containers); err != nil {
+		return "", err
+	}
+
+	if err := PullImageIfNotThere(ctx, client, "alpine:latest"); err != nil {
+		return "", err
+	}
+
+	image := ""
+
+	for _, snapshot := range slices.Sorted(maps.Keys(latest)) {
+		target := snapshot[:strings.LastIndex(snapshot, serviceSnapshotMarker)]
+		image = latest[snapshot].Labels[serviceSnapshotLabel]
+
+		if err := client.VolumeRemove(ctx, target, true); err != nil {
+			return "", fmt.Errorf("could not remove volume %s: %w", target, err)
+		}
+
+		_, err := client.VolumeCreate(ctx, volume.CreateOptions{
+			Name: target,
+			Labels: map[string]string{
+				"tanjun":         "true",
+				"tanjun.project": projectName,
+				"tanjun.service": serviceName,
+			},
+		})
+
+		if err != nil {
+			return "", err
+		}
+
+		if err := copyVolume(ctx, client, snapshot, target); err != nil {
+			return "", fmt.Errorf("could not restore volume %s: %w", target, err)
+		}
+	}
+
+	return image, nil
+}
+
+// latestServiceSnapshots returns the snapshot volumes of the most recent upgrade by name
+func latestServiceSnapshots(volumes []*volume.Volume) map[string]*volume.Volume {
+	latestTime := ""
+
+	for _, v := range volumes {
+		if t := v.Name[strings.LastIndex(v.Name, serviceSnapshotMarker)+len(serviceSnapshotMarker):]; t > latestTime {
+			latestTime = t
+		}
+	}
+
+	latest := make(map[string]*volume.Volume)
+
+	for _, v := range volumes {
+		if strings.HasSuffix(v.Name, serviceSnapshotMarker+latestTime) {
+			latest[v.Name] = v
+		}
+	}
+
+	return latest
+}
+
+// parseMajorVersion returns the leading numeric components of a version like 16-alpine, 8.0.36 or 1:11.4.2+maria~ubu2404
+func parseMajorVersion(version string, components int) string {
+	if _, withoutEpoch, ok := strings.Cut(version, ":"); ok {
+		version = withoutEpoch
+	}
+
+	version, _, _ = strings.Cut(version, "-")
+	version, _, _ = strings.Cut(version, "+")
+	parts := strings.Split(version, ".")
+
+	if len(parts) < components {
+		return ""
+	}
+
+	for _, part := range parts[:components] {
+		if _, err := strconv.Atoi(part); err != nil {
+			return ""
+		}
+	}
+
+	return strings.Join(parts[:components], ".")
+}
+
+// imageDataVersion returns the version from the environment variable of an existing container, or from the tag of the image
+func imageDataVersion(image string, env []string, envKey string, components int) string {
+	for _, value := range env {
+		if key, version, ok := strings.Cut(value, "="); ok && key == envKey {
+			if major := parseMajorVersion(version, components); major != "" {
+				return major
+			}
+		}
+	}
+
+	_, tag, _ := strings.Cut(image, ":")
+
+	return parseMajorVersion(tag, components)
+}
+
+// compareVersions compares dotted numeric versions
+func compareVersions(a, b string) int {
+	aParts := strings.Split(a, ".")
+	bParts := strings.Split(b, ".")
+
+	for i := 0; i < len(aParts) && i < len(bParts); i++ {
+		aNum, _ := strconv.Atoi(aParts[i])
+		bNum, _ := strconv.Atoi(bParts[i])
+
+		if aNum != bNum {
+			return aNum - bNum
+		}
+	}
+
+	return len(aParts) - len(bParts)
+}
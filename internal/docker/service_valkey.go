@@ -6,7 +6,6 @@ import (
 	"slices"
 
 	"github.com/docker/docker/api/types/container"
-	"github.com/docker/docker/client"
 	"github.com/invopop/jsonschema"
 	"github.com/shyim/tanjun/internal/config"
 	orderedmap "github.com/wk8/go-ordered-map/v2"
@@ -15,10 +14,14 @@ import (
 type ValkeyService struct {
 }
 
-func (v ValkeyService) Deploy(ctx context.Context, client *client.Client, serviceName string, deployCfg DeployConfiguration, existingContainer *container.InspectResponse) error {
+func (v ValkeyService) Plan(ctx context.Context, serviceName string, deployCfg DeployConfiguration, existingContainer *container.InspectResponse) (*ServicePlan, error) {
 	serviceConfig := deployCfg.ProjectConfig.Services[serviceName]
 
-	containerName, containerCfg, networkConfig, hostCfg := getDefaultServiceContainers(ctx, deployCfg, serviceName)
+	containerName, containerCfg, networkConfig, hostCfg, err := getDefaultServiceContainers(ctx, deployCfg, serviceName)
+
+	if err != nil {
+		return nil, err
+	}
 
 	containerCfg.Healthcheck = &container.HealthConfig{
 		Test: []string{"CMD", "valkey-cli", "ping"},
@@ -32,17 +35,13 @@ func (v ValkeyService) Deploy(ctx context.Context, client *client.Client, servic
 		containerCfg.Cmd = append(containerCfg.Cmd, fmt.Sprintf("--%s=%s", key, value))
 	}
 
-	if existingContainer != nil {
-		if slices.Compare(existingContainer.Config.Cmd, containerCfg.Cmd) == 0 {
-			return nil
-		}
+	plan := newServicePlan(serviceName, containerName, containerCfg, hostCfg, networkConfig, existingContainer)
 
-		if err := stopAndRemoveContainer(ctx, client, existingContainer.ID); err != nil {
-			return fmt.Errorf("failed to stop and remove service %s (id: %s): %w", serviceName, existingContainer.ID, err)
-		}
+	if existingContainer != nil && slices.Compare(existingContainer.Config.Cmd, containerCfg.Cmd) != 0 {
+		plan.recreate("settings changed")
 	}
 
-	return startService(ctx, client, serviceName, containerName, containerCfg, hostCfg, networkConfig)
+	return plan, nil
 }
 
 func (v ValkeyService) AttachInfo(serviceName string, serviceConfig config.ProjectService) interface{} {
@@ -0,0 +1,159 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/pterm/pterm"
+	"github.com/shyim/tanjun/internal/config"
+)
+
+// verifyDeployment repeats the configured probes and command over the grace period, the first failing check fails the verification
+func verifyDeployment(ctx context.Context, deployCfg DeployConfiguration, deployments []*serverDeployment) error {
+	verify := deployCfg.ProjectConfig.Proxy.Verify
+
+	var commandClient *client.Client
+	var commandContainerID string
+
+	for _, d := range deployments {
+		if len(d.newContainerIDs) > 0 {
+			commandClient = d.client
+			commandContainerID = d.newContainerIDs[0]
+			break
+		}
+	}
+
+	spinnerInfo, err := pterm.DefaultSpinner.Start(fmt.Sprintf("Verifying new version for %ds", verify.GracePeriod))
+
+	if err != nil {
+		return err
+	}
+
+	httpClient := &http.Client{
+		Timeout: 10 * time.Second,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	deadline := time.Now().Add(time.Duration(verify.GracePeriod) * time.Second)
+
+	for {
+		for _, probe := range verify.Probes {
+			if err := runVerifyProbe(ctx, httpClient, deployCfg.ProjectConfig.Proxy, probe); err != nil {
+				spinnerInfo.Fail(err)
+				return err
+			}
+		}
+
+		if verify.Command != "" && commandContainerID != "" {
+			if err := runVerifyCommand(ctx, commandClient, commandContainerID, verify.Command); err != nil {
+				spinnerInfo.Fail(err)
+				return err
+			}
+		}
+
+		if time.Now().After(deadline) {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			spinnerInfo.Fail(ctx.Err())
+			return ctx.Err()
+		case <-time.After(time.Duration(verify.Interval) * time.Second):
+		}
+	}
+
+	spinnerInfo.Success("Verified new version")
+
+	return nil
+}
+
+func runVerifyProbe(ctx context.Context, httpClient *http.Client, proxy config.ProjectProxy, probe config.ProjectVerifyProbe) error {
+	url := proxy.GetURL() + probe.Path
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+
+	if err != nil {
+		return err
+	}
+
+	resp, err := httpClient.Do(req)
+
+	if err != nil {
+		return fmt.Errorf("probe %s failed: %w", url, err)
+	}
+
+	defer resp.Body.Close()
+
+	if probe.Status != 0 && resp.StatusCode != probe.Status {
+		return fmt.Errorf("probe %s returned status %d, expected %d", url, resp.StatusCode, probe.Status)
+	}
+
+	if probe.Status == 0 && resp.StatusCode >= 400 {
+		return fmt.Errorf("probe %s returned status %d", url, resp.StatusCode)
+	}
+
+	if probe.Contains == "" {
+		return nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+
+	if err != nil {
+		return fmt.Errorf("probe %s failed: %w", url, err)
+	}
+
+	if !strings.Contains(string(body), probe.Contains) {
+		return fmt.Errorf("probe %s does not contain %q", url, probe.Contains)
+	}
+
+	return nil
+}
+
+func runVerifyCommand(ctx context.Context, client *client.Client, containerID, command string) error {
+	exec, err := client.ContainerExecCreate(ctx, containerID, container.ExecOptions{
+		Cmd:          []string{"sh", "-c", command},
+		AttachStdout: true,
+		AttachStderr: true,
+	})
+
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.ContainerExecAttach(ctx, exec.ID, container.ExecAttachOptions{})
+
+	if err != nil {
+		return err
+	}
+
+	defer resp.Close()
+
+	var output bytes.Buffer
+
+	if _, err := stdcopy.StdCopy(&output, &output, resp.Reader); err != nil {
+		return err
+	}
+
+	inspect, err := client.ContainerExecInspect(ctx, exec.ID)
+
+	if err != nil {
+		return err
+	}
+
+	if inspect.ExitCode != 0 {
+		return fmt.Errorf("verify command failed with status code %d: %s", inspect.ExitCode, strings.TrimSpace(output.String()))
+	}
+
+	return nil
+}
@@ -0,0 +1,41 @@
+package docker
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/shyim/tanjun/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunVerifyProbe(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/health":
+			_, _ = w.Write([]byte("status: ok"))
+		case "/login":
+			http.Redirect(w, r, "/", http.StatusFound)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+
+	defer server.Close()
+
+	proxy := config.ProjectProxy{Host: strings.TrimPrefix(server.URL, "http://")}
+	client := server.Client()
+
+	assert.NoError(t, runVerifyProbe(context.Background(), client, proxy, config.ProjectVerifyProbe{Path: "/health", Contains: "ok"}))
+	assert.ErrorContains(t, runVerifyProbe(context.Background(), client, proxy, config.ProjectVerifyProbe{Path: "/health", Contains: "healthy"}), "does not contain")
+	assert.ErrorContains(t, runVerifyProbe(context.Background(), client, proxy, config.ProjectVerifyProbe{Path: "/broken"}), "returned status 500")
+	assert.ErrorContains(t, runVerifyProbe(context.Background(), client, proxy, config.ProjectVerifyProbe{Path: "/health", Status: 204}), "expected 204")
+
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	assert.NoError(t, runVerifyProbe(context.Background(), client, proxy, config.ProjectVerifyProbe{Path: "/login", Status: http.StatusFound}))
+}
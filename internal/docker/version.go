@@ -3,6 +3,7 @@ package docker
 import (
 	"context"
 	"fmt"
+	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/filters"
 	"github.com/docker/docker/api/types/image"
 	"github.com/docker/docker/client"
@@ -13,11 +14,26 @@ import (
 	"time"
 )
 
+// ImageProjectLabel is set on the built images, so versions of environments sharing an image repository can be told apart
+const ImageProjectLabel = "tanjun.project"
+
 type Version struct {
 	Name      string
 	Aliases   []string
 	CreatedAt time.Time
 	Active    bool
+	// Project is the project which built the image
+	Project string
+}
+
+// imageProject returns the project which built the image. Images built before the label was introduced belong to the
+// project without environment, environments and previews did not exist back then
+func imageProject(img image.Summary, cfg *config.ProjectConfig) string {
+	if owner := img.Labels[ImageProjectLabel]; owner != "" {
+		return owner
+	}
+
+	return cfg.Name
 }
 
 func VersionList(ctx context.Context, client *client.Client, cfg *config.ProjectConfig) ([]Version, error) {
@@ -38,8 +54,13 @@ func VersionList(ctx context.Context, client *client.Client, cfg *config.Project
 	}
 
 	versions := make([]Version, 0, len(images))
+	project := cfg.Identifier()
 
 	for _, img := range images {
+		if imageProject(img, cfg) != project {
+			continue
+		}
+
 		aliases := make([]string, 0, len(img.RepoTags)-1)
 
 		for index, tag := range img.RepoTags {
@@ -61,6 +82,7 @@ func VersionList(ctx context.Context, client *client.Client, cfg *config.Project
 			CreatedAt: time.Unix(img.Created, 0),
 			Aliases:   aliases,
 			Active:    activeVersion,
+			Project:   project,
 		})
 	}
 
@@ -72,7 +94,13 @@ func VersionList(ctx context.Context, client *client.Client, cfg *config.Project
 	return versions, nil
 }
 
+// VersionDrain removes the images above cfg.KeepVersions which are not used anymore
 func VersionDrain(ctx context.Context, client *client.Client, cfg *config.ProjectConfig) error {
+	// Previews push into the image repository of the project, draining there could remove the versions of the project itself
+	if cfg.PreviewName != "" {
+		return nil
+	}
+
 	versions, err := VersionList(ctx, client, cfg)
 
 	if err != nil {
@@ -83,12 +111,25 @@ func VersionDrain(ctx context.Context, client *client.Client, cfg *config.Projec
 		return nil
 	}
 
+	appContainers, err := listAppContainers(ctx, client, cfg.Identifier())
+
+	if err != nil {
+		return err
+	}
+
 	for _, version := range versions[cfg.KeepVersions:] {
 		// Skip active versions
 		if version.Active {
 			continue
 		}
 
+		// Skip versions of app containers kept warm for a rollback
+		if slices.ContainsFunc(appContainers, func(c container.Summary) bool {
+			return c.Image == cfg.Image+":"+version.Name || slices.Contains(version.Aliases, strings.TrimPrefix(c.Image, cfg.Image+":"))
+		}) {
+			continue
+		}
+
 		for _, alias := range append(version.Aliases, version.Name) {
 			_, err := client.ImageRemove(ctx, cfg.Image+":"+alias, image.RemoveOptions{PruneChildren: true})
 
@@ -102,17 +143,27 @@ func VersionDrain(ctx context.Context, client *client.Client, cfg *config.Projec
 }
 
 func VersionCurrentlyActive(ctx context.Context, client *client.Client, cfg *config.ProjectConfig) (string, error) {
-	c, err := getEnvironmentContainers(ctx, client, cfg.Name)
+	c, err := getEnvironmentContainers(ctx, client, cfg.Identifier())
 
 	if err != nil {
 		return "", err
 	}
 
 	if len(c) == 0 {
-		return "", fmt.Errorf("there is no deployment yet for project %s", cfg.Name)
+		return "", fmt.Errorf("there is no deployment yet for project %s", cfg.Identifier())
+	}
+
+	active := c[0]
+
+	// A running canary serves only a part of the traffic, the other container is the active version
+	for _, appContainer := range c {
+		if _, ok := appContainer.Labels[canaryLabel]; !ok {
+			active = appContainer
+			break
+		}
 	}
 
-	imageSplit := strings.SplitN(c[0].Image, ":", 2)
+	imageSplit := strings.SplitN(active.Image, ":", 2)
 
 	return imageSplit[1], nil
 }
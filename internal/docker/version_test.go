@@ -0,0 +1,18 @@
+package docker
+
+import (
+	"testing"
+
+	"github.com/docker/docker/api/types/image"
+	"github.com/shyim/tanjun/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImageProject(t *testing.T) {
+	cfg := &config.ProjectConfig{Name: "app", Environment: "staging"}
+
+	assert.Equal(t, "app_staging", imageProject(image.Summary{Labels: map[string]string{ImageProjectLabel: "app_staging"}}, cfg))
+
+	// images built before the label belong to the project without environment
+	assert.Equal(t, "app", imageProject(image.Summary{}, cfg))
+}
@@ -0,0 +1,134 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+)
+
+// warmContainerMarker is appended together with the expiry to the name of previous app containers kept for a fast rollback.
+// Labels cannot be changed on existing containers, so the name marks them
+const warmContainerMarker = "_warm_"
+
+func isWarmContainer(c container.Summary) bool {
+	return len(c.Names) > 0 && strings.Contains(c.Names[0], warmContainerMarker)
+}
+
+func warmContainerExpired(c container.Summary) bool {
+	name := c.Names[0]
+	expiry, err := strconv.ParseInt(name[strings.LastIndex(name, warmContainerMarker)+len(warmContainerMarker):], 10, 64)
+
+	if err != nil {
+		return true
+	}
+
+	return time.Now().After(time.Unix(expiry, 0))
+}
+
+func getWarmContainers(ctx context.Context, client *client.Client, projectName string) ([]container.Summary, error) {
+	containers, err := listAppContainers(ctx, client, projectName)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return slices.DeleteFunc(containers, func(c container.Summary) bool {
+		return !isWarmContainer(c)
+	}), nil
+}
+
+// keepWarm stops and renames the previous app containers instead of removing them
+func (d *serverDeployment) keepWarm(ctx context.Context) error {
+	keepWarm := d.deployCfg.ProjectConfig.App.KeepWarm
+	expiresAt := time.Now().Add(time.Duration(keepWarm.Duration) * time.Second)
+
+	for _, c := range d.beforeContainers {
+		if !keepWarm.Running {
+			if err := d.client.ContainerStop(ctx, c.ID, container.StopOptions{}); err != nil {
+				return fmt.Errorf("could not stop previous app container: %w", err)
+			}
+		}
+
+		name := fmt.Sprintf("%s%s%d", strings.TrimPrefix(c.Names[0], "/"), warmContainerMarker, expiresAt.Unix())
+
+		if err := d.client.ContainerRename(ctx, c.ID, name); err != nil {
+			return fmt.Errorf("could not rename previous app container: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (d *serverDeployment) removeExpiredWarmContainers(ctx context.Context) error {
+	warmContainers, err := getWarmContainers(ctx, d.client, d.deployCfg.Name)
+
+	if err != nil {
+		return err
+	}
+
+	return removeContainers(ctx, d.client, slices.DeleteFunc(warmContainers, func(c container.Summary) bool {
+		return !warmContainerExpired(c)
+	}))
+}
+
+// reuseWarmContainers brings warm containers of the deployed version back, so they do not need to be created again
+func (d *serverDeployment) reuseWarmContainers(ctx context.Context) error {
+	warmContainers, err := getWarmContainers(ctx, d.client, d.deployCfg.Name)
+
+	if err != nil {
+		return err
+	}
+
+	for _, c := range warmContainers {
+		if len(d.newContainerIDs) >= d.deployCfg.ProjectConfig.App.Replicas {
+			break
+		}
+
+		if c.Image != d.deployCfg.ImageName || warmContainerExpired(c) {
+			continue
+		}
+
+		if err := d.client.ContainerRename(ctx, c.ID, fmt.Sprintf("%s_app_%d", d.deployCfg.ContainerPrefix(), rand.IntN(1000000))); err != nil {
+			return fmt.Errorf("could not rename warm app container: %w", err)
+		}
+
+		d.newContainerIDs = append(d.newContainerIDs, c.ID)
+
+		if d.reusedWarm == nil {
+			d.reusedWarm = make(map[string]container.Summary)
+		}
+
+		d.reusedWarm[c.ID] = c
+
+		if c.State != container.StateRunning {
+			if err := d.client.ContainerStart(ctx, c.ID, container.StartOptions{}); err != nil {
+				return fmt.Errorf("could not start warm app container: %w", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// returnWarmContainer puts a reused warm container back into the pool with its previous name and state, so a failed
+// deployment does not consume the containers kept for a rollback
+func (d *serverDeployment) returnWarmContainer(ctx context.Context, warm container.Summary) error {
+	if warm.State != container.StateRunning {
+		if err := d.client.ContainerStop(ctx, warm.ID, container.StopOptions{}); err != nil {
+			return fmt.Errorf("could not stop warm app container: %w", err)
+		}
+	}
+
+	if err := d.client.ContainerRename(ctx, warm.ID, strings.TrimPrefix(warm.Names[0], "/")); err != nil {
+		return fmt.Errorf("could not rename warm app container: %w", err)
+	}
+
+	return nil
+}
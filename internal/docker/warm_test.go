@@ -0,0 +1,23 @@
+package docker
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWarmContainer(t *testing.T) {
+	active := container.Summary{Names: []string{"/tanjun_app_app_123"}}
+	warm := container.Summary{Names: []string{fmt.Sprintf("/tanjun_app_app_123_warm_%d", time.Now().Add(time.Hour).Unix())}}
+	expired := container.Summary{Names: []string{fmt.Sprintf("/tanjun_app_app_123_warm_%d", time.Now().Add(-time.Minute).Unix())}}
+
+	assert.False(t, isWarmContainer(active))
+	assert.True(t, isWarmContainer(warm))
+	assert.True(t, isWarmContainer(expired))
+
+	assert.False(t, warmContainerExpired(warm))
+	assert.True(t, warmContainerExpired(expired))
+}
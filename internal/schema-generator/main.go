@@ -35,8 +35,50 @@ func main() {
 						}),
 					},
 				},
+				"backup": {
+					Type:     "object",
+					Required: []string{"schedule"},
+					Properties: newOrderedMap(map[string]*jsonschema.Schema{
+						"schedule": {
+							Type: "string",
+						},
+						"keep": {
+							Type: "integer",
+						},
+						"max_age": {
+							Type: "integer",
+						},
+					}),
+				},
+				"expose": {
+					Type:     "object",
+					Required: []string{"host", "port"},
+					Properties: newOrderedMap(map[string]*jsonschema.Schema{
+						"host": {
+							Type: "string",
+						},
+						"port": {
+							Type: "integer",
+						},
+						"health_check_path": {
+							Type: "string",
+						},
+						"basic_auth": {
+							Type: "object",
+							Properties: newOrderedMap(map[string]*jsonschema.Schema{
+								"username": {
+									Type: "string",
+								},
+							}),
+						},
+					}),
+				},
+				"host_port": {
+					Type: "integer",
+				},
 				"secrets": {
-					Type: "object",
+					Type:        "object",
+					Description: "When a variable is set by multiple sources, the later one wins: env, from_env, from_stored, from_env_file, from_sops, from_age, onepassword",
 					Properties: newOrderedMap(map[string]*jsonschema.Schema{
 						"from_env": {
 							Type: "object",
@@ -63,6 +105,29 @@ func main() {
 								Type: "string",
 							},
 						},
+						"from_sops": {
+							Type:        "array",
+							Description: "SOPS encrypted files relative to the config file",
+							Items: &jsonschema.Schema{
+								Type: "string",
+							},
+						},
+						"from_age": {
+							Type:        "array",
+							Description: "age encrypted files relative to the config file",
+							Items: &jsonschema.Schema{
+								Type: "object",
+								Properties: newOrderedMap(map[string]*jsonschema.Schema{
+									"file": {
+										Type: "string",
+									},
+									"identity": {
+										Type: "string",
+									},
+								}),
+								Required: []string{"file"},
+							},
+						},
 						"onepassword": {
 							Type: "object",
 							Properties: newOrderedMap(map[string]*jsonschema.Schema{
@@ -110,6 +175,18 @@ func main() {
 		for _, t := range svc.SupportedTypes() {
 			types = append(types, t)
 
+			then := &jsonschema.Schema{
+				Properties: newOrderedMap(map[string]*jsonschema.Schema{
+					"settings": svc.ConfigSchema(t),
+				}),
+			}
+
+			// The custom service is configured with its own properties next to the settings
+			if t == "custom" {
+				then = customServiceSchema()
+				then.Properties.Set("settings", svc.ConfigSchema(t))
+			}
+
 			allOf = append(allOf, &jsonschema.Schema{
 				If: &jsonschema.Schema{
 					Properties: newOrderedMap(map[string]*jsonschema.Schema{
@@ -118,11 +195,7 @@ func main() {
 						},
 					}),
 				},
-				Then: &jsonschema.Schema{
-					Properties: newOrderedMap(map[string]*jsonschema.Schema{
-						"settings": svc.ConfigSchema(t),
-					}),
-				},
+				Then: then,
 			})
 		}
 	}
@@ -158,6 +231,48 @@ func main() {
 	}
 }
 
+func customServiceSchema() *jsonschema.Schema {
+	properties := orderedmap.New[string, *jsonschema.Schema]()
+
+	properties.Set("image", &jsonschema.Schema{
+		Type:        "string",
+		Description: "The image to run, like gotenberg/gotenberg:8",
+	})
+
+	properties.Set("command", &jsonschema.Schema{
+		Type:  "array",
+		Items: &jsonschema.Schema{Type: "string"},
+	})
+
+	properties.Set("ports", &jsonschema.Schema{
+		Type:        "array",
+		Items:       &jsonschema.Schema{Type: "integer"},
+		Description: "Ports the service listens on, the first one is exposed as service.<name>.port",
+	})
+
+	properties.Set("volumes", &jsonschema.Schema{
+		Type:                 "object",
+		AdditionalProperties: &jsonschema.Schema{Type: "string"},
+		Description:          "Maps a volume name to the path in the container",
+	})
+
+	healthcheck := orderedmap.New[string, *jsonschema.Schema]()
+	healthcheck.Set("command", &jsonschema.Schema{Type: "string"})
+	healthcheck.Set("interval", &jsonschema.Schema{Type: "integer"})
+	healthcheck.Set("retries", &jsonschema.Schema{Type: "integer"})
+
+	properties.Set("healthcheck", &jsonschema.Schema{
+		Type:       "object",
+		Required:   []string{"command"},
+		Properties: healthcheck,
+	})
+
+	return &jsonschema.Schema{
+		Required:   []string{"image"},
+		Properties: properties,
+	}
+}
+
 func newOrderedMap(schemaMap map[string]*jsonschema.Schema) *orderedmap.OrderedMap[string, *jsonschema.Schema] {
 	om := orderedmap.New[string, *jsonschema.Schema]()
 
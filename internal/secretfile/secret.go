@@ -0,0 +1,129 @@
+package secretfile
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/shyim/tanjun/internal/config"
+	"gopkg.in/yaml.v3"
+)
+
+// ResolveSops decrypts a SOPS encrypted file with the sops binary, which finds the keys of the user like on the command line
+func ResolveSops(ctx context.Context, file string) (map[string]string, error) {
+	output, err := exec.CommandContext(ctx, "sops", "--decrypt", "--output-type", "yaml", file).Output()
+
+	if err != nil {
+		return nil, fmt.Errorf("error decrypting sops file %s: %w%s", file, err, commandStderr(err))
+	}
+
+	secrets, err := parse(output)
+
+	if err != nil {
+		return nil, fmt.Errorf("error parsing sops file %s: %w", file, err)
+	}
+
+	return secrets, nil
+}
+
+// ResolveAge decrypts an age encrypted YAML file with the age binary
+func ResolveAge(ctx context.Context, file config.ProjectAgeFile) (map[string]string, error) {
+	identity, err := ageIdentity(file.Identity)
+
+	if err != nil {
+		return nil, err
+	}
+
+	output, err := exec.CommandContext(ctx, "age", "--decrypt", "--identity", identity, file.File).Output()
+
+	if err != nil {
+		return nil, fmt.Errorf("error decrypting age file %s: %w%s", file.File, err, commandStderr(err))
+	}
+
+	secrets, err := parse(output)
+
+	if err != nil {
+		return nil, fmt.Errorf("error parsing age file %s: %w", file.File, err)
+	}
+
+	return secrets, nil
+}
+
+func ageIdentity(identity string) (string, error) {
+	if identity == "" {
+		identity = os.Getenv("AGE_IDENTITY_FILE")
+	}
+
+	if identity == "" {
+		home, err := os.UserHomeDir()
+
+		if err != nil {
+			return "", err
+		}
+
+		identity = filepath.Join(home, ".config", "age", "keys.txt")
+	}
+
+	if strings.HasPrefix(identity, "~/") {
+		home, err := os.UserHomeDir()
+
+		if err != nil {
+			return "", err
+		}
+
+		identity = filepath.Join(home, identity[2:])
+	}
+
+	return identity, nil
+}
+
+func commandStderr(err error) string {
+	if exitErr, ok := err.(*exec.ExitError); ok && len(exitErr.Stderr) > 0 {
+		return ", " + strings.TrimSpace(string(exitErr.Stderr))
+	}
+
+	return ""
+}
+
+// parse reads the decrypted YAML document. Nested keys are joined with an underscore, so database: {password: x} becomes database_password
+func parse(content []byte) (map[string]string, error) {
+	var document map[string]interface{}
+
+	if err := yaml.Unmarshal(content, &document); err != nil {
+		return nil, err
+	}
+
+	secrets := make(map[string]string)
+
+	if err := flatten(secrets, "", document); err != nil {
+		return nil, err
+	}
+
+	return secrets, nil
+}
+
+func flatten(secrets map[string]string, prefix string, document map[string]interface{}) error {
+	for key, value := range document {
+		if prefix != "" {
+			key = prefix + "_" + key
+		}
+
+		switch typed := value.(type) {
+		case map[string]interface{}:
+			if err := flatten(secrets, key, typed); err != nil {
+				return err
+			}
+		case []interface{}:
+			return fmt.Errorf("lists are not supported, found one at %s", key)
+		case nil:
+			secrets[key] = ""
+		default:
+			secrets[key] = fmt.Sprintf("%v", typed)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,45 @@
+package secretfile
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	secrets, err := parse([]byte(`
+APP_SECRET: foo
+PORT: 8080
+DEBUG: false
+EMPTY:
+database:
+  password: bar
+`))
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"APP_SECRET":        "foo",
+		"PORT":              "8080",
+		"DEBUG":             "false",
+		"EMPTY":             "",
+		"database_password": "bar",
+	}, secrets)
+}
+
+func TestParseRejectsLists(t *testing.T) {
+	_, err := parse([]byte("hosts:\n  - a\n  - b\n"))
+
+	assert.ErrorContains(t, err, "lists are not supported, found one at hosts")
+}
+
+func TestAgeIdentity(t *testing.T) {
+	t.Setenv("AGE_IDENTITY_FILE", "/keys/age.txt")
+
+	identity, err := ageIdentity("")
+	assert.NoError(t, err)
+	assert.Equal(t, "/keys/age.txt", identity)
+
+	identity, err = ageIdentity("/other.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, "/other.txt", identity)
+}
@@ -6,13 +6,16 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"os"
+	"strings"
+
 	kvstore "github.com/shyim/tanjun/kv-store"
 	_ "modernc.org/sqlite"
-	"os"
 )
 
 func main() {
 	scanner := bufio.NewScanner(os.Stdin)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
 
 	db, err := sql.Open("sqlite", "kv.db")
 	if err != nil {
@@ -23,12 +26,17 @@ func main() {
 	_, _ = db.Exec(`PRAGMA synchronous = NORMAL`)
 	_, _ = db.Exec(`PRAGMA busy_timeout = 5000`)
 
-	_, err = db.Exec("CREATE TABLE IF NOT EXISTS secrets (\n                                       `key` TEXT NOT NULL,\n                                       `value` BLOB NOT NULL,\n                                       PRIMARY KEY (`key`)\n    );")
+	_, err = db.Exec("CREATE TABLE IF NOT EXISTS secrets (\n                                       `key` TEXT NOT NULL,\n                                       `value` BLOB NOT NULL,\n                                       `revision` INTEGER NOT NULL DEFAULT 1,\n                                       PRIMARY KEY (`key`)\n    );")
 
 	if err != nil {
 		panic(err)
 	}
 
+	// databases created before revisions were introduced
+	if _, err := db.Exec("ALTER TABLE secrets ADD COLUMN `revision` INTEGER NOT NULL DEFAULT 1"); err != nil && !strings.Contains(err.Error(), "duplicate column") {
+		panic(err)
+	}
+
 	for scanner.Scan() {
 		var parsed kvstore.KVInput
 
@@ -37,49 +45,227 @@ func main() {
 			continue
 		}
 
-		if parsed.Operation == "del" {
-			_, err = db.Exec("DELETE FROM secrets WHERE `key` = ?", parsed.Key)
+		response, err := handle(db, parsed)
+
+		if err != nil {
+			encodeResponse(kvstore.KVResponse{Type: "error", ErrorMessage: err.Error()})
+			continue
+		}
+
+		encodeResponse(response)
+	}
+
+	if err := scanner.Err(); err != nil {
+		encodeResponse(kvstore.KVResponse{Type: "error", ErrorMessage: err.Error()})
+	}
+}
+
+func handle(db *sql.DB, parsed kvstore.KVInput) (kvstore.KVResponse, error) {
+	switch parsed.Operation {
+	case "del":
+		if _, err := db.Exec("DELETE FROM secrets WHERE `key` = ?", parsed.Key); err != nil {
+			return kvstore.KVResponse{}, err
+		}
+
+		return kvstore.KVResponse{Type: "success"}, nil
+	case "set":
+		revision, err := setValue(db, parsed.Key, parsed.Value)
+
+		if err != nil {
+			return kvstore.KVResponse{}, err
+		}
+
+		return kvstore.KVResponse{Type: "success", Revision: revision}, nil
+	case "get":
+		value, revision, err := getValue(db, parsed.Key)
+
+		if err != nil {
+			return kvstore.KVResponse{}, err
+		}
+
+		return kvstore.KVResponse{Type: "success", Value: value, Revision: revision}, nil
+	case "list":
+		keys, err := listKeys(db, parsed.Key)
+
+		if err != nil {
+			return kvstore.KVResponse{}, err
+		}
+
+		return kvstore.KVResponse{Type: "success", Keys: keys}, nil
+	case "mget":
+		values := map[string]string{}
+		revisions := map[string]int64{}
+
+		for _, key := range parsed.Keys {
+			value, revision, err := getValue(db, key)
+
 			if err != nil {
-				encodeResponse(kvstore.KVResponse{Type: "error", ErrorMessage: err.Error()})
-				continue
+				return kvstore.KVResponse{}, err
 			}
 
-			encodeResponse(kvstore.KVResponse{Type: "success"})
-		} else if parsed.Operation == "set" {
-			_, err = db.Exec("REPLACE INTO secrets VALUES(?, ?)", parsed.Key, parsed.Value)
-			if err != nil {
-				encodeResponse(kvstore.KVResponse{Type: "error", ErrorMessage: err.Error()})
+			if revision == 0 {
 				continue
 			}
 
-			encodeResponse(kvstore.KVResponse{Type: "success"})
-		} else if parsed.Operation == "get" {
-			row := db.QueryRow("SELECT value FROM secrets WHERE key = ?", parsed.Key)
+			values[key] = value
+			revisions[key] = revision
+		}
 
-			if row.Err() != nil {
-				encodeResponse(kvstore.KVResponse{Type: "error", ErrorMessage: err.Error()})
-				continue
-			}
+		return kvstore.KVResponse{Type: "success", Values: values, Revisions: revisions}, nil
+	case "mset":
+		revisions, err := setValues(db, parsed.Values)
 
-			var dbVal string
-			if err := row.Scan(&dbVal); err != nil {
-				if errors.Is(err, sql.ErrNoRows) {
-					encodeResponse(kvstore.KVResponse{Type: "success", Value: ""})
-					continue
-				}
+		if err != nil {
+			return kvstore.KVResponse{}, err
+		}
 
-				encodeResponse(kvstore.KVResponse{Type: "error", ErrorMessage: err.Error()})
-				continue
-			}
+		return kvstore.KVResponse{Type: "success", Revisions: revisions}, nil
+	case "cas":
+		revision, swapped, err := compareAndSwap(db, parsed.Key, parsed.Value, parsed.Revision)
 
-			encodeResponse(kvstore.KVResponse{Type: "success", Value: dbVal})
-			continue
+		if err != nil {
+			return kvstore.KVResponse{}, err
 		}
+
+		if !swapped {
+			return kvstore.KVResponse{Type: "conflict", Revision: revision}, nil
+		}
+
+		return kvstore.KVResponse{Type: "success", Revision: revision}, nil
 	}
 
-	if err := scanner.Err(); err != nil {
-		encodeResponse(kvstore.KVResponse{Type: "error", ErrorMessage: err.Error()})
+	return kvstore.KVResponse{}, fmt.Errorf("unknown operation %s", parsed.Operation)
+}
+
+// getValue returns the value and its revision, the revision is 0 when the key does not exist
+func getValue(db *sql.DB, key string) (string, int64, error) {
+	var value string
+	var revision int64
+
+	if err := db.QueryRow("SELECT `value`, `revision` FROM secrets WHERE `key` = ?", key).Scan(&value, &revision); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", 0, nil
+		}
+
+		return "", 0, err
+	}
+
+	return value, revision, nil
+}
+
+type rowQuerier interface {
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// setValue stores the value and increments the revision of the key
+func setValue(db rowQuerier, key, value string) (int64, error) {
+	var revision int64
+
+	err := db.QueryRow("INSERT INTO secrets (`key`, `value`, `revision`) VALUES (?, ?, 1) ON CONFLICT(`key`) DO UPDATE SET `value` = excluded.`value`, `revision` = `revision` + 1 RETURNING `revision`", key, value).Scan(&revision)
+
+	return revision, err
+}
+
+func setValues(db *sql.DB, values map[string]string) (map[string]int64, error) {
+	tx, err := db.Begin()
+
+	if err != nil {
+		return nil, err
+	}
+
+	revisions := map[string]int64{}
+
+	for key, value := range values {
+		revision, err := setValue(tx, key, value)
+
+		if err != nil {
+			_ = tx.Rollback()
+			return nil, err
+		}
+
+		revisions[key] = revision
+	}
+
+	return revisions, tx.Commit()
+}
+
+// compareAndSwap sets the value only when the key has the expected revision, otherwise the current revision is returned
+func compareAndSwap(db *sql.DB, key, value string, expected int64) (int64, bool, error) {
+	var err error
+	var result sql.Result
+
+	if expected == 0 {
+		result, err = db.Exec("INSERT INTO secrets (`key`, `value`, `revision`) VALUES (?, ?, 1) ON CONFLICT(`key`) DO NOTHING", key, value)
+	} else {
+		result, err = db.Exec("UPDATE secrets SET `value` = ?, `revision` = `revision` + 1 WHERE `key` = ? AND `revision` = ?", value, key, expected)
+	}
+
+	if err != nil {
+		return 0, false, err
+	}
+
+	affected, err := result.RowsAffected()
+
+	if err != nil {
+		return 0, false, err
 	}
+
+	if affected == 1 {
+		return expected + 1, true, nil
+	}
+
+	_, current, err := getValue(db, key)
+
+	return current, false, err
+}
+
+// listKeys returns all keys starting with the prefix. Keys are compared bytewise, so all keys with the prefix are in
+// the range from the prefix up to the next prefix, which also uses the primary key index
+func listKeys(db *sql.DB, prefix string) ([]string, error) {
+	var rows *sql.Rows
+	var err error
+
+	if upper, ok := prefixUpperBound(prefix); ok {
+		rows, err = db.Query("SELECT `key` FROM secrets WHERE `key` >= ? AND `key` < ? ORDER BY `key`", prefix, upper)
+	} else {
+		rows, err = db.Query("SELECT `key` FROM secrets WHERE `key` >= ? ORDER BY `key`", prefix)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	keys := []string{}
+
+	for rows.Next() {
+		var key string
+
+		if err := rows.Scan(&key); err != nil {
+			return nil, err
+		}
+
+		keys = append(keys, key)
+	}
+
+	return keys, rows.Err()
+}
+
+// prefixUpperBound returns the smallest string greater than all strings starting with the prefix, there is none when
+// the prefix is empty or only consists of 0xff bytes
+func prefixUpperBound(prefix string) (string, bool) {
+	upper := []byte(prefix)
+
+	for i := len(upper) - 1; i >= 0; i-- {
+		if upper[i] < 0xff {
+			upper[i]++
+
+			return string(upper[:i+1]), true
+		}
+	}
+
+	return "", false
 }
 
 func encodeResponse(input kvstore.KVResponse) {
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	_ "modernc.org/sqlite"
+)
+
+func TestPrefixUpperBound(t *testing.T) {
+	upper, ok := prefixUpperBound("tanjun_app_")
+
+	assert.True(t, ok)
+	assert.Equal(t, "tanjun_app`", upper)
+
+	upper, ok = prefixUpperBound("a\xff")
+
+	assert.True(t, ok)
+	assert.Equal(t, "b", upper)
+
+	_, ok = prefixUpperBound("")
+
+	assert.False(t, ok)
+}
+
+func TestListKeys(t *testing.T) {
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "kv.db"))
+
+	assert.NoError(t, err)
+
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+
+	_, err = db.Exec("CREATE TABLE secrets (`key` TEXT NOT NULL, `value` BLOB NOT NULL, `revision` INTEGER NOT NULL DEFAULT 1, PRIMARY KEY (`key`))")
+
+	assert.NoError(t, err)
+
+	for _, key := range []string{"tanjun_app_secrets/A", "tanjun_app_secrets/ä", "tanjun_app_secrets0", "tanjun_äpp_secrets/A", "tanjun_app"} {
+		_, err = db.Exec("INSERT INTO secrets (`key`, `value`) VALUES (?, ?)", key, "value")
+
+		assert.NoError(t, err)
+	}
+
+	keys, err := listKeys(db, "tanjun_app_secrets/")
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"tanjun_app_secrets/A", "tanjun_app_secrets/ä"}, keys)
+
+	keys, err = listKeys(db, "tanjun_ä")
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"tanjun_äpp_secrets/A"}, keys)
+
+	keys, err = listKeys(db, "")
+
+	assert.NoError(t, err)
+	assert.Len(t, keys, 5)
+}
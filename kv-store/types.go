@@ -1,13 +1,25 @@
 package kv_store
 
+// KVInput is a single request. Operations:
+//   - get, set, del, cas: single key, cas only sets the value when the key still has Revision (0 means the key must not exist)
+//   - list: all keys starting with Key
+//   - mget: the values of Keys, mset: stores all Values in one transaction
 type KVInput struct {
-	Operation string `json:"operation"`
-	Key       string `json:"key"`
-	Value     string `json:"value,omitempty"`
+	Operation string            `json:"operation"`
+	Key       string            `json:"key"`
+	Value     string            `json:"value,omitempty"`
+	Keys      []string          `json:"keys,omitempty"`
+	Values    map[string]string `json:"values,omitempty"`
+	Revision  int64             `json:"revision,omitempty"`
 }
 
+// KVResponse has the Type success, error or conflict. A conflict is returned by cas when the revision did not match, Revision is the current revision then
 type KVResponse struct {
-	Type         string `json:"type,omitempty"`
-	ErrorMessage string `json:"error_message,omitempty"`
-	Value        string `json:"value,omitempty"`
+	Type         string            `json:"type,omitempty"`
+	ErrorMessage string            `json:"error_message,omitempty"`
+	Value        string            `json:"value,omitempty"`
+	Keys         []string          `json:"keys,omitempty"`
+	Values       map[string]string `json:"values,omitempty"`
+	Revision     int64             `json:"revision,omitempty"`
+	Revisions    map[string]int64  `json:"revisions,omitempty"`
 }
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"strings"
+	"sync/atomic"
+)
+
+const page = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Maintenance</title>
+<style>body{font-family:system-ui,sans-serif;display:flex;align-items:center;justify-content:center;min-height:100vh;margin:0;color:#333}main{max-width:40rem;padding:2rem;text-align:center}</style>
+</head>
+<body><main><h1>Maintenance</h1><p>%s</p></main></body>
+</html>
+`
+
+func main() {
+	message := os.Getenv("MAINTENANCE_MESSAGE")
+
+	if message == "" {
+		message = "We are currently performing maintenance, please try again in a few minutes."
+	}
+
+	healthPath := os.Getenv("MAINTENANCE_HEALTH_PATH")
+
+	if healthPath == "" {
+		healthPath = "/up"
+	}
+
+	allowList, err := parseAllowList(os.Getenv("MAINTENANCE_ALLOW_IPS"))
+
+	if err != nil {
+		log.Fatalf("invalid MAINTENANCE_ALLOW_IPS: %s", err)
+	}
+
+	var proxies []*httputil.ReverseProxy
+
+	for _, upstream := range strings.Split(os.Getenv("MAINTENANCE_UPSTREAMS"), ",") {
+		if upstream == "" {
+			continue
+		}
+
+		proxies = append(proxies, httputil.NewSingleHostReverseProxy(&url.URL{Scheme: "http", Host: upstream}))
+	}
+
+	body := fmt.Sprintf(page, html.EscapeString(message))
+
+	var next atomic.Uint64
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == healthPath {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		if len(proxies) > 0 && allowed(allowList, clientIP(r)) {
+			proxies[next.Add(1)%uint64(len(proxies))].ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Retry-After", "60")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = w.Write([]byte(body))
+	})
+
+	log.Println("Listening on port 80")
+
+	if err := http.ListenAndServe(":80", handler); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// clientIP returns the address kamal-proxy has seen, it is always the last entry of X-Forwarded-For. Entries before can be set by the client
+func clientIP(r *http.Request) net.IP {
+	if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+		entries := strings.Split(forwarded, ",")
+
+		return net.ParseIP(strings.TrimSpace(entries[len(entries)-1]))
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+
+	if err != nil {
+		return nil
+	}
+
+	return net.ParseIP(host)
+}
+
+func parseAllowList(value string) ([]*net.IPNet, error) {
+	var allowList []*net.IPNet
+
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+
+		if entry == "" {
+			continue
+		}
+
+		if !strings.Contains(entry, "/") {
+			if strings.Contains(entry, ":") {
+				entry += "/128"
+			} else {
+				entry += "/32"
+			}
+		}
+
+		_, network, err := net.ParseCIDR(entry)
+
+		if err != nil {
+			return nil, err
+		}
+
+		allowList = append(allowList, network)
+	}
+
+	return allowList, nil
+}
+
+func allowed(allowList []*net.IPNet, ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+
+	for _, network := range allowList {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
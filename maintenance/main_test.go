@@ -0,0 +1,86 @@
+package main
+
+import (
+	"net"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	cases := []struct {
+		name       string
+		forwarded  string
+		remoteAddr string
+		ip         string
+	}{
+		{name: "remote address", remoteAddr: "192.0.2.1:4321", ip: "192.0.2.1"},
+		{name: "forwarded by kamal-proxy", forwarded: "203.0.113.5", remoteAddr: "172.18.0.2:4321", ip: "203.0.113.5"},
+		{name: "entries set by the client are ignored", forwarded: "10.0.0.1, 203.0.113.5", remoteAddr: "172.18.0.2:4321", ip: "203.0.113.5"},
+		{name: "ipv6", forwarded: "2001:db8::1", remoteAddr: "172.18.0.2:4321", ip: "2001:db8::1"},
+		{name: "invalid", forwarded: "unknown", remoteAddr: "172.18.0.2:4321"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			r.RemoteAddr = c.remoteAddr
+
+			if c.forwarded != "" {
+				r.Header.Set("X-Forwarded-For", c.forwarded)
+			}
+
+			ip := clientIP(r)
+
+			if c.ip == "" {
+				if ip != nil {
+					t.Errorf("expected no ip, got %s", ip)
+				}
+
+				return
+			}
+
+			if !ip.Equal(net.ParseIP(c.ip)) {
+				t.Errorf("expected %s, got %s", c.ip, ip)
+			}
+		})
+	}
+}
+
+func TestAllowed(t *testing.T) {
+	allowList, err := parseAllowList("203.0.113.5, 10.0.0.0/8,2001:db8::1")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		ip      string
+		allowed bool
+	}{
+		{"203.0.113.5", true},
+		{"203.0.113.6", false},
+		{"10.20.30.40", true},
+		{"11.0.0.1", false},
+		{"2001:db8::1", true},
+		{"2001:db8::2", false},
+		{"", false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.ip, func(t *testing.T) {
+			if got := allowed(allowList, net.ParseIP(c.ip)); got != c.allowed {
+				t.Errorf("expected allowed to be %t for %q", c.allowed, c.ip)
+			}
+		})
+	}
+
+	if allowed(nil, net.ParseIP("203.0.113.5")) {
+		t.Error("an empty allow list must not allow anything")
+	}
+}
+
+func TestParseAllowListInvalid(t *testing.T) {
+	if _, err := parseAllowList("203.0.113.5, not-an-ip"); err == nil {
+		t.Error("expected an error for an invalid entry")
+	}
+}
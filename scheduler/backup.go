@@ -0,0 +1,187 @@
+package main
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+const backupDir = "/backups"
+
+// BackupJob dumps a service by executing the command in its container and stores the gzip compressed output in the backups volume
+type BackupJob struct {
+	Service   string   `json:"service"`
+	Container string   `json:"container"`
+	Command   []string `json:"command"`
+	// Keep is the amount of backups kept, older ones are removed after a successful backup
+	Keep int `json:"keep"`
+	// MaxAge removes backups older than the given seconds, 0 disables it
+	MaxAge int `json:"max_age"`
+}
+
+// Backup is a file in the backups volume
+type Backup struct {
+	Service   string    `json:"service"`
+	Name      string    `json:"name"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (b BackupJob) run(ctx context.Context, dockerClient *client.Client) (string, error) {
+	dir := filepath.Join(backupDir, b.Service)
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+
+	name := time.Now().UTC().Format("2006-01-02T15-04-05Z") + ".sql.gz"
+	partial := filepath.Join(dir, "."+name+".partial")
+
+	if err := b.dump(ctx, dockerClient, partial); err != nil {
+		_ = os.Remove(partial)
+		return "", err
+	}
+
+	if err := os.Rename(partial, filepath.Join(dir, name)); err != nil {
+		return "", err
+	}
+
+	removed, err := b.rotate()
+
+	if err != nil {
+		return "", fmt.Errorf("backup %s written, but rotation failed: %w", name, err)
+	}
+
+	output := fmt.Sprintf("Backup %s written\n", name)
+
+	for _, backup := range removed {
+		output += fmt.Sprintf("Removed old backup %s\n", backup)
+	}
+
+	return output, nil
+}
+
+func (b BackupJob) dump(ctx context.Context, dockerClient *client.Client, target string) error {
+	file, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+
+	if err != nil {
+		return err
+	}
+
+	defer file.Close()
+
+	exec, err := dockerClient.ContainerExecCreate(ctx, b.Container, container.ExecOptions{
+		AttachStdout: true,
+		AttachStderr: true,
+		Cmd:          b.Command,
+	})
+
+	if err != nil {
+		return err
+	}
+
+	resp, err := dockerClient.ContainerExecAttach(ctx, exec.ID, container.ExecStartOptions{})
+
+	if err != nil {
+		return err
+	}
+
+	defer resp.Close()
+
+	compressed := gzip.NewWriter(file)
+	var stderr strings.Builder
+
+	if _, err := stdcopy.StdCopy(compressed, &stderr, resp.Reader); err != nil {
+		return err
+	}
+
+	if err := compressed.Close(); err != nil {
+		return err
+	}
+
+	inspect, err := dockerClient.ContainerExecInspect(ctx, exec.ID)
+
+	if err != nil {
+		return err
+	}
+
+	if inspect.ExitCode != 0 {
+		return fmt.Errorf("dump exited with status code %d: %s", inspect.ExitCode, strings.TrimSpace(stderr.String()))
+	}
+
+	return file.Sync()
+}
+
+// rotate removes the backups exceeding the amount to keep or the maximum age
+func (b BackupJob) rotate() ([]string, error) {
+	backups, err := listBackups(b.Service)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var removed []string
+
+	for i, backup := range backups {
+		expired := b.MaxAge > 0 && time.Since(backup.CreatedAt) > time.Duration(b.MaxAge)*time.Second
+
+		if (b.Keep > 0 && i >= b.Keep) || expired {
+			if err := os.Remove(filepath.Join(backupDir, b.Service, backup.Name)); err != nil {
+				return removed, err
+			}
+
+			removed = append(removed, backup.Name)
+		}
+	}
+
+	return removed, nil
+}
+
+// listBackups returns the backups of the service, newest first
+func listBackups(service string) ([]Backup, error) {
+	entries, err := os.ReadDir(filepath.Join(backupDir, service))
+
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	var backups []Backup
+
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+
+		info, err := entry.Info()
+
+		if err != nil {
+			return nil, err
+		}
+
+		backups = append(backups, Backup{
+			Service:   service,
+			Name:      entry.Name(),
+			Size:      info.Size(),
+			CreatedAt: info.ModTime().UTC(),
+		})
+	}
+
+	slices.SortFunc(backups, func(a, b Backup) int {
+		return strings.Compare(b.Name, a.Name)
+	})
+
+	return backups, nil
+}
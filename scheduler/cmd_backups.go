@@ -0,0 +1,37 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+)
+
+var cmdBackups = &cobra.Command{
+	Use:   "backups [service]",
+	Short: "Lists the stored backups of a service as JSON",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if filepath.Base(args[0]) != args[0] {
+			return fmt.Errorf("invalid service name %s", args[0])
+		}
+
+		backups, err := listBackups(args[0])
+
+		if err != nil {
+			return err
+		}
+
+		if backups == nil {
+			backups = []Backup{}
+		}
+
+		return json.NewEncoder(os.Stdout).Encode(backups)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(cmdBackups)
+}
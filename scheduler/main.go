@@ -20,8 +20,15 @@ func main() {
 	rootCmd.SilenceUsage = true
 	rootCmd.SilenceErrors = true
 
+	// The database keeps the activity of the jobs, /data is a volume so it survives a recreation of the container
+	databasePath := os.Getenv("SCHEDULER_DATABASE")
+
+	if databasePath == "" {
+		databasePath = "/data/database.db"
+	}
+
 	var err error
-	db, err = sql.Open("sqlite", "database.db")
+	db, err = sql.Open("sqlite", databasePath)
 	if err != nil {
 		panic(err)
 	}
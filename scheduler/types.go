@@ -24,10 +24,76 @@ type Job struct {
 	Name          string `json:"name"`
 	Command       string `json:"command"`
 	Cron          string `json:"schedule"`
-	dockerClient  *client.Client
+	// Backup is set for jobs dumping a service instead of running a command in the app container
+	Backup       *BackupJob `json:"backup,omitempty"`
+	dockerClient *client.Client
 }
 
 func (j Job) Run() {
+	now := time.Now()
+
+	var exitCode int
+	var output string
+
+	if j.Backup != nil {
+		var err error
+
+		output, err = j.Backup.run(context.Background(), j.dockerClient)
+
+		if err != nil {
+			log.Errorf("Job: %s, backup failed: %s", j.Name, err)
+			output += err.Error() + "\n"
+			exitCode = 1
+		} else {
+			log.Infof("Job: %s, Output: %s", j.Name, strings.TrimSpace(output))
+		}
+	} else {
+		var ok bool
+
+		exitCode, output, ok = j.runCommand()
+
+		if !ok {
+			return
+		}
+	}
+
+	if j.ManualExecute {
+		// dont persist manual executions into the database
+		return
+	}
+
+	nextExecutionTime := ""
+
+	for _, entry := range c.Entries() {
+		if entry.Job.(Job).Name == j.Name {
+			nextExecutionTime = entry.Schedule.Next(time.Now()).Format("2006-01-02 15:04:05")
+		}
+	}
+
+	currentTime := time.Now().Format("2006-01-02 15:04:05")
+	_, err := db.Exec("UPDATE jobs SET last_execution = ?, next_execution = ?, last_exit_code = ? WHERE name = ?", currentTime, nextExecutionTime, exitCode, j.Name)
+
+	if err != nil {
+		log.Errorf("error updating job: %s", err)
+		return
+	}
+
+	diff := time.Since(now).Milliseconds()
+
+	_, err = db.Exec("INSERT INTO activity (name, run_at, exit_code, log, execution_time) VALUES (?, ?, ?, ?, ?)", j.Name, currentTime, exitCode, output, diff)
+
+	if err != nil {
+		log.Errorf("error inserting activity: %s", err)
+		return
+	}
+
+	if exitCode != 0 {
+		log.Errorf("Job: %s, exited with error code: %d", j.Name, exitCode)
+	}
+}
+
+// runCommand executes the command of the job in the app container, ok is false when the command could not be executed at all
+func (j Job) runCommand() (exitCode int, output string, ok bool) {
 	exec, err := j.dockerClient.ContainerExecCreate(context.Background(), j.ContainerID, container.ExecOptions{
 		AttachStderr: true,
 		AttachStdout: true,
@@ -40,16 +106,14 @@ func (j Job) Run() {
 
 	if err != nil {
 		log.Errorf("error creating exec: %s", err)
-		return
+		return 0, "", false
 	}
 
-	now := time.Now()
-
 	resp, err := j.dockerClient.ContainerExecAttach(context.Background(), exec.ID, container.ExecStartOptions{})
 
 	if err != nil {
 		log.Errorf("error attaching to exec: %s", err)
-		return
+		return 0, "", false
 	}
 
 	pr, pw := io.Pipe()
@@ -75,42 +139,8 @@ func (j Job) Run() {
 
 	if err != nil {
 		log.Errorf("error inspecting exec: %s", err)
-		return
-	}
-
-	if j.ManualExecute {
-		// dont persist manual executions into the database
-		return
-	}
-
-	nextExecutionTime := ""
-
-	for _, entry := range c.Entries() {
-		if entry.Job.(Job).Name == j.Name {
-			nextExecutionTime = entry.Schedule.Next(time.Now()).Format("2006-01-02 15:04:05")
-		}
-	}
-
-	currentTime := time.Now().Format("2006-01-02 15:04:05")
-	_, err = db.Exec("UPDATE jobs SET last_execution = ?, next_execution = ?, last_exit_code = ? WHERE name = ?", currentTime, nextExecutionTime, inspect.ExitCode, j.Name)
-
-	if err != nil {
-		log.Errorf("error updating job: %s", err)
-		return
+		return 0, "", false
 	}
 
-	diff := time.Since(now).Milliseconds()
-
-	_, err = db.Exec("INSERT INTO activity (name, run_at, exit_code, log, execution_time) VALUES (?, ?, ?, ?, ?)", j.Name, currentTime, inspect.ExitCode, strBufffer.String(), diff)
-
-	if err != nil {
-		log.Errorf("error inserting activity: %s", err)
-		return
-	}
-
-	strBufffer.Reset()
-
-	if inspect.ExitCode != 0 {
-		log.Errorf("Job: %s, exited with error code: %d", j.Name, inspect.ExitCode)
-	}
+	return inspect.ExitCode, strBufffer.String(), true
 }